@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -23,8 +25,25 @@ type Config struct {
 	DBPassword  string
 
 	// JWT
-	JWTSecret    string
-	JWTExpiresIn time.Duration
+	JWTAlgorithm           string        // HS256 (shared secret), RS256 or EdDSA
+	JWTKeyRotationInterval time.Duration // How often asymmetric signing keys are rotated
+	JWTSecret             string
+	JWTExpiresIn          time.Duration // Access token lifetime
+	RefreshTokenExpiresIn time.Duration // Session lifetime, extended on each refresh
+
+	// First-run setup
+	SetupToken string
+
+	// Password policy
+	PasswordMinLength      int
+	PasswordRequireMixed   bool // Require both upper and lower case letters
+	PasswordRequireNumber  bool
+	PasswordRequireSymbol  bool
+	PasswordResetExpiresIn time.Duration
+
+	// Account lockout
+	MaxFailedLogins int
+	LockoutDuration time.Duration
 
 	// AWS S3
 	AWSAccessKeyID     string
@@ -57,6 +76,30 @@ type Config struct {
 	MaxFileSize        int64
 	AllowedImageTypes  []string
 	AllowedVideoTypes  []string
+
+	// Duplicate uploads
+	DuplicateUploads string // warn, reject or allow uploads of files already in the library
+
+	// Resumable uploads
+	MaxUploadSize   int64         // Largest file accepted through resumable uploads
+	UploadExpiresIn time.Duration // Incomplete uploads are removed after this long
+
+	// Trash
+	TrashRetention time.Duration // How long deleted media, messages and bookings can be restored
+
+	// Video processing
+	FFmpegPath         string
+	FFprobePath        string
+	VideoPreviewLength time.Duration // Length of the muted preview loop
+	VideoHLS           bool          // Transcode videos into adaptive HLS renditions
+
+	// Storage for generated files such as video renditions
+	StorageDir string
+	StorageURL string // Path the stored files are served under
+
+	// Image transformations
+	ImageSizes     []int  // Widths and heights that may be requested from /api/img
+	ImageURLSecret string // Signs image URLs, generated and stored in the database when empty
 }
 
 // LoadConfig loads configuration from environment variables
@@ -73,8 +116,22 @@ func LoadConfig() *Config {
 		DBUser:      getEnv("DB_USER", "user"),
 		DBPassword:  getEnv("DB_PASSWORD", "password"),
 
-		JWTSecret:    getEnv("JWT_SECRET", "your-secret-key"),
-		JWTExpiresIn: parseDuration(getEnv("JWT_EXPIRES_IN", "24h"), 24*time.Hour),
+		JWTAlgorithm:           getEnv("JWT_ALGORITHM", "EdDSA"),
+		JWTKeyRotationInterval: parseDuration(getEnv("JWT_KEY_ROTATION_INTERVAL", "720h"), 30*24*time.Hour),
+		JWTSecret:             getEnv("JWT_SECRET", ""),
+		JWTExpiresIn:          parseDuration(getEnv("JWT_EXPIRES_IN", "15m"), 15*time.Minute),
+		RefreshTokenExpiresIn: parseDuration(getEnv("REFRESH_TOKEN_EXPIRES_IN", "720h"), 30*24*time.Hour),
+
+		SetupToken: getEnv("SETUP_TOKEN", ""),
+
+		PasswordMinLength:      parseInt(getEnv("PASSWORD_MIN_LENGTH", "10"), 10),
+		PasswordRequireMixed:   parseBool(getEnv("PASSWORD_REQUIRE_MIXED_CASE", "true"), true),
+		PasswordRequireNumber:  parseBool(getEnv("PASSWORD_REQUIRE_NUMBER", "true"), true),
+		PasswordRequireSymbol:  parseBool(getEnv("PASSWORD_REQUIRE_SYMBOL", "false"), false),
+		PasswordResetExpiresIn: parseDuration(getEnv("PASSWORD_RESET_EXPIRES_IN", "1h"), time.Hour),
+
+		MaxFailedLogins: parseInt(getEnv("MAX_FAILED_LOGINS", "5"), 5),
+		LockoutDuration: parseDuration(getEnv("LOCKOUT_DURATION", "15m"), 15*time.Minute),
 
 		AWSAccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
 		AWSSecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
@@ -100,13 +157,82 @@ func LoadConfig() *Config {
 		RateLimitWindow:   parseDuration(getEnv("RATE_LIMIT_WINDOW", "900s"), 15*time.Minute),
 
 		MaxFileSize:       parseFileSize(getEnv("MAX_FILE_SIZE", "50MB"), 50*1024*1024),
-		AllowedImageTypes: parseStringSlice(getEnv("ALLOWED_IMAGE_TYPES", "jpg,jpeg,png,webp")),
+		AllowedImageTypes: parseStringSlice(getEnv("ALLOWED_IMAGE_TYPES", "jpg,jpeg,png,gif,webp")),
 		AllowedVideoTypes: parseStringSlice(getEnv("ALLOWED_VIDEO_TYPES", "mp4,mov,avi")),
+
+		DuplicateUploads: getEnv("DUPLICATE_UPLOADS", "warn"),
+
+		MaxUploadSize:   parseFileSize(getEnv("MAX_UPLOAD_SIZE", "10GB"), 10*1024*1024*1024),
+		UploadExpiresIn: parseDuration(getEnv("UPLOAD_EXPIRES_IN", "24h"), 24*time.Hour),
+
+		TrashRetention: parseDuration(getEnv("TRASH_RETENTION", "720h"), 30*24*time.Hour),
+
+		FFmpegPath:         getEnv("FFMPEG_PATH", "ffmpeg"),
+		FFprobePath:        getEnv("FFPROBE_PATH", "ffprobe"),
+		VideoPreviewLength: parseDuration(getEnv("VIDEO_PREVIEW_LENGTH", "6s"), 6*time.Second),
+		VideoHLS:           parseBool(getEnv("VIDEO_HLS", "true"), true),
+
+		StorageDir: getEnv("STORAGE_DIR", "uploads/storage"),
+		StorageURL: getEnv("STORAGE_URL", "/files"),
+
+		ImageSizes:     parseIntSlice(getEnv("IMAGE_SIZES", "160,320,480,640,800,1024,1280,1600,1920,2560")),
+		ImageURLSecret: getEnv("IMAGE_URL_SECRET", ""),
 	}
 
 	return cfg
 }
 
+// insecureJWTSecrets are placeholder secrets from example env files that must never be used in production
+var insecureJWTSecrets = []string{
+	"your-secret-key",
+	"your-jwt-secret-key",
+	"your-super-secret-jwt-key-change-this-in-production",
+}
+
+// minJWTSecretLength is the minimum accepted JWT secret length in production
+const minJWTSecretLength = 32
+
+// supportedJWTAlgorithms are the accepted values of JWT_ALGORITHM
+var supportedJWTAlgorithms = []string{"HS256", "RS256", "EdDSA"}
+
+// Validate checks the configuration for insecure settings.
+// Production refuses to start with a missing, short or placeholder JWT secret
+// when tokens are signed with HS256.
+func (c *Config) Validate() error {
+	supported := false
+	for _, algorithm := range supportedJWTAlgorithms {
+		if c.JWTAlgorithm == algorithm {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("JWT_ALGORITHM must be one of %s", strings.Join(supportedJWTAlgorithms, ", "))
+	}
+
+	if c.JWTSecret == "" {
+		if c.IsProduction() && c.UsesJWTSecret() {
+			return errors.New("JWT_SECRET must be set in production when JWT_ALGORITHM is HS256")
+		}
+		return nil
+	}
+
+	if !c.IsProduction() {
+		return nil
+	}
+
+	for _, insecure := range insecureJWTSecrets {
+		if c.JWTSecret == insecure {
+			return errors.New("JWT_SECRET is set to a placeholder value from the example configuration")
+		}
+	}
+
+	if len(c.JWTSecret) < minJWTSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d characters in production", minJWTSecretLength)
+	}
+
+	return nil
+}
+
 // getEnv gets an environment variable with a fallback value
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
@@ -123,6 +249,14 @@ func parseInt(s string, fallback int) int {
 	return fallback
 }
 
+// parseBool parses a string to bool with fallback
+func parseBool(s string, fallback bool) bool {
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b
+	}
+	return fallback
+}
+
 // parseDuration parses a string to time.Duration with fallback
 func parseDuration(s string, fallback time.Duration) time.Duration {
 	if d, err := time.ParseDuration(s); err == nil {
@@ -133,12 +267,17 @@ func parseDuration(s string, fallback time.Duration) time.Duration {
 
 // parseFileSize parses file size string (e.g., "50MB") to bytes
 func parseFileSize(s string, fallback int64) int64 {
-	// Simple parsing for MB
+	// Simple parsing for MB and GB
 	if len(s) > 2 && s[len(s)-2:] == "MB" {
 		if size, err := strconv.ParseInt(s[:len(s)-2], 10, 64); err == nil {
 			return size * 1024 * 1024
 		}
 	}
+	if len(s) > 2 && s[len(s)-2:] == "GB" {
+		if size, err := strconv.ParseInt(s[:len(s)-2], 10, 64); err == nil {
+			return size * 1024 * 1024 * 1024
+		}
+	}
 	return fallback
 }
 
@@ -156,6 +295,22 @@ func parseStringSlice(s string) []string {
 	return result
 }
 
+// parseIntSlice parses comma-separated integers, skipping invalid ones
+func parseIntSlice(s string) []int {
+	result := []int{}
+	for _, item := range parseStringSlice(s) {
+		if i, err := strconv.Atoi(item); err == nil && i > 0 {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// UsesJWTSecret checks if tokens are signed with the shared JWT_SECRET
+func (c *Config) UsesJWTSecret() bool {
+	return c.JWTAlgorithm == "HS256"
+}
+
 // IsProduction checks if the environment is production
 func (c *Config) IsProduction() bool {
 	return c.Environment == "production"
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"photography-portfolio/models"
-	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -48,33 +47,18 @@ func (h *AdminHandler) GetDashboard(c *fiber.Ctx) error {
 
 // GetContactMessages returns all contact messages with pagination
 func (h *AdminHandler) GetContactMessages(c *fiber.Ctx) error {
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
-	status := c.Query("status", "") // "read", "unread", or "" for all
+	filter := parseContactFilter(c) // ?status=read or unread, or all
 
-	offset := (page - 1) * limit
-
-	var messages []models.ContactMessage
-	var total int64
-
-	query := h.db.Model(&models.ContactMessage{})
-
-	// Filter by read status if specified
-	if status == "read" {
-		query = query.Where("is_read = ?", true)
-	} else if status == "unread" {
-		query = query.Where("is_read = ?", false)
+	list, err := newListQuery(contactListSpec, filter.Page, filter.PageSize, filter.SortBy, filter.SortOrder, c.Query("cursor"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
 	}
 
-	// Get total count
-	query.Count(&total)
-
-	// Get paginated results
-	err := query.Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&messages).Error
-
+	var messages []models.ContactMessage
+	pagination, err := list.Find(applyContactFilter(h.db.Model(&models.ContactMessage{}), filter), &messages)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"success": false,
@@ -84,13 +68,7 @@ func (h *AdminHandler) GetContactMessages(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"data": fiber.Map{
-			"messages": messages,
-			"total":    total,
-			"page":     page,
-			"limit":    limit,
-			"pages":    (total + int64(limit) - 1) / int64(limit),
-		},
+		"data":    listResponse("messages", messages, pagination),
 	})
 }
 
@@ -112,6 +90,7 @@ func (h *AdminHandler) MarkMessageAsRead(c *fiber.Ctx) error {
 		})
 	}
 
+	before := message
 	message.IsRead = true
 	if err := h.db.Save(&message).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -120,6 +99,8 @@ func (h *AdminHandler) MarkMessageAsRead(c *fiber.Ctx) error {
 		})
 	}
 
+	recordAudit(h.db, c, "message.mark_read", models.AuditEntityMessage, message.ID, before, message)
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Message marked as read",
@@ -145,6 +126,7 @@ func (h *AdminHandler) MarkMessageAsUnread(c *fiber.Ctx) error {
 		})
 	}
 
+	before := message
 	message.IsRead = false
 	if err := h.db.Save(&message).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -153,6 +135,8 @@ func (h *AdminHandler) MarkMessageAsUnread(c *fiber.Ctx) error {
 		})
 	}
 
+	recordAudit(h.db, c, "message.mark_unread", models.AuditEntityMessage, message.ID, before, message)
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Message marked as unread",
@@ -185,12 +169,47 @@ func (h *AdminHandler) DeleteMessage(c *fiber.Ctx) error {
 		})
 	}
 
+	recordAudit(h.db, c, "message.delete", models.AuditEntityMessage, message.ID, message, nil)
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Message deleted successfully",
 	})
 }
 
+// DeleteBooking moves a booking to the trash
+func (h *AdminHandler) DeleteBooking(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var booking models.Booking
+	if err := h.db.First(&booking, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(404).JSON(fiber.Map{
+				"success": false,
+				"message": "Booking not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Database error",
+		})
+	}
+
+	if err := h.db.Delete(&booking).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to delete booking",
+		})
+	}
+
+	recordAudit(h.db, c, "booking.delete", models.AuditEntityBooking, booking.ID, booking, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Booking moved to trash",
+	})
+}
+
 // GetAnalytics returns admin analytics data
 func (h *AdminHandler) GetAnalytics(c *fiber.Ctx) error {
 	var analytics struct {
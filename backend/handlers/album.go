@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"strings"
+
+	"photography-portfolio/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type AlbumHandler struct {
+	db *gorm.DB
+}
+
+func NewAlbumHandler(db *gorm.DB) *AlbumHandler {
+	return &AlbumHandler{db: db}
+}
+
+// GetAlbums returns all albums with their media counts
+func (h *AlbumHandler) GetAlbums(c *fiber.Ctx) error {
+	var albums []models.Album
+	if err := h.db.Order("created_at DESC").Find(&albums).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch albums",
+		})
+	}
+
+	var counts []struct {
+		AlbumID uint
+		Count   int64
+	}
+	h.db.Model(&models.Media{}).
+		Select("album_id, COUNT(*) AS count").
+		Where("album_id IS NOT NULL").
+		Group("album_id").
+		Scan(&counts)
+
+	byAlbum := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		byAlbum[count.AlbumID] = count.Count
+	}
+	for i := range albums {
+		albums[i].MediaCount = byAlbum[albums[i].ID]
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    albums,
+	})
+}
+
+// CreateAlbum creates an album
+func (h *AlbumHandler) CreateAlbum(c *fiber.Ctx) error {
+	var req models.AlbumRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || len(req.Title) > 255 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Title is required and must be at most 255 characters",
+		})
+	}
+
+	album := models.Album{
+		Title:        req.Title,
+		Description:  req.Description,
+		CoverMediaID: req.CoverMediaID,
+	}
+	if err := h.db.Create(&album).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create album",
+		})
+	}
+
+	// is_public defaults to true in the database, so false has to be set after creating
+	if req.IsPublic != nil && !*req.IsPublic {
+		album.IsPublic = false
+		h.db.Model(&album).Update("is_public", false)
+	}
+
+	recordAudit(h.db, c, "album.create", models.AuditEntityAlbum, album.ID, nil, album)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Album created successfully",
+		"data":    album,
+	})
+}
+
+// UpdateAlbum updates an album's details
+func (h *AlbumHandler) UpdateAlbum(c *fiber.Ctx) error {
+	var album models.Album
+	if err := h.db.First(&album, c.Params("id")).Error; err != nil {
+		return albumLookupError(c, err)
+	}
+
+	var req models.AlbumRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	before := album
+	if title := strings.TrimSpace(req.Title); title != "" {
+		if len(title) > 255 {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "Title must be at most 255 characters",
+			})
+		}
+		album.Title = title
+	}
+	if req.Description != "" {
+		album.Description = req.Description
+	}
+	if req.IsPublic != nil {
+		album.IsPublic = *req.IsPublic
+	}
+	if req.CoverMediaID != nil {
+		album.CoverMediaID = req.CoverMediaID
+	}
+
+	if err := h.db.Save(&album).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update album",
+		})
+	}
+
+	invalidateFeatured()
+	recordAudit(h.db, c, "album.update", models.AuditEntityAlbum, album.ID, before, album)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Album updated successfully",
+		"data":    album,
+	})
+}
+
+// DeleteAlbum deletes an album. Its media is kept and no longer belongs to an album.
+func (h *AlbumHandler) DeleteAlbum(c *fiber.Ctx) error {
+	var album models.Album
+	if err := h.db.First(&album, c.Params("id")).Error; err != nil {
+		return albumLookupError(c, err)
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Media{}).
+			Where("album_id = ?", album.ID).
+			Update("album_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&album).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to delete album",
+		})
+	}
+
+	invalidateFeatured()
+	recordAudit(h.db, c, "album.delete", models.AuditEntityAlbum, album.ID, album, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Album deleted successfully",
+	})
+}
+
+// albumLookupError writes the response for a failed album lookup
+func albumLookupError(c *fiber.Ctx, err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Album not found",
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"success": false,
+		"message": "Database error",
+	})
+}
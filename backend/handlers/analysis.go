@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"image"
+	"log"
+
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+)
+
+// analyzeImage stores the estimated focal point, the colors and the EXIF data
+// of an image that has none yet, such as images imported by script, uploaded
+// before image analysis existed or reset to an automatic focal point
+func (h *MediaHandler) analyzeImage(mediaID uint) error {
+	var media models.Media
+	if err := h.db.First(&media, mediaID).Error; err != nil || !media.IsImage() {
+		return err
+	}
+	needsFocus := media.FocalSource == ""
+	needsColors := media.BlurHash == ""
+	if media.Exif == "" {
+		if err := h.readExif(&media); err != nil {
+			return err
+		}
+	}
+	if !needsFocus && !needsColors {
+		return nil
+	}
+
+	imageRenderSlots <- struct{}{}
+	defer func() { <-imageRenderSlots }()
+
+	file, err := openUploadedFile(media.FileName)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return errImageNotDecodable
+	}
+
+	if needsFocus {
+		focus := utils.EstimateFocalPoint(img)
+		// An admin may have set the focal point while the image was analysed
+		if err := h.db.Model(&models.Media{}).
+			Where("id = ? AND (focal_source = '' OR focal_source IS NULL)", media.ID).
+			UpdateColumns(map[string]interface{}{
+				"focal_x":      focus.X,
+				"focal_y":      focus.Y,
+				"focal_source": models.FocalSourceAuto,
+			}).Error; err != nil {
+			return err
+		}
+	}
+
+	if needsColors {
+		colors := utils.AnalyzeColors(img)
+		media.SetPalette(colors.Palette)
+		if err := h.db.Model(&media).UpdateColumns(map[string]interface{}{
+			"palette":       media.Palette,
+			"average_color": colors.Average,
+			"blur_hash":     colors.BlurHash,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readExif stores the EXIF data of an image, or an empty object when it has none
+func (h *MediaHandler) readExif(media *models.Media) error {
+	exif := &models.MediaExif{}
+	if media.MimeType == "image/jpeg" {
+		file, err := openUploadedFile(media.FileName)
+		if err != nil {
+			return err
+		}
+		exif, err = utils.ReadExif(file)
+		file.Close()
+		if err != nil {
+			exif = &models.MediaExif{}
+		}
+	}
+	media.SetExif(exif)
+	return h.db.Model(media).UpdateColumn("exif", media.Exif).Error
+}
+
+// BackfillImageAnalysis estimates focal points and extracts colors and EXIF
+// data of images uploaded before image analysis existed
+func (h *MediaHandler) BackfillImageAnalysis() {
+	var ids []uint
+	h.db.Model(&models.Media{}).
+		Where("type = ? AND mime_type <> ?", models.MediaTypeImage, "image/webp").
+		Where("focal_source = '' OR focal_source IS NULL OR blur_hash = '' OR blur_hash IS NULL OR exif = '' OR exif IS NULL").
+		Pluck("id", &ids)
+
+	analyzed := 0
+	for _, id := range ids {
+		if err := h.analyzeImage(id); err != nil {
+			log.Printf("Failed to analyze image %d: %v", id, err)
+			continue
+		}
+		analyzed++
+	}
+	if analyzed > 0 {
+		log.Printf("🎯 Analyzed focal points, colors and EXIF data of %d images", analyzed)
+	}
+}
+
+// mediaFocalPoint returns the point cover crops of an image keep in view,
+// estimating it from the decoded image when none is stored yet
+func mediaFocalPoint(media *models.Media, img image.Image) *utils.FocalPoint {
+	if media.FocalSource != "" {
+		return &utils.FocalPoint{X: media.FocalX, Y: media.FocalY}
+	}
+	focus := utils.EstimateFocalPoint(img)
+	return &focus
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"photography-portfolio/config"
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type APIKeyHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewAPIKeyHandler(db *gorm.DB, cfg *config.Config) *APIKeyHandler {
+	return &APIKeyHandler{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// GetAPIKeys returns the current user's API keys
+func (h *APIKeyHandler) GetAPIKeys(c *fiber.Ctx) error {
+	userID, _ := middleware.GetUserIDFromContext(c)
+
+	var keys []models.APIKey
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch API keys",
+		})
+	}
+
+	responses := make([]models.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		responses = append(responses, key.ToResponse())
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"api_keys": responses,
+			"scopes":   models.GetAPIKeyScopes(),
+		},
+	})
+}
+
+// CreateAPIKey creates a scoped API key for the current user. The full key is
+// only returned in this response.
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	var req models.APIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Name is required and must be at most 100 characters",
+		})
+	}
+
+	if len(req.Scopes) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "At least one scope is required",
+		})
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	role, _ := middleware.GetUserRoleFromContext(c)
+
+	// Keys can only carry scopes the owner's role already grants
+	for _, scope := range req.Scopes {
+		if !models.ValidateAPIKeyScope(scope) {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "Invalid scope: " + string(scope),
+			})
+		}
+		if !models.Role(role).HasPermission(scope) {
+			return c.Status(403).JSON(fiber.Map{
+				"success": false,
+				"message": "Your role does not grant the scope: " + string(scope),
+			})
+		}
+	}
+
+	for i, entry := range req.AllowedIPs {
+		req.AllowedIPs[i] = strings.TrimSpace(entry)
+		if !models.ValidateIPAllowlistEntry(req.AllowedIPs[i]) {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "Invalid IP address or CIDR range: " + entry,
+			})
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Expiry must be in the future",
+		})
+	}
+
+	prefix, err := utils.GenerateRandomToken(6)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate API key",
+		})
+	}
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate API key",
+		})
+	}
+
+	apiKey := models.APIKey{
+		Name:       req.Name,
+		Prefix:     models.APIKeyPrefix + prefix,
+		SecretHash: utils.HashToken(secret),
+		Scopes:     req.Scopes,
+		AllowedIPs: req.AllowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+		UserID:     userID,
+	}
+
+	if err := h.db.Create(&apiKey).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create API key",
+		})
+	}
+
+	recordAudit(h.db, c, "api_key.create", models.AuditEntityAPIKey, apiKey.ID, nil, apiKey.ToResponse())
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "API key created. Copy it now, it will not be shown again.",
+		"data": fiber.Map{
+			"api_key": apiKey.ToResponse(),
+			"key":     apiKey.Prefix + "_" + secret,
+		},
+	})
+}
+
+// RevokeAPIKey revokes one of the current user's API keys
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	userID, _ := middleware.GetUserIDFromContext(c)
+	return h.revoke(c, h.db.Where("id = ? AND user_id = ?", c.Params("id"), userID))
+}
+
+// GetAllAPIKeys returns the API keys of all users (owner only)
+func (h *APIKeyHandler) GetAllAPIKeys(c *fiber.Ctx) error {
+	query := h.db.Preload("User")
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if c.Query("active") == "true" {
+		query = query.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now())
+	}
+
+	var keys []models.APIKey
+	if err := query.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch API keys",
+		})
+	}
+
+	responses := make([]models.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		responses = append(responses, key.ToResponse())
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    responses,
+	})
+}
+
+// AdminRevokeAPIKey revokes any user's API key (owner only)
+func (h *APIKeyHandler) AdminRevokeAPIKey(c *fiber.Ctx) error {
+	return h.revoke(c, h.db.Where("id = ?", c.Params("id")))
+}
+
+// revoke marks the API key matched by the scoped query as revoked
+func (h *APIKeyHandler) revoke(c *fiber.Ctx, scope *gorm.DB) error {
+	result := scope.Model(&models.APIKey{}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to revoke API key",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "API key not found",
+		})
+	}
+
+	recordAudit(h.db, c, "api_key.revoke", models.AuditEntityAPIKey, c.Params("id"), nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "API key revoked",
+	})
+}
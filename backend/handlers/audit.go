@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type AuditHandler struct {
+	db *gorm.DB
+}
+
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+// GetAuditEvents returns audit events, newest first (owner only).
+// Filters: actor_type, actor_id, action (a trailing "." matches a prefix such as "media."),
+// entity_type, entity_id, ip_address, from and to (YYYY-MM-DD or RFC 3339).
+func (h *AuditHandler) GetAuditEvents(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "50"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	query := h.db.Model(&models.AuditEvent{})
+	if actorType := c.Query("actor_type"); actorType != "" {
+		query = query.Where("actor_type = ?", actorType)
+	}
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if action := c.Query("action"); action != "" {
+		if strings.HasSuffix(action, ".") {
+			query = query.Where("action LIKE ?", action+"%")
+		} else {
+			query = query.Where("action = ?", action)
+		}
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if ip := c.Query("ip_address"); ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+	if from := c.Query("from"); from != "" {
+		date, err := parseAuditTime(from)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "Invalid from date",
+			})
+		}
+		query = query.Where("created_at >= ?", date)
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := parseAuditTime(to)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "Invalid to date",
+			})
+		}
+		// A plain date includes the whole day
+		if len(to) == len("2006-01-02") {
+			date = date.AddDate(0, 0, 1)
+		}
+		query = query.Where("created_at < ?", date)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var events []models.AuditEvent
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&events).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch audit events",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"events":      events,
+			"total_count": total,
+			"page":        page,
+			"page_size":   pageSize,
+			"total_pages": int((total + int64(pageSize) - 1) / int64(pageSize)),
+		},
+	})
+}
+
+// recordAudit records an action performed by the authenticated user or API key
+func recordAudit(db *gorm.DB, c *fiber.Ctx, action, entityType string, entityID interface{}, before, after interface{}) {
+	event := &models.AuditEvent{
+		ActorType:  models.AuditActorUser,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Changes:    models.DiffForAudit(before, after),
+		IPAddress:  c.IP(),
+		UserAgent:  requestUserAgent(c),
+	}
+
+	if userID, ok := middleware.GetUserIDFromContext(c); ok {
+		event.ActorID = &userID
+	}
+	event.ActorName, _ = middleware.GetUserEmailFromContext(c)
+
+	if apiKey, ok := middleware.GetAPIKeyFromContext(c); ok {
+		event.ActorType = models.AuditActorAPIKey
+		event.Metadata = map[string]interface{}{
+			"api_key_id":   apiKey.ID,
+			"api_key_name": apiKey.Name,
+		}
+	}
+
+	models.RecordAuditEvent(db, event)
+}
+
+// recordExternalAudit records an action performed by a client, webhook or background job
+func recordExternalAudit(db *gorm.DB, c *fiber.Ctx, actorType models.AuditActorType, actorName, action, entityType string, entityID interface{}, before, after interface{}) {
+	event := &models.AuditEvent{
+		ActorType:  actorType,
+		ActorName:  actorName,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Changes:    models.DiffForAudit(before, after),
+	}
+	if c != nil {
+		event.IPAddress = c.IP()
+		event.UserAgent = requestUserAgent(c)
+	}
+
+	models.RecordAuditEvent(db, event)
+}
+
+// parseAuditTime parses a date or RFC 3339 timestamp filter
+func parseAuditTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return parseISODate(value)
+}
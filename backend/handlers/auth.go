@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"log"
+	"strings"
+	"time"
+
 	"photography-portfolio/config"
+	"photography-portfolio/middleware"
 	"photography-portfolio/models"
 	"photography-portfolio/utils"
 
@@ -10,14 +15,16 @@ import (
 )
 
 type AuthHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db   *gorm.DB
+	cfg  *config.Config
+	keys *utils.KeyManager
 }
 
-func NewAuthHandler(db *gorm.DB, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, cfg *config.Config, keys *utils.KeyManager) *AuthHandler {
 	return &AuthHandler{
-		db:  db,
-		cfg: cfg,
+		db:   db,
+		cfg:  cfg,
+		keys: keys,
 	}
 }
 
@@ -31,10 +38,12 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Find user by email
+	// Find user by email, ignoring case like the password reset does
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
 	var user models.User
-	if err := h.db.Where("email = ? AND is_active = ?", req.Email, true).First(&user).Error; err != nil {
+	if err := h.db.Where("LOWER(email) = ? AND is_active = ?", req.Email, true).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
+			h.recordLoginAttempt(c, req.Email, nil, false, models.LoginFailureUnknownUser)
 			return c.Status(401).JSON(fiber.Map{
 				"success": false,
 				"message": "Invalid email or password",
@@ -46,22 +55,39 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	// Locked accounts are rejected before the password is checked
+	if user.IsLocked() {
+		h.recordLoginAttempt(c, user.Email, &user.ID, false, models.LoginFailureAccountLocked)
+		return accountLockedError(c)
+	}
+
 	// Check password
 	if !user.CheckPassword(req.Password) {
+		if h.registerFailedLogin(c, &user, models.LoginFailureBadPassword) {
+			return accountLockedError(c)
+		}
 		return c.Status(401).JSON(fiber.Map{
 			"success": false,
 			"message": "Invalid email or password",
 		})
 	}
 
+	// Accounts with two-factor authentication need a second step
+	if user.TOTPEnabled {
+		return h.challengeResponse(c, &user, utils.ChallengeTwoFactorVerify, "Two-factor authentication required")
+	}
+	if models.GetBoolSetting(h.db, models.SettingRequireTwoFactor, false) {
+		return h.challengeResponse(c, &user, utils.ChallengeTwoFactorEnroll, "Two-factor authentication must be set up before logging in")
+	}
+
 	// Update last login time
 	if err := user.UpdateLastLogin(h.db); err != nil {
 		// Log error but don't fail the login
 		// log.Printf("Failed to update last login: %v", err)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID, user.Email, user.Role, h.cfg.JWTSecret, h.cfg.JWTExpiresIn)
+	// Start a new session and issue tokens
+	tokens, err := h.createSession(c, &user)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"success": false,
@@ -73,24 +99,225 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Login successful",
+		"data":    tokens,
+	})
+}
+
+// Refresh rotates the refresh token and issues a new access token
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Refresh token is required",
+		})
+	}
+
+	tokenHash := utils.HashToken(req.RefreshToken)
+
+	var session models.Session
+	if err := h.db.Joins("User").Where("sessions.refresh_token_hash = ?", tokenHash).First(&session).Error; err != nil {
+		// A rotated token being presented again means it was leaked; revoke that session
+		var reused models.Session
+		if h.db.Where("previous_token_hash = ? AND revoked_at IS NULL", tokenHash).First(&reused).Error == nil {
+			log.Printf("⚠️  Refresh token reuse detected for session %d, revoking", reused.ID)
+			h.db.Model(&reused).Update("revoked_at", time.Now())
+		}
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid refresh token",
+		})
+	}
+
+	if !session.IsActive() || !session.User.IsActive {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Session has been revoked or expired",
+		})
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate token",
+		})
+	}
+
+	now := time.Now()
+	result := h.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", session.ID, tokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  utils.HashToken(refreshToken),
+			"previous_token_hash": tokenHash,
+			"last_used_at":        now,
+			"ip_address":          c.IP(),
+			"expires_at":          now.Add(h.cfg.RefreshTokenExpiresIn),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		// Another request rotated the token concurrently
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid refresh token",
+		})
+	}
+
+	accessToken, err := utils.GenerateJWT(session.User.ID, session.User.Email, string(session.User.Role), session.ID, h.keys, h.cfg.JWTExpiresIn)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
 		"data": fiber.Map{
-			"user":  user.ToResponse(),
-			"token": token,
+			"user":          session.User.ToResponse(),
+			"token":         accessToken,
+			"refresh_token": refreshToken,
+			"expires_in":    int(h.cfg.JWTExpiresIn.Seconds()),
 		},
 	})
 }
 
-// Logout handles user logout
+// Logout revokes the current session
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
-	// In a JWT-based system, logout is typically handled client-side
-	// by removing the token. Server-side logout would require token blacklisting.
-	// For now, we'll just return a success response.
+	sessionID, ok := middleware.GetSessionIDFromContext(c)
+	if !ok {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Session not found in token",
+		})
+	}
+
+	if err := h.db.Model(&models.Session{}).Where("id = ?", sessionID).Update("revoked_at", time.Now()).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to revoke session",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Logout successful",
 	})
 }
 
+// LogoutAll revokes every active session of the current user
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	userID, _ := middleware.GetUserIDFromContext(c)
+
+	result := h.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to revoke sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":       true,
+		"message":       "Logged out of all devices",
+		"revoked_count": result.RowsAffected,
+	})
+}
+
+// GetSessions returns the current user's active sessions
+func (h *AuthHandler) GetSessions(c *fiber.Ctx) error {
+	userID, _ := middleware.GetUserIDFromContext(c)
+	currentID, _ := middleware.GetSessionIDFromContext(c)
+
+	var sessions []models.Session
+	if err := h.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch sessions",
+		})
+	}
+
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, session.ToResponse(currentID))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    responses,
+	})
+}
+
+// RevokeSession revokes one of the current user's sessions
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID, _ := middleware.GetUserIDFromContext(c)
+
+	result := h.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Params("id"), userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to revoke session",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Session not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Session revoked",
+	})
+}
+
+// createSession stores a new session for the user and returns the login payload
+// with a short-lived access token and a refresh token
+func (h *AuthHandler) createSession(c *fiber.Ctx, user *models.User) (fiber.Map, error) {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := requestUserAgent(c)
+
+	now := time.Now()
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		Device:           models.DescribeDevice(userAgent),
+		UserAgent:        userAgent,
+		IPAddress:        c.IP(),
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(h.cfg.RefreshTokenExpiresIn),
+	}
+	if err := h.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateJWT(user.ID, user.Email, string(user.Role), session.ID, h.keys, h.cfg.JWTExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+
+	// A completed login clears the failed attempt counter
+	h.clearFailedLogins(user)
+	h.recordLoginAttempt(c, user.Email, &user.ID, true, "")
+
+	return fiber.Map{
+		"user":          user.ToResponse(),
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.cfg.JWTExpiresIn.Seconds()),
+	}, nil
+}
+
 // GetProfile returns the current user's profile
 func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
 	// Get user ID from JWT token (set by auth middleware)
@@ -136,4 +363,10 @@ func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
 		"success": true,
 		"data":    user.ToResponse(),
 	})
-} 
\ No newline at end of file
+}
+
+// GetJWKS publishes the public keys that verify access tokens
+func (h *AuthHandler) GetJWKS(c *fiber.Ctx) error {
+	c.Set("Cache-Control", "public, max-age=300")
+	return c.JSON(h.keys.JWKS())
+}
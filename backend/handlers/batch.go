@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxBatchFiles      = 200
+	batchUploadWorkers = 4
+)
+
+// batchUploadResult reports the outcome for one file of a batch upload
+type batchUploadResult struct {
+	Index       int           `json:"index"`
+	FileName    string        `json:"file_name"`
+	Success     bool          `json:"success"`
+	Media       *models.Media `json:"media,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Warning     string        `json:"warning,omitempty"`
+	DuplicateOf *uint         `json:"duplicate_of,omitempty"`
+}
+
+// UploadMediaBatch uploads several files sent as "files" form fields. The
+// category, tags, album_id and is_featured fields apply to every file and each
+// file is titled after its name. A failed file does not fail the batch, the
+// response reports the result of every file in order. The request is limited
+// by the body size limit, larger batches send their files one at a time with
+// CreateUploadBatch and UploadBatchFile.
+func (h *MediaHandler) UploadMediaBatch(c *fiber.Ctx) error {
+	shared, allowDuplicates, err := h.parseBatchSettings(c)
+	if err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "No files uploaded",
+		})
+	}
+
+	files := form.File["files"]
+	if len(files) > maxBatchFiles {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("A batch can contain at most %d files", maxBatchFiles),
+		})
+	}
+	var totalSize int64
+	for _, file := range files {
+		totalSize += file.Size
+	}
+	if totalSize > h.cfg.MaxUploadSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("A batch is limited to %d bytes", h.cfg.MaxUploadSize),
+		})
+	}
+
+	if err := os.MkdirAll("uploads/media", 0755); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create upload directory",
+		})
+	}
+
+	// Process files with a bounded number of workers
+	results := make([]batchUploadResult, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < batchUploadWorkers && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = h.storeBatchFile(i, files[i], shared, allowDuplicates)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	uploaded := 0
+	for _, result := range results {
+		if result.Success {
+			uploaded++
+			recordAudit(h.db, c, "media.create", models.AuditEntityMedia, result.Media.ID, nil, result.Media)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Uploaded %d of %d files", uploaded, len(files)),
+		"data": fiber.Map{
+			"results":  results,
+			"uploaded": uploaded,
+			"failed":   len(files) - uploaded,
+		},
+	})
+}
+
+// CreateUploadBatch starts a batch whose files are uploaded one request at a
+// time with UploadBatchFile. It takes the same category, tags, album_id,
+// is_featured and allow_duplicate fields as UploadMediaBatch.
+func (h *MediaHandler) CreateUploadBatch(c *fiber.Ctx) error {
+	shared, allowDuplicates, err := h.parseBatchSettings(c)
+	if err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	batch := models.UploadBatch{
+		ID:             uuid.New().String(),
+		Category:       shared.Category,
+		Tags:           shared.Tags,
+		AlbumID:        shared.AlbumID,
+		IsFeatured:     shared.IsFeatured,
+		AllowDuplicate: allowDuplicates,
+		ExpiresAt:      time.Now().Add(h.cfg.UploadExpiresIn),
+		UserID:         userID,
+	}
+	if err := h.db.Create(&batch).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create batch",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"batch":     batch,
+			"max_files": maxBatchFiles,
+			"max_size":  h.cfg.MaxUploadSize,
+		},
+	})
+}
+
+// UploadBatchFile uploads one file of a batch, sent as the "file" form field
+// with its position in the batch as "index". Files count towards the batch's
+// file and size limits once they are accepted.
+func (h *MediaHandler) UploadBatchFile(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "No file uploaded",
+		})
+	}
+	index, _ := strconv.Atoi(c.FormValue("index"))
+
+	// Claim room in the batch before storing, so concurrent requests cannot
+	// exceed its limits
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var batch models.UploadBatch
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ? AND expires_at > ?", c.Params("id"), userID, time.Now()).
+			First(&batch).Error; err != nil {
+			return &uploadError{fiber.StatusNotFound, "Batch not found"}
+		}
+		result := tx.Model(&models.UploadBatch{}).
+			Where("id = ? AND file_count < ? AND total_size + ? <= ?", batch.ID, maxBatchFiles, file.Size, h.cfg.MaxUploadSize).
+			Updates(map[string]interface{}{
+				"file_count": gorm.Expr("file_count + 1"),
+				"total_size": gorm.Expr("total_size + ?", file.Size),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &uploadError{fiber.StatusRequestEntityTooLarge, fmt.Sprintf("A batch can contain at most %d files and %d bytes", maxBatchFiles, h.cfg.MaxUploadSize)}
+		}
+		return nil
+	})
+	if err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	if err := os.MkdirAll("uploads/media", 0755); err != nil {
+		h.releaseBatchFile(batch.ID, file.Size)
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create upload directory",
+		})
+	}
+
+	shared := storedMedia{
+		Category:   batch.Category,
+		Tags:       batch.Tags,
+		AlbumID:    batch.AlbumID,
+		IsFeatured: batch.IsFeatured,
+		UserID:     batch.UserID,
+	}
+	result := h.storeBatchFile(index, file, shared, batch.AllowDuplicate)
+	if !result.Success {
+		h.releaseBatchFile(batch.ID, file.Size)
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": result.Error,
+			"data":    result,
+		})
+	}
+
+	recordAudit(h.db, c, "media.create", models.AuditEntityMedia, result.Media.ID, nil, result.Media)
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// releaseBatchFile gives the room claimed by a file that was not stored back to its batch
+func (h *MediaHandler) releaseBatchFile(batchID string, size int64) {
+	h.db.Model(&models.UploadBatch{}).Where("id = ?", batchID).Updates(map[string]interface{}{
+		"file_count": gorm.Expr("file_count - 1"),
+		"total_size": gorm.Expr("total_size - ?", size),
+	})
+}
+
+// parseBatchSettings reads the category, tags, album_id, is_featured and
+// allow_duplicate form fields shared by the files of a batch
+func (h *MediaHandler) parseBatchSettings(c *fiber.Ctx) (storedMedia, bool, error) {
+	category := c.FormValue("category")
+	if !models.ValidateCategory(category) {
+		return storedMedia{}, false, &uploadError{fiber.StatusBadRequest, "Invalid category. Must be one of: athletes, food, nature, portraits, action"}
+	}
+
+	albumID, err := h.findAlbumID(c.FormValue("album_id"))
+	if err != nil {
+		return storedMedia{}, false, &uploadError{fiber.StatusBadRequest, "Album not found"}
+	}
+
+	isFeatured, _ := strconv.ParseBool(c.FormValue("is_featured", "false"))
+	userID, _ := middleware.GetUserIDFromContext(c)
+	return storedMedia{
+		Category:   category,
+		Tags:       parseTagList(c.FormValue("tags")),
+		AlbumID:    albumID,
+		IsFeatured: isFeatured,
+		UserID:     userID,
+	}, c.FormValue("allow_duplicate") == "true", nil
+}
+
+// storeBatchFile saves one file of a batch and creates its media record
+func (h *MediaHandler) storeBatchFile(index int, file *multipart.FileHeader, shared storedMedia, allowDuplicate bool) batchUploadResult {
+	result := batchUploadResult{
+		Index:    index,
+		FileName: file.Filename,
+	}
+
+	fileExt := strings.ToLower(filepath.Ext(file.Filename))
+	if !utils.IsAllowedMediaExtension(h.cfg, fileExt) {
+		result.Error = h.invalidFileTypeMessage()
+		return result
+	}
+	if limit := utils.MaxMediaFileSize(h.cfg, fileExt); file.Size > limit {
+		result.Error = fmt.Sprintf("%s files are limited to %d MB", fileExt, limit/1024/1024)
+		return result
+	}
+
+	filename := fmt.Sprintf("%s%s", uuid.New().String(), fileExt)
+	filePath := filepath.Join("uploads/media", filename)
+	if err := saveUploadedFile(file, filePath); err != nil {
+		result.Error = "Failed to save file"
+		return result
+	}
+
+	info, err := h.validateStoredFile(filePath, fileExt)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	duplicate, reject := h.checkDuplicate(info, allowDuplicate)
+	if duplicate != nil {
+		result.DuplicateOf = &duplicate.ID
+		if reject {
+			os.Remove(filePath)
+			result.Error = duplicateMessage(duplicate)
+			return result
+		}
+		result.Warning = duplicateMessage(duplicate)
+	}
+
+	stored := shared
+	stored.Title = strings.TrimSuffix(filepath.Base(file.Filename), filepath.Ext(file.Filename))
+	stored.FileName = filename
+	stored.FileSize = file.Size
+	stored.Info = info
+
+	media, err := h.insertMedia(stored)
+	if err != nil {
+		result.Error = "Failed to save media record"
+		return result
+	}
+
+	result.Success = true
+	result.Media = media
+	return result
+}
+
+// findAlbumID checks that an album ID form value refers to an album. An empty
+// value means no album.
+func (h *MediaHandler) findAlbumID(value string) (*uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var album models.Album
+	if err := h.db.Select("id").First(&album, "id = ?", value).Error; err != nil {
+		return nil, err
+	}
+	return &album.ID, nil
+}
+
+// parseTagList splits a comma separated list of tags
+func parseTagList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return models.NormalizeTags(strings.Split(value, ","))
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultColorDistance matches colors that look alike at a glance
+	defaultColorDistance = 20.0
+	maxColorDistance     = 100.0
+	// paletteRankPenalty makes less common palette colors count as slightly
+	// further away, so images dominated by the color come first
+	paletteRankPenalty = 3.0
+)
+
+// colorFilter matches images with a palette color near a target color
+type colorFilter struct {
+	Color       string
+	MaxDistance float64
+}
+
+// parseColorFilter reads ?color=rrggbb&color_distance=20, or returns nil
+// when no color is requested. The returned error can be shown to users.
+func parseColorFilter(c *fiber.Ctx) (*colorFilter, error) {
+	value := c.Query("color")
+	if value == "" {
+		return nil, nil
+	}
+	color, ok := utils.NormalizeHexColor(value)
+	if !ok {
+		return nil, fmt.Errorf("color must be a hex color such as #c0563a")
+	}
+
+	filter := &colorFilter{Color: color, MaxDistance: defaultColorDistance}
+	if distance := c.Query("color_distance"); distance != "" {
+		parsed, err := strconv.ParseFloat(distance, 64)
+		if err != nil || parsed <= 0 || parsed > maxColorDistance {
+			return nil, fmt.Errorf("color_distance must be between 0 and %g", maxColorDistance)
+		}
+		filter.MaxDistance = parsed
+	}
+	return filter, nil
+}
+
+// findByColor returns a page of the media matched by the query whose palette
+// is near the filter color, the closest first, and the number of matches
+func findByColor(db, query *gorm.DB, filter *colorFilter, offset, limit int) ([]models.Media, int64, error) {
+	var candidates []models.Media
+	if err := query.Session(&gorm.Session{}).
+		Select("id", "palette").
+		Where("palette <> '' AND palette IS NOT NULL").
+		Find(&candidates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	type match struct {
+		id       uint
+		distance float64
+	}
+	var matches []match
+	for i := range candidates {
+		if distance, ok := paletteDistance(candidates[i].GetPalette(), filter.Color); ok && distance <= filter.MaxDistance {
+			matches = append(matches, match{id: candidates[i].ID, distance: distance})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].distance < matches[j].distance })
+
+	total := int64(len(matches))
+	if offset < 0 || limit < 1 || offset >= len(matches) {
+		return []models.Media{}, total, nil
+	}
+	matches = matches[offset:min(offset+limit, len(matches))]
+
+	ids := make([]uint, len(matches))
+	for i, m := range matches {
+		ids[i] = m.id
+	}
+	var media []models.Media
+	if err := db.Where("id IN ?", ids).Find(&media).Error; err != nil {
+		return nil, 0, err
+	}
+
+	position := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+	sort.Slice(media, func(i, j int) bool { return position[media[i].ID] < position[media[j].ID] })
+	return media, total, nil
+}
+
+// paletteDistance returns how close the nearest palette color is to a color
+func paletteDistance(palette []string, color string) (float64, bool) {
+	best, found := 0.0, false
+	for rank, paletteColor := range palette {
+		distance, ok := utils.ColorDistance(paletteColor, color)
+		if !ok {
+			continue
+		}
+		distance += float64(rank) * paletteRankPenalty
+		if !found || distance < best {
+			best, found = distance, true
+		}
+	}
+	return best, found
+}
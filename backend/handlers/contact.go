@@ -3,7 +3,6 @@ package handlers
 import (
 	"photography-portfolio/config"
 	"photography-portfolio/models"
-	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -105,52 +104,24 @@ func (h *ContactHandler) SubmitContact(c *fiber.Ctx) error {
 
 // GetMessages retrieves all contact messages (admin only)
 func (h *ContactHandler) GetMessages(c *fiber.Ctx) error {
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	pageSize, _ := strconv.Atoi(c.Query("page_size", "20"))
-	isRead := c.Query("is_read")
-	search := c.Query("search")
+	filter := parseContactFilter(c)
 
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
-	}
-
-	offset := (page - 1) * pageSize
-
-	query := h.db.Model(&models.ContactMessage{})
-
-	// Apply filters
-	if isRead != "" {
-		if isRead == "true" {
-			query = query.Where("is_read = ?", true)
-		} else {
-			query = query.Where("is_read = ?", false)
-		}
-	}
-
-	if search != "" {
-		search = "%" + search + "%"
-		query = query.Where("name ILIKE ? OR email ILIKE ? OR subject ILIKE ? OR message ILIKE ?",
-			search, search, search, search)
+	list, err := newListQuery(inboxListSpec, filter.Page, filter.PageSize, filter.SortBy, filter.SortOrder, c.Query("cursor"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Invalid list parameters",
+			"message": err.Error(),
+		})
 	}
 
-	// Get total count
-	var total int64
-	query.Count(&total)
-
 	// Get unread count
 	var unreadCount int64
 	h.db.Model(&models.ContactMessage{}).Where("is_read = ?", false).Count(&unreadCount)
 
 	// Get messages
 	var messages []models.ContactMessage
-	err := query.Order("created_at DESC").
-		Offset(offset).
-		Limit(pageSize).
-		Find(&messages).Error
-
+	pagination, err := list.Find(applyContactFilter(h.db.Model(&models.ContactMessage{}), filter), &messages)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
@@ -160,20 +131,15 @@ func (h *ContactHandler) GetMessages(c *fiber.Ctx) error {
 	}
 
 	// Convert to response format
-	var responses []models.ContactResponse
+	responses := make([]models.ContactResponse, 0, len(messages))
 	for _, message := range messages {
 		responses = append(responses, message.ToResponse())
 	}
 
-	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
-
 	response := models.ContactListResponse{
 		Messages:    responses,
-		TotalCount:  total,
 		UnreadCount: unreadCount,
-		Page:        page,
-		PageSize:    pageSize,
-		TotalPages:  totalPages,
+		Pagination:  pagination,
 	}
 
 	return c.JSON(fiber.Map{
@@ -206,6 +172,7 @@ func (h *ContactHandler) MarkAsRead(c *fiber.Ctx) error {
 		})
 	}
 
+	before := message
 	if err := message.MarkAsRead(h.db); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"success": false,
@@ -213,6 +180,8 @@ func (h *ContactHandler) MarkAsRead(c *fiber.Ctx) error {
 		})
 	}
 
+	recordAudit(h.db, c, "message.mark_read", models.AuditEntityMessage, message.ID, before, message)
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Message marked as read",
@@ -251,6 +220,8 @@ func (h *ContactHandler) DeleteMessage(c *fiber.Ctx) error {
 		})
 	}
 
+	recordAudit(h.db, c, "message.delete", models.AuditEntityMessage, message.ID, message, nil)
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Message deleted successfully",
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"photography-portfolio/config"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// contractDir is where signed contract PDFs are stored. It is kept outside
+// of the publicly served uploads directory.
+const contractDir = "data/contracts"
+
+// errContractNotPending is returned when a contract was signed or voided
+// while it was being signed
+var errContractNotPending = errors.New("contract is not pending")
+
+type ContractHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewContractHandler(db *gorm.DB, cfg *config.Config) *ContractHandler {
+	return &ContractHandler{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// GetTemplates returns all contract templates (admin only)
+func (h *ContractHandler) GetTemplates(c *fiber.Ctx) error {
+	var templates []models.ContractTemplate
+	if err := h.db.Order("name ASC").Find(&templates).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch contract templates",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    templates,
+	})
+}
+
+// CreateTemplate creates a new contract template (admin only)
+func (h *ContractHandler) CreateTemplate(c *fiber.Ctx) error {
+	var req models.ContractTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Body) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Name and body are required",
+		})
+	}
+
+	if req.ServiceType != "" && !models.ValidateServiceType(string(req.ServiceType)) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid service type",
+		})
+	}
+
+	template := models.ContractTemplate{
+		Name:        strings.TrimSpace(req.Name),
+		ServiceType: req.ServiceType,
+		Body:        req.Body,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+	}
+
+	if err := h.db.Create(&template).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create contract template",
+		})
+	}
+
+	recordAudit(h.db, c, "contract_template.create", models.AuditEntityContractTemplate, template.ID, nil, template)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Contract template created successfully",
+		"data":    template,
+	})
+}
+
+// UpdateTemplate updates an existing contract template (admin only).
+// Contracts already issued keep the body they were rendered with.
+func (h *ContractHandler) UpdateTemplate(c *fiber.Ctx) error {
+	var template models.ContractTemplate
+	if err := h.db.First(&template, c.Params("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(404).JSON(fiber.Map{
+				"success": false,
+				"message": "Contract template not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Database error",
+		})
+	}
+
+	var req models.ContractTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if req.ServiceType != "" && !models.ValidateServiceType(string(req.ServiceType)) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid service type",
+		})
+	}
+
+	before := template
+	if strings.TrimSpace(req.Name) != "" {
+		template.Name = strings.TrimSpace(req.Name)
+	}
+	if strings.TrimSpace(req.Body) != "" {
+		template.Body = req.Body
+	}
+	template.ServiceType = req.ServiceType
+	if req.IsActive != nil {
+		template.IsActive = *req.IsActive
+	}
+
+	if err := h.db.Save(&template).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update contract template",
+		})
+	}
+
+	recordAudit(h.db, c, "contract_template.update", models.AuditEntityContractTemplate, template.ID, before, template)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Contract template updated successfully",
+		"data":    template,
+	})
+}
+
+// IssueContract renders a template for a booking and emails the signing link to the client (admin only)
+func (h *ContractHandler) IssueContract(c *fiber.Ctx) error {
+	var req struct {
+		TemplateID uint `json:"template_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	var booking models.Booking
+	if err := h.db.First(&booking, c.Params("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(404).JSON(fiber.Map{
+				"success": false,
+				"message": "Booking not found",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Database error",
+		})
+	}
+
+	if booking.ContractStatus == models.ContractStatusSigned {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Booking already has a signed contract",
+		})
+	}
+
+	var template models.ContractTemplate
+	if err := h.db.Where("id = ? AND is_active = ?", req.TemplateID, true).First(&template).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Contract template not found",
+		})
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate contract token",
+		})
+	}
+
+	body := template.Render(&booking)
+	contract := models.Contract{
+		Token:        token,
+		Title:        template.Name,
+		Body:         body,
+		DocumentHash: utils.HashToken(body),
+		Status:       models.ContractStatusPending,
+		BookingID:    booking.ID,
+		TemplateID:   template.ID,
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Void any previously issued unsigned contracts for this booking
+		if err := tx.Model(&models.Contract{}).
+			Where("booking_id = ? AND status = ?", booking.ID, models.ContractStatusPending).
+			Update("status", models.ContractStatusVoid).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&contract).Error; err != nil {
+			return err
+		}
+		return tx.Model(&booking).Update("contract_status", models.ContractStatusPending).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to issue contract",
+		})
+	}
+
+	recordAudit(h.db, c, "contract.issue", models.AuditEntityContract, contract.ID, nil, contract.ToResponse())
+
+	url := h.contractURL(&contract)
+	emailBody := fmt.Sprintf(
+		"Hi %s,\n\nPlease review and sign your contract \"%s\" before your session on %s.\n\n%s\n",
+		booking.ClientName, contract.Title, booking.ScheduledDate.Format("January 2, 2006"), url,
+	)
+	if err := utils.SendEmail(h.cfg, booking.ClientEmail, "Please sign your photography contract", emailBody); err != nil {
+		log.Printf("Failed to email contract %d: %v", contract.ID, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Contract issued successfully",
+		"data": fiber.Map{
+			"contract": contract.ToResponse(),
+			"url":      url,
+		},
+	})
+}
+
+// GetBookingContracts returns all contracts issued for a booking (admin only)
+func (h *ContractHandler) GetBookingContracts(c *fiber.Ctx) error {
+	var contracts []models.Contract
+	if err := h.db.Where("booking_id = ?", c.Params("id")).Order("created_at DESC").Find(&contracts).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch contracts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    contracts,
+	})
+}
+
+// GetContractByToken returns the contract for the client to review
+func (h *ContractHandler) GetContractByToken(c *fiber.Ctx) error {
+	contract, err := h.findContract(c.Params("token"))
+	if err != nil {
+		return contractLookupError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    contract.ToResponse(),
+	})
+}
+
+// SignContract records the client's typed signature and generates the signed PDF
+func (h *ContractHandler) SignContract(c *fiber.Ctx) error {
+	contract, err := h.findContract(c.Params("token"))
+	if err != nil {
+		return contractLookupError(c, err)
+	}
+
+	if contract.Status != models.ContractStatusPending {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("Contract is %s and can no longer be signed", contract.Status),
+		})
+	}
+
+	var req models.ContractSignRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	req.SignerName = strings.TrimSpace(req.SignerName)
+	if req.SignerName == "" || !req.Agree {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Please type your full name and agree to the contract terms",
+		})
+	}
+
+	// The client must sign exactly the document they reviewed
+	if req.DocumentHash != contract.DocumentHash || utils.HashToken(contract.Body) != contract.DocumentHash {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "The contract has changed since it was loaded. Please review it again.",
+		})
+	}
+
+	now := time.Now().UTC()
+	contract.Status = models.ContractStatusSigned
+	contract.SignerName = req.SignerName
+	contract.SignerIP = c.IP()
+	contract.SignerUserAgent = c.Get("User-Agent")
+	contract.SignedAt = &now
+
+	if err := os.MkdirAll(contractDir, 0755); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create contract directory",
+		})
+	}
+
+	pdfPath := filepath.Join(contractDir, fmt.Sprintf("contract-%d-%s.pdf", contract.ID, contract.Token[:8]))
+	contract.SignedPDFPath = pdfPath
+
+	booking := contract.Booking
+	bookingBefore := booking
+	pdfWritten := false
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Claim the contract first so concurrent requests cannot both sign it
+		// and write its PDF
+		result := tx.Model(&models.Contract{}).
+			Where("id = ? AND status = ?", contract.ID, models.ContractStatusPending).
+			Updates(map[string]interface{}{
+				"status":            contract.Status,
+				"signer_name":       contract.SignerName,
+				"signer_ip":         contract.SignerIP,
+				"signer_user_agent": contract.SignerUserAgent,
+				"signed_at":         now,
+				"signed_pdf_path":   pdfPath,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errContractNotPending
+		}
+
+		updates := map[string]interface{}{"contract_status": models.ContractStatusSigned}
+		booking.ContractStatus = models.ContractStatusSigned
+		if booking.Status == models.BookingStatusPending && booking.CanBeConfirmed() {
+			updates["status"] = models.BookingStatusConfirmed
+			booking.Status = models.BookingStatusConfirmed
+		}
+		if err := tx.Model(&booking).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(pdfPath, h.renderSignedPDF(contract), 0644); err != nil {
+			return err
+		}
+		pdfWritten = true
+		return nil
+	})
+	if err == errContractNotPending {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Contract can no longer be signed",
+		})
+	}
+	if err != nil {
+		if pdfWritten {
+			os.Remove(pdfPath)
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to record signature",
+		})
+	}
+
+	recordExternalAudit(h.db, c, models.AuditActorClient, contract.SignerName, "contract.sign", models.AuditEntityBooking, booking.ID, bookingBefore, booking)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Contract signed successfully",
+		"data":    contract.ToResponse(),
+	})
+}
+
+// DownloadSignedContract serves the signed contract PDF
+func (h *ContractHandler) DownloadSignedContract(c *fiber.Ctx) error {
+	contract, err := h.findContract(c.Params("token"))
+	if err != nil {
+		return contractLookupError(c, err)
+	}
+
+	if !contract.IsSigned() || contract.SignedPDFPath == "" {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Signed contract not available",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"contract-%d.pdf\"", contract.ID))
+	return c.SendFile(contract.SignedPDFPath)
+}
+
+// renderSignedPDF builds the signed contract document including the signature record
+func (h *ContractHandler) renderSignedPDF(contract *models.Contract) []byte {
+	var b strings.Builder
+	b.WriteString(contract.Title + "\n\n")
+	b.WriteString(contract.Body)
+	b.WriteString("\n\n----------------------------------------\n")
+	b.WriteString("ELECTRONIC SIGNATURE\n\n")
+	fmt.Fprintf(&b, "Signed by: %s\n", contract.SignerName)
+	fmt.Fprintf(&b, "Signed at: %s\n", contract.SignedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "IP address: %s\n", contract.SignerIP)
+	fmt.Fprintf(&b, "User agent: %s\n", contract.SignerUserAgent)
+	fmt.Fprintf(&b, "Document SHA-256: %s\n", contract.DocumentHash)
+	fmt.Fprintf(&b, "Booking reference: #%d\n", contract.BookingID)
+	return utils.GenerateTextPDF(contract.Title, b.String())
+}
+
+// findContract loads a non-void contract and its booking by token
+func (h *ContractHandler) findContract(token string) (*models.Contract, error) {
+	var contract models.Contract
+	err := h.db.Preload("Booking").
+		Where("token = ? AND status <> ?", token, models.ContractStatusVoid).
+		First(&contract).Error
+	if err != nil {
+		return nil, err
+	}
+	return &contract, nil
+}
+
+// contractURL returns the client-facing signing URL for a contract
+func (h *ContractHandler) contractURL(contract *models.Contract) string {
+	return fmt.Sprintf("%s/contracts/%s", h.cfg.CorsOrigin, contract.Token)
+}
+
+// contractLookupError converts a contract lookup error into a JSON response
+func contractLookupError(c *fiber.Ctx, err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Contract not found",
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"success": false,
+		"message": "Database error",
+	})
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// immutableCacheControl is used for files whose URL changes with their content
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// playlistCacheControl lets players pick up a re-transcoded video within an hour
+	playlistCacheControl = "public, max-age=3600"
+	// privateCacheControl keeps unpublished media out of shared caches
+	privateCacheControl = "private, no-cache"
+)
+
+var (
+	// errRangeUnsatisfiable is returned for ranges that start past the end of the file
+	errRangeUnsatisfiable = errors.New("range not satisfiable")
+	// errRangeIgnored is returned for malformed or multi-part ranges, which are
+	// answered with the whole file
+	errRangeIgnored = errors.New("range ignored")
+)
+
+// deliveredFile describes a file sent by the media delivery handlers
+type deliveredFile struct {
+	Size         int64
+	ModTime      time.Time
+	ContentType  string
+	ETag         string
+	CacheControl string
+}
+
+type DeliveryHandler struct {
+	db    *gorm.DB
+	store storage.Storage
+}
+
+func NewDeliveryHandler(db *gorm.DB, store storage.Storage) *DeliveryHandler {
+	return &DeliveryHandler{
+		db:    db,
+		store: store,
+	}
+}
+
+// ServeUpload serves an uploaded media file or a video's poster or preview
+// with byte ranges for seeking and conditional requests. Media that is not
+// public or is in the trash, and the originals of watermarked images, are
+// only served to users who can read media.
+func (h *DeliveryHandler) ServeUpload(c *fiber.Ctx) error {
+	name := c.Params("file")
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	url := "/uploads/" + name
+	var media models.Media
+	if err := h.db.Unscoped().
+		Where("file_name = ? OR poster_url = ? OR preview_url = ?", name, url, url).
+		First(&media).Error; err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if !canAccessMedia(c, &media) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	// The original of a watermarked image is only served to users who can
+	// read media. Everyone else gets the watermarked renditions.
+	watermarkedOriginal := name == media.FileName && hasWatermark(&media, cachedWatermarkSettings(h.db))
+	if watermarkedOriginal && !middleware.HasPermission(c, models.PermMediaRead) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	file, err := openUploadedFile(name)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	delivered := deliveredFile{
+		Size:         stat.Size(),
+		ModTime:      stat.ModTime(),
+		ContentType:  storage.ContentType(name),
+		ETag:         fileETag(stat.Size(), stat.ModTime()),
+		CacheControl: mediaCacheControl(&media, immutableCacheControl),
+	}
+	if watermarkedOriginal {
+		// Shared caches must not hand the clean original to the public
+		delivered.CacheControl = privateCacheControl
+	}
+	if name == media.FileName {
+		if media.MimeType != "" {
+			delivered.ContentType = media.MimeType
+		}
+		if media.ContentHash != "" {
+			delivered.ETag = `"` + media.ContentHash + `"`
+		}
+	}
+
+	return serveFile(c, file, delivered)
+}
+
+// ServeStoredFile serves a generated file such as an HLS playlist or segment
+// from storage. Files generated for a media item follow its access rules.
+// Image renditions are only served through the signed image handler, since
+// their keys reveal the unwatermarked variants.
+func (h *DeliveryHandler) ServeStoredFile(c *fiber.Ctx) error {
+	key, err := storage.CleanKey(c.Params("*"))
+	if err != nil || strings.HasPrefix(key, imageStorageRoot) {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	cacheControl := immutableCacheControl
+	if path.Ext(key) == ".m3u8" {
+		cacheControl = playlistCacheControl
+	}
+
+	if mediaID, ok := storedMediaID(key); ok {
+		var media models.Media
+		if err := h.db.Unscoped().First(&media, mediaID).Error; err != nil {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if !canAccessMedia(c, &media) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		cacheControl = mediaCacheControl(&media, cacheControl)
+	}
+
+	file, info, err := h.store.Open(key)
+	if err == storage.ErrNotFound {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return serveFile(c, file, deliveredFile{
+		Size:         info.Size,
+		ModTime:      info.ModTime,
+		ContentType:  storage.ContentType(key),
+		ETag:         fileETag(info.Size, info.ModTime),
+		CacheControl: cacheControl,
+	})
+}
+
+// canAccessMedia checks whether the current request may download a media
+// item's files. Public media is available to everyone, anything else needs
+// the media read permission.
+func canAccessMedia(c *fiber.Ctx, media *models.Media) bool {
+	if media.IsPublic && !media.DeletedAt.Valid {
+		return true
+	}
+	return middleware.HasPermission(c, models.PermMediaRead)
+}
+
+// mediaCacheControl returns the cache policy for a media item's files
+func mediaCacheControl(media *models.Media, public string) string {
+	if media.IsPublic && !media.DeletedAt.Valid {
+		return public
+	}
+	return privateCacheControl
+}
+
+// storedMediaID returns the media item a storage key such as "hls/12/..." belongs to
+func storedMediaID(key string) (uint, bool) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) < 3 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// openUploadedFile opens a file in the media directory, falling back to the
+// uploads root where older installs keep their files
+func openUploadedFile(name string) (*os.File, error) {
+	file, err := os.Open(filepath.Join("uploads/media", name))
+	if os.IsNotExist(err) {
+		return os.Open(legacyUploadPath(name))
+	}
+	return file, err
+}
+
+// legacyUploadPath returns the path of a file uploaded before media files
+// were kept under uploads/media
+func legacyUploadPath(name string) string {
+	return filepath.Join("uploads", name)
+}
+
+// fileETag builds an ETag for files without a stored content hash
+func fileETag(size int64, modTime time.Time) string {
+	return fmt.Sprintf(`"%x-%x"`, modTime.UnixNano(), size)
+}
+
+// serveFile writes a file with validators, answering conditional requests
+// with 304 and single byte ranges with 206. The file is always closed.
+func serveFile(c *fiber.Ctx, file io.ReadSeekCloser, delivered deliveredFile) error {
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderETag, delivered.ETag)
+	c.Set(fiber.HeaderLastModified, delivered.ModTime.UTC().Format(http.TimeFormat))
+	c.Set(fiber.HeaderCacheControl, delivered.CacheControl)
+
+	if notModified(c, delivered) {
+		file.Close()
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	start, length := int64(0), delivered.Size
+	if header := c.Get(fiber.HeaderRange); header != "" && ifRangeMatches(c, delivered) {
+		rangeStart, rangeLength, err := parseByteRange(header, delivered.Size)
+		switch err {
+		case nil:
+			start, length = rangeStart, rangeLength
+			c.Status(fiber.StatusPartialContent)
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, delivered.Size))
+		case errRangeUnsatisfiable:
+			file.Close()
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", delivered.Size))
+			return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+		}
+	}
+
+	if start > 0 {
+		if _, err := file.Seek(start, io.SeekStart); err != nil {
+			file.Close()
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, delivered.ContentType)
+	return c.SendStream(limitedFile{io.LimitReader(file, length), file}, int(length))
+}
+
+// limitedFile reads part of a file and closes the file once sent
+type limitedFile struct {
+	io.Reader
+	io.Closer
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when no ETags are sent
+func notModified(c *fiber.Ctx, delivered deliveredFile) bool {
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == strings.TrimPrefix(delivered.ETag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+	return !delivered.ModTime.Truncate(time.Second).After(since)
+}
+
+// ifRangeMatches checks that a range request's If-Range still matches the
+// file. ETags must match strongly, dates exactly.
+func ifRangeMatches(c *fiber.Ctx, delivered deliveredFile) bool {
+	ifRange := c.Get(fiber.HeaderIfRange)
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) {
+		return ifRange == delivered.ETag
+	}
+	if strings.HasPrefix(ifRange, "W/") {
+		return false
+	}
+	date, err := http.ParseTime(ifRange)
+	return err == nil && delivered.ModTime.Truncate(time.Second).Equal(date)
+}
+
+// parseByteRange parses a single "bytes=start-end", "bytes=start-" or
+// "bytes=-suffix" range and returns its start and length
+func parseByteRange(header string, size int64) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, errRangeIgnored
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, errRangeIgnored
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix < 0 {
+			return 0, 0, errRangeIgnored
+		}
+		if suffix == 0 || size == 0 {
+			return 0, 0, errRangeUnsatisfiable
+		}
+		suffix = min(suffix, size)
+		return size - suffix, suffix, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, errRangeIgnored
+	}
+	if start >= size {
+		return 0, 0, errRangeUnsatisfiable
+	}
+
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, errRangeIgnored
+		}
+		end = min(end, size-1)
+	}
+	return start, end - start + 1, nil
+}
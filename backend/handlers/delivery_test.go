@@ -0,0 +1,50 @@
+package handlers
+
+import "testing"
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		size       int64
+		wantStart  int64
+		wantLength int64
+		wantErr    error
+	}{
+		{"closed range", "bytes=0-499", 1000, 0, 500, nil},
+		{"middle range", "bytes=500-599", 1000, 500, 100, nil},
+		{"single byte", "bytes=999-999", 1000, 999, 1, nil},
+		{"end past the file", "bytes=900-5000", 1000, 900, 100, nil},
+		{"open range", "bytes=900-", 1000, 900, 100, nil},
+		{"open range from zero", "bytes=0-", 1000, 0, 1000, nil},
+		{"suffix range", "bytes=-100", 1000, 900, 100, nil},
+		{"suffix longer than the file", "bytes=-5000", 1000, 0, 1000, nil},
+		{"spaces around the range", "bytes= 10 - 19 ", 1000, 10, 10, nil},
+
+		{"start at the end", "bytes=1000-", 1000, 0, 0, errRangeUnsatisfiable},
+		{"start past the end", "bytes=2000-3000", 1000, 0, 0, errRangeUnsatisfiable},
+		{"empty suffix", "bytes=-0", 1000, 0, 0, errRangeUnsatisfiable},
+		{"suffix of an empty file", "bytes=-10", 0, 0, 0, errRangeUnsatisfiable},
+		{"range of an empty file", "bytes=0-", 0, 0, 0, errRangeUnsatisfiable},
+
+		{"multiple ranges", "bytes=0-99,200-299", 1000, 0, 0, errRangeIgnored},
+		{"other unit", "items=0-9", 1000, 0, 0, errRangeIgnored},
+		{"missing dash", "bytes=100", 1000, 0, 0, errRangeIgnored},
+		{"end before start", "bytes=500-100", 1000, 0, 0, errRangeIgnored},
+		{"negative start", "bytes=-5-10", 1000, 0, 0, errRangeIgnored},
+		{"not a number", "bytes=a-b", 1000, 0, 0, errRangeIgnored},
+		{"no range at all", "bytes=-", 1000, 0, 0, errRangeIgnored},
+		{"empty header", "", 1000, 0, 0, errRangeIgnored},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, length, err := parseByteRange(tt.header, tt.size)
+			if err != tt.wantErr {
+				t.Fatalf("parseByteRange(%q, %d) error = %v, want %v", tt.header, tt.size, err, tt.wantErr)
+			}
+			if err == nil && (start != tt.wantStart || length != tt.wantLength) {
+				t.Errorf("parseByteRange(%q, %d) = %d, %d, want %d, %d", tt.header, tt.size, start, length, tt.wantStart, tt.wantLength)
+			}
+		})
+	}
+}
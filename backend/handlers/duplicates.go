@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Values of DUPLICATE_UPLOADS
+const (
+	duplicateUploadsWarn   = "warn"
+	duplicateUploadsReject = "reject"
+)
+
+const (
+	defaultDuplicateThreshold = 6  // Differing perceptual hash bits for near duplicates
+	maxDuplicateThreshold     = 16 // Above this unrelated photos start to match
+	// maxNearDuplicateCandidates limits how many of the newest images are
+	// compared pairwise for near duplicates
+	maxNearDuplicateCandidates = 5000
+)
+
+// duplicateCluster is a group of media items that are copies of each other
+type duplicateCluster struct {
+	Exact bool           `json:"exact"` // All items have identical content
+	Media []models.Media `json:"media"`
+}
+
+// checkDuplicate looks for media with the same content as an uploaded file. It
+// returns the existing item and whether the upload must be rejected because
+// DUPLICATE_UPLOADS is "reject" and the uploader did not allow duplicates.
+func (h *MediaHandler) checkDuplicate(info *utils.MediaFileInfo, allowDuplicate bool) (*models.Media, bool) {
+	policy := h.cfg.DuplicateUploads
+	if policy != duplicateUploadsWarn && policy != duplicateUploadsReject {
+		return nil, false
+	}
+
+	var existing models.Media
+	if err := h.db.Where("content_hash = ?", info.ContentHash).Order("id ASC").First(&existing).Error; err != nil {
+		return nil, false
+	}
+	return &existing, policy == duplicateUploadsReject && !allowDuplicate
+}
+
+// duplicateMessage describes the existing copy of an uploaded file
+func duplicateMessage(existing *models.Media) string {
+	return fmt.Sprintf("This file was already uploaded as media %d (%s)", existing.ID, existing.Title)
+}
+
+// GetDuplicates returns clusters of duplicate media. Exact duplicates share a
+// content hash; near duplicates are images whose perceptual hashes differ by
+// at most "threshold" bits (default 6). Near duplicates are looked for among
+// the newest 5000 images.
+func (h *MediaHandler) GetDuplicates(c *fiber.Ctx) error {
+	threshold := c.QueryInt("threshold", defaultDuplicateThreshold)
+	if threshold < 0 || threshold > maxDuplicateThreshold {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("threshold must be between 0 and %d", maxDuplicateThreshold),
+		})
+	}
+
+	// Candidates are all items sharing a content hash with another item, and
+	// the newest images with a perceptual hash
+	exactHashes := h.db.Model(&models.Media{}).
+		Select("content_hash").
+		Where("content_hash <> ''").
+		Group("content_hash").
+		Having("count(*) > 1")
+	nearCandidates := h.db.Model(&models.Media{}).
+		Select("id").
+		Where("p_hash <> ''").
+		Order("id DESC").
+		Limit(maxNearDuplicateCandidates)
+	var media []models.Media
+	if err := h.db.Where("content_hash IN (?) OR id IN (?)", exactHashes, nearCandidates).
+		Order("id ASC").
+		Find(&media).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch media",
+		})
+	}
+
+	// Union items with the same content hash, then images with similar perceptual hashes
+	parent := make([]int, len(media))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		if ra, rb := find(a), find(b); ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	firstByHash := make(map[string]int, len(media))
+	for i, item := range media {
+		if first, ok := firstByHash[item.ContentHash]; ok {
+			union(first, i)
+		} else {
+			firstByHash[item.ContentHash] = i
+		}
+	}
+
+	// Decode the perceptual hashes once for the pairwise comparison
+	var hashed []int
+	hashes := make([]uint64, len(media))
+	for i, item := range media {
+		if hash, ok := utils.ParsePerceptualHash(item.PHash); ok {
+			hashes[i] = hash
+			hashed = append(hashed, i)
+		}
+	}
+	for a, i := range hashed {
+		for _, j := range hashed[a+1:] {
+			if utils.HashDistance(hashes[i], hashes[j]) <= threshold {
+				union(i, j)
+			}
+		}
+	}
+
+	groups := make(map[int][]models.Media)
+	for i, item := range media {
+		root := find(i)
+		groups[root] = append(groups[root], item)
+	}
+
+	clusters := []duplicateCluster{}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		exact := true
+		for _, item := range group[1:] {
+			if item.ContentHash != group[0].ContentHash {
+				exact = false
+				break
+			}
+		}
+		clusters = append(clusters, duplicateCluster{Exact: exact, Media: group})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Media) != len(clusters[j].Media) {
+			return len(clusters[i].Media) > len(clusters[j].Media)
+		}
+		return clusters[i].Media[0].ID < clusters[j].Media[0].ID
+	})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"clusters":  clusters,
+			"threshold": threshold,
+		},
+	})
+}
+
+// MergeDuplicates keeps one media item of a duplicate cluster and moves the
+// others to the trash. View counts are added up, tags are combined and the
+// kept item inherits the featured flag, album and description if it lacks them.
+func (h *MediaHandler) MergeDuplicates(c *fiber.Ctx) error {
+	var req struct {
+		KeepID   uint   `json:"keep_id"`
+		MergeIDs []uint `json:"merge_ids"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if req.KeepID == 0 || len(req.MergeIDs) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "keep_id and merge_ids are required",
+		})
+	}
+	for _, id := range req.MergeIDs {
+		if id == req.KeepID {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "merge_ids cannot contain keep_id",
+			})
+		}
+	}
+
+	var keep models.Media
+	if err := h.db.First(&keep, req.KeepID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Media to keep not found",
+		})
+	}
+
+	var merged []models.Media
+	if err := h.db.Where("id IN ?", req.MergeIDs).Find(&merged).Error; err != nil || len(merged) != len(req.MergeIDs) {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Media to merge not found",
+		})
+	}
+
+	before := keep
+	tags := keep.GetTags()
+	for _, item := range merged {
+		keep.ViewCount += item.ViewCount
+		keep.IsFeatured = keep.IsFeatured || item.IsFeatured
+		if keep.AlbumID == nil {
+			keep.AlbumID = item.AlbumID
+		}
+		if keep.Description == "" {
+			keep.Description = item.Description
+		}
+		tags = append(tags, item.GetTags()...)
+	}
+	keep.SetTags(tags)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&keep).Error; err != nil {
+			return err
+		}
+		// Albums using a merged item as cover show the kept item instead
+		if err := tx.Model(&models.Album{}).
+			Where("cover_media_id IN ?", req.MergeIDs).
+			Update("cover_media_id", keep.ID).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", req.MergeIDs).Delete(&models.Media{}).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to merge media",
+		})
+	}
+
+	invalidateFeatured()
+	recordAudit(h.db, c, "media.merge", models.AuditEntityMedia, keep.ID, before, keep)
+	for _, item := range merged {
+		recordAudit(h.db, c, "media.delete", models.AuditEntityMedia, item.ID, item, nil)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": fmt.Sprintf("Merged %d media items, duplicates moved to trash", len(merged)),
+		"data":    keep,
+	})
+}
+
+// BackfillHashes computes content and perceptual hashes for media uploaded
+// before duplicate detection existed
+func (h *MediaHandler) BackfillHashes() {
+	var media []models.Media
+	if err := h.db.Where("content_hash = '' OR content_hash IS NULL").Find(&media).Error; err != nil {
+		return
+	}
+
+	updated := 0
+	for i := range media {
+		contentHash, pHash, err := utils.HashMediaFile(mediaFilePath(&media[i]))
+		if err != nil {
+			continue
+		}
+		if err := h.db.Model(&media[i]).UpdateColumns(map[string]interface{}{
+			"content_hash": contentHash,
+			"p_hash":       pHash,
+		}).Error; err == nil {
+			updated++
+		}
+	}
+	if updated > 0 {
+		log.Printf("🔍 Computed duplicate detection hashes for %d media items", updated)
+	}
+}
@@ -0,0 +1,676 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// featuredCacheTTL is how long the featured response is reused, so
+	// changes made through another instance are picked up
+	featuredCacheTTL = 5 * time.Minute
+	// maxFeaturedMedia limits the featured media returned with the sections
+	maxFeaturedMedia = 24
+)
+
+// featuredCache keeps the public featured response between requests. It is
+// emptied when curation changes and expires when a section's schedule starts
+// or ends. The generation tells a response built during a change from one
+// built after it.
+var featuredCache struct {
+	sync.Mutex
+	data       fiber.Map
+	expiresAt  time.Time
+	generation int
+}
+
+// invalidateFeatured empties the featured cache after curation changes,
+// such as sections, featured media or album covers
+func invalidateFeatured() {
+	featuredCache.Lock()
+	featuredCache.data = nil
+	featuredCache.generation++
+	featuredCache.Unlock()
+}
+
+// featuredSection is a live homepage section with the public content of its items
+type featuredSection struct {
+	ID       uint                       `json:"id"`
+	Type     models.HomepageSectionType `json:"type"`
+	Title    string                     `json:"title"`
+	Subtitle string                     `json:"subtitle"`
+	StartsAt *time.Time                 `json:"starts_at"`
+	EndsAt   *time.Time                 `json:"ends_at"`
+	Items    []featuredItem             `json:"items"`
+}
+
+// featuredItem is a slide, album or testimonial of a live homepage section
+type featuredItem struct {
+	ID         uint           `json:"id"`
+	Caption    string         `json:"caption,omitempty"`
+	Quote      string         `json:"quote,omitempty"`
+	Author     string         `json:"author,omitempty"`
+	AuthorRole string         `json:"author_role,omitempty"`
+	LinkURL    string         `json:"link_url,omitempty"`
+	Media      *models.Media  `json:"media,omitempty"`
+	Album      *featuredAlbum `json:"album,omitempty"`
+}
+
+// featuredAlbum is a public album with its cover image, or its newest
+// image when no cover is set
+type featuredAlbum struct {
+	models.Album
+	Cover *models.Media `json:"cover,omitempty"`
+}
+
+type HomepageHandler struct {
+	db     *gorm.DB
+	signer *utils.ImageURLSigner
+}
+
+func NewHomepageHandler(db *gorm.DB, signer *utils.ImageURLSigner) *HomepageHandler {
+	return &HomepageHandler{db: db, signer: signer}
+}
+
+// GetFeatured returns the live homepage sections and the featured public media
+func (h *HomepageHandler) GetFeatured(c *fiber.Ctx) error {
+	now := time.Now()
+	featuredCache.Lock()
+	data, generation := featuredCache.data, featuredCache.generation
+	if data != nil && now.After(featuredCache.expiresAt) {
+		data = nil
+	}
+	featuredCache.Unlock()
+
+	if data == nil {
+		var expiresAt time.Time
+		var err error
+		data, expiresAt, err = h.buildFeatured(now)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"success": false,
+				"message": "Failed to fetch featured content",
+			})
+		}
+
+		featuredCache.Lock()
+		if featuredCache.generation == generation {
+			featuredCache.data = data
+			featuredCache.expiresAt = expiresAt
+		}
+		featuredCache.Unlock()
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// buildFeatured loads the featured response at a time and returns when it
+// has to be rebuilt: after the cache TTL or when a section's schedule
+// starts or ends, whichever comes first
+func (h *HomepageHandler) buildFeatured(now time.Time) (fiber.Map, time.Time, error) {
+	var media []models.Media
+	if err := h.db.Where("is_featured = ? AND is_public = ?", true, true).
+		Order("sort_order ASC, created_at DESC").
+		Limit(maxFeaturedMedia).
+		Find(&media).Error; err != nil {
+		return nil, time.Time{}, err
+	}
+	watermarks := cachedWatermarkSettings(h.db)
+	for i := range media {
+		setPublicImageURLs(h.signer, &media[i], watermarks)
+	}
+
+	var sections []models.HomepageSection
+	if err := h.db.Where("is_active = ?", true).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC, id ASC")
+		}).
+		Order("position ASC, id ASC").
+		Find(&sections).Error; err != nil {
+		return nil, time.Time{}, err
+	}
+
+	expiresAt := now.Add(featuredCacheTTL)
+	live := make([]models.HomepageSection, 0, len(sections))
+	for _, section := range sections {
+		for _, boundary := range []*time.Time{section.StartsAt, section.EndsAt} {
+			if boundary != nil && boundary.After(now) && boundary.Before(expiresAt) {
+				expiresAt = *boundary
+			}
+		}
+		if section.IsLiveAt(now) {
+			live = append(live, section)
+		}
+	}
+
+	resolved, err := h.resolveSections(live)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return fiber.Map{
+		"sections": resolved,
+		"media":    media,
+	}, expiresAt, nil
+}
+
+// resolveSections replaces the media and album IDs of sections by their
+// public content. Items whose media or album is hidden or deleted are left
+// out, testimonials keep their quote without the photo, and sections left
+// without items are skipped.
+func (h *HomepageHandler) resolveSections(sections []models.HomepageSection) ([]featuredSection, error) {
+	var mediaIDs, albumIDs []uint
+	for _, section := range sections {
+		for _, item := range section.Items {
+			if item.MediaID != nil {
+				mediaIDs = append(mediaIDs, *item.MediaID)
+			}
+			if item.AlbumID != nil {
+				albumIDs = append(albumIDs, *item.AlbumID)
+			}
+		}
+	}
+
+	albums, err := h.featuredAlbums(albumIDs)
+	if err != nil {
+		return nil, err
+	}
+	media, err := h.publicMedia(mediaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]featuredSection, 0, len(sections))
+	for _, section := range sections {
+		featured := featuredSection{
+			ID:       section.ID,
+			Type:     section.Type,
+			Title:    section.Title,
+			Subtitle: section.Subtitle,
+			StartsAt: section.StartsAt,
+			EndsAt:   section.EndsAt,
+			Items:    make([]featuredItem, 0, len(section.Items)),
+		}
+		for _, item := range section.Items {
+			entry := featuredItem{
+				ID:         item.ID,
+				Caption:    item.Caption,
+				Quote:      item.Quote,
+				Author:     item.Author,
+				AuthorRole: item.AuthorRole,
+				LinkURL:    item.LinkURL,
+			}
+			if item.MediaID != nil {
+				entry.Media = media[*item.MediaID]
+			}
+			if item.AlbumID != nil {
+				entry.Album = albums[*item.AlbumID]
+			}
+
+			switch section.Type {
+			case models.SectionHero:
+				if entry.Media == nil {
+					continue
+				}
+			case models.SectionFeaturedAlbums:
+				if entry.Album == nil {
+					continue
+				}
+			}
+			featured.Items = append(featured.Items, entry)
+		}
+		if len(featured.Items) > 0 {
+			resolved = append(resolved, featured)
+		}
+	}
+	return resolved, nil
+}
+
+// publicMedia loads the public media among the IDs with their image URLs
+func (h *HomepageHandler) publicMedia(ids []uint) (map[uint]*models.Media, error) {
+	found := make(map[uint]*models.Media, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	var media []models.Media
+	if err := h.db.Where("id IN ? AND is_public = ?", ids, true).Find(&media).Error; err != nil {
+		return nil, err
+	}
+	watermarks := cachedWatermarkSettings(h.db)
+	for i := range media {
+		setPublicImageURLs(h.signer, &media[i], watermarks)
+		found[media[i].ID] = &media[i]
+	}
+	return found, nil
+}
+
+// featuredAlbums loads the public albums among the IDs with their covers
+// and the number of public media in them
+func (h *HomepageHandler) featuredAlbums(ids []uint) (map[uint]*featuredAlbum, error) {
+	found := make(map[uint]*featuredAlbum, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	var albums []models.Album
+	if err := h.db.Where("id IN ? AND is_public = ?", ids, true).Find(&albums).Error; err != nil {
+		return nil, err
+	}
+	if len(albums) == 0 {
+		return found, nil
+	}
+
+	albumIDs := make([]uint, len(albums))
+	var coverIDs []uint
+	for i, album := range albums {
+		albumIDs[i] = album.ID
+		if album.CoverMediaID != nil {
+			coverIDs = append(coverIDs, *album.CoverMediaID)
+		}
+	}
+	covers, err := h.publicMedia(coverIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Albums without a visible cover show their newest public image
+	var newest []models.Media
+	if err := h.db.Select("DISTINCT ON (album_id) *").
+		Where("album_id IN ? AND is_public = ? AND type = ?", albumIDs, true, models.MediaTypeImage).
+		Order("album_id, created_at DESC").
+		Find(&newest).Error; err != nil {
+		return nil, err
+	}
+	watermarks := cachedWatermarkSettings(h.db)
+	newestByAlbum := make(map[uint]*models.Media, len(newest))
+	for i := range newest {
+		setPublicImageURLs(h.signer, &newest[i], watermarks)
+		newestByAlbum[*newest[i].AlbumID] = &newest[i]
+	}
+
+	var counts []struct {
+		AlbumID uint
+		Count   int64
+	}
+	if err := h.db.Model(&models.Media{}).
+		Select("album_id, COUNT(*) AS count").
+		Where("album_id IN ? AND is_public = ?", albumIDs, true).
+		Group("album_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	byAlbum := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		byAlbum[count.AlbumID] = count.Count
+	}
+
+	for _, album := range albums {
+		album.MediaCount = byAlbum[album.ID]
+		featured := &featuredAlbum{Album: album, Cover: newestByAlbum[album.ID]}
+		if album.CoverMediaID != nil && covers[*album.CoverMediaID] != nil {
+			featured.Cover = covers[*album.CoverMediaID]
+		}
+		found[album.ID] = featured
+	}
+	return found, nil
+}
+
+// GetSections returns all homepage sections in order, with whether each is
+// shown right now
+func (h *HomepageHandler) GetSections(c *fiber.Ctx) error {
+	var sections []models.HomepageSection
+	if err := h.db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC, id ASC")
+	}).Order("position ASC, id ASC").Find(&sections).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch homepage sections",
+		})
+	}
+
+	now := time.Now()
+	for i := range sections {
+		sections[i].IsLive = sections[i].IsLiveAt(now)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    sections,
+	})
+}
+
+// CreateSection adds a homepage section, at the end unless a position is given
+func (h *HomepageHandler) CreateSection(c *fiber.Ctx) error {
+	var req models.HomepageSectionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	section := models.HomepageSection{IsActive: true}
+	if err := h.applySectionRequest(&section, &req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
+	}
+	if req.Position == nil {
+		var last struct{ Position *int }
+		h.db.Model(&models.HomepageSection{}).Select("MAX(position) AS position").Scan(&last)
+		if last.Position != nil {
+			section.Position = *last.Position + 1
+		}
+	}
+
+	if err := h.db.Create(&section).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create homepage section",
+		})
+	}
+	invalidateFeatured()
+	section.IsLive = section.IsLiveAt(time.Now())
+
+	recordAudit(h.db, c, "homepage_section.create", models.AuditEntityHomepageSection, section.ID, nil, section)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Homepage section created successfully",
+		"data":    section,
+	})
+}
+
+// UpdateSection replaces a homepage section and its items
+func (h *HomepageHandler) UpdateSection(c *fiber.Ctx) error {
+	var section models.HomepageSection
+	if err := h.db.Preload("Items").First(&section, c.Params("id")).Error; err != nil {
+		return sectionLookupError(c, err)
+	}
+
+	var req models.HomepageSectionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	before := section
+	if err := h.applySectionRequest(&section, &req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("section_id = ?", section.ID).Delete(&models.HomepageSectionItem{}).Error; err != nil {
+			return err
+		}
+		for i := range section.Items {
+			section.Items[i].SectionID = section.ID
+		}
+		if len(section.Items) > 0 {
+			if err := tx.Create(&section.Items).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&section).Select("*").Omit("id", "created_at", "Items").Updates(&section).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update homepage section",
+		})
+	}
+	invalidateFeatured()
+	section.IsLive = section.IsLiveAt(time.Now())
+
+	recordAudit(h.db, c, "homepage_section.update", models.AuditEntityHomepageSection, section.ID, before, section)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Homepage section updated successfully",
+		"data":    section,
+	})
+}
+
+// DeleteSection removes a homepage section and its items
+func (h *HomepageHandler) DeleteSection(c *fiber.Ctx) error {
+	var section models.HomepageSection
+	if err := h.db.Preload("Items").First(&section, c.Params("id")).Error; err != nil {
+		return sectionLookupError(c, err)
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("section_id = ?", section.ID).Delete(&models.HomepageSectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&section).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to delete homepage section",
+		})
+	}
+	invalidateFeatured()
+
+	recordAudit(h.db, c, "homepage_section.delete", models.AuditEntityHomepageSection, section.ID, section, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Homepage section deleted successfully",
+	})
+}
+
+// ReorderSections sets the order of the homepage sections to the order of
+// the given IDs, which must list every section
+func (h *HomepageHandler) ReorderSections(c *fiber.Ctx) error {
+	var req struct {
+		IDs []uint `json:"ids"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	var ids []uint
+	if err := h.db.Model(&models.HomepageSection{}).Order("position ASC, id ASC").Pluck("id", &ids).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Database error",
+		})
+	}
+	existing := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		existing[id] = true
+	}
+	seen := make(map[uint]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if !existing[id] || seen[id] {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": fmt.Sprintf("Unknown or repeated section %d", id),
+			})
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(existing) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Every homepage section must be listed",
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range req.IDs {
+			if err := tx.Model(&models.HomepageSection{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to reorder homepage sections",
+		})
+	}
+	invalidateFeatured()
+
+	recordAudit(h.db, c, "homepage_section.reorder", models.AuditEntityHomepageSection, "", fiber.Map{"order": ids}, fiber.Map{"order": req.IDs})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Homepage sections reordered successfully",
+	})
+}
+
+// applySectionRequest validates a section request and copies it onto a
+// section, replacing its items. The returned error can be shown to users.
+func (h *HomepageHandler) applySectionRequest(section *models.HomepageSection, req *models.HomepageSectionRequest) error {
+	if !models.ValidateHomepageSectionType(string(req.Type)) {
+		return errors.New("type must be hero, featured_albums or testimonials")
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Subtitle = strings.TrimSpace(req.Subtitle)
+	if len(req.Title) > 255 || len(req.Subtitle) > 500 {
+		return errors.New("Title must be at most 255 and subtitle at most 500 characters")
+	}
+	if len(req.Items) == 0 || len(req.Items) > models.MaxHomepageSectionItems {
+		return fmt.Errorf("A section needs between 1 and %d items", models.MaxHomepageSectionItems)
+	}
+
+	var startsAt, endsAt *time.Time
+	if req.StartsAt != "" {
+		date, err := parseISODate(req.StartsAt)
+		if err != nil {
+			return errors.New("Invalid start date format. Use ISO format (YYYY-MM-DD or YYYY-MM-DDTHH:MM:SSZ)")
+		}
+		startsAt = &date
+	}
+	if req.EndsAt != "" {
+		date, err := parseISODate(req.EndsAt)
+		if err != nil {
+			return errors.New("Invalid end date format. Use ISO format (YYYY-MM-DD or YYYY-MM-DDTHH:MM:SSZ)")
+		}
+		endsAt = &date
+	}
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return errors.New("End date must be after the start date")
+	}
+
+	items := make([]models.HomepageSectionItem, 0, len(req.Items))
+	for i, item := range req.Items {
+		if err := h.validateSectionItem(req.Type, &item); err != nil {
+			return fmt.Errorf("Item %d: %w", i+1, err)
+		}
+		items = append(items, models.HomepageSectionItem{
+			MediaID:    item.MediaID,
+			AlbumID:    item.AlbumID,
+			Caption:    item.Caption,
+			Quote:      item.Quote,
+			Author:     item.Author,
+			AuthorRole: item.AuthorRole,
+			LinkURL:    item.LinkURL,
+			SortOrder:  i,
+		})
+	}
+
+	section.Type = req.Type
+	section.Title = req.Title
+	section.Subtitle = req.Subtitle
+	if req.Position != nil {
+		section.Position = *req.Position
+	}
+	if req.IsActive != nil {
+		section.IsActive = *req.IsActive
+	}
+	section.StartsAt = startsAt
+	section.EndsAt = endsAt
+	section.Items = items
+	return nil
+}
+
+// validateSectionItem checks that an item has what its section type shows:
+// an image for hero slides, an album for featured albums and a quote and
+// author for testimonials. Fields other types do not use are cleared.
+func (h *HomepageHandler) validateSectionItem(sectionType models.HomepageSectionType, item *models.HomepageSectionItemRequest) error {
+	item.Caption = strings.TrimSpace(item.Caption)
+	item.Quote = strings.TrimSpace(item.Quote)
+	item.Author = strings.TrimSpace(item.Author)
+	item.AuthorRole = strings.TrimSpace(item.AuthorRole)
+	item.LinkURL = strings.TrimSpace(item.LinkURL)
+
+	if len(item.Caption) > 500 || len(item.Quote) > 2000 || len(item.Author) > 255 || len(item.AuthorRole) > 255 || len(item.LinkURL) > 500 {
+		return errors.New("text is too long")
+	}
+	if item.LinkURL != "" && !strings.HasPrefix(item.LinkURL, "/") &&
+		!strings.HasPrefix(item.LinkURL, "https://") && !strings.HasPrefix(item.LinkURL, "http://") {
+		return errors.New("link_url must be a path or an http(s) URL")
+	}
+
+	switch sectionType {
+	case models.SectionHero:
+		item.AlbumID, item.Quote, item.Author, item.AuthorRole = nil, "", "", ""
+		if item.MediaID == nil {
+			return errors.New("media_id is required")
+		}
+	case models.SectionFeaturedAlbums:
+		item.MediaID, item.Quote, item.Author, item.AuthorRole = nil, "", "", ""
+		if item.AlbumID == nil {
+			return errors.New("album_id is required")
+		}
+	case models.SectionTestimonials:
+		item.AlbumID, item.Caption = nil, ""
+		if item.Quote == "" || item.Author == "" {
+			return errors.New("quote and author are required")
+		}
+	}
+
+	if item.MediaID != nil {
+		var media models.Media
+		if err := h.db.Select("id", "type").First(&media, *item.MediaID).Error; err != nil || !media.IsImage() {
+			return fmt.Errorf("media %d is not an image", *item.MediaID)
+		}
+	}
+	if item.AlbumID != nil {
+		var count int64
+		if h.db.Model(&models.Album{}).Where("id = ?", *item.AlbumID).Count(&count); count == 0 {
+			return fmt.Errorf("album %d not found", *item.AlbumID)
+		}
+	}
+	return nil
+}
+
+// sectionLookupError writes the response for a failed homepage section lookup
+func sectionLookupError(c *fiber.Ctx, err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Homepage section not found",
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"success": false,
+		"message": "Database error",
+	})
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"io"
+	"runtime"
+	"sync"
+
+	"photography-portfolio/config"
+	"photography-portfolio/models"
+	"photography-portfolio/storage"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// imagePresets are the renditions listed in a media item's "images"
+var imagePresets = map[string]utils.ImageTransform{
+	"thumb":  {Width: 320, Height: 320, Fit: utils.FitCover},
+	"small":  {Width: 640},
+	"medium": {Width: 1280},
+	"large":  {Width: 1920},
+}
+
+var (
+	// imageRenderSlots limits how many images are decoded and resized at once
+	imageRenderSlots = make(chan struct{}, runtime.NumCPU())
+	// imageRenderLocks make concurrent requests for the same rendition wait
+	// for one render instead of each computing it
+	imageRenderLocks [64]sync.Mutex
+)
+
+// errImageNotDecodable is returned for images without a Go decoder, such as WebP
+var errImageNotDecodable = errors.New("image cannot be decoded")
+
+type ImageHandler struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	store  storage.Storage
+	signer *utils.ImageURLSigner
+}
+
+func NewImageHandler(db *gorm.DB, cfg *config.Config, store storage.Storage, signer *utils.ImageURLSigner) *ImageHandler {
+	return &ImageHandler{
+		db:     db,
+		cfg:    cfg,
+		store:  store,
+		signer: signer,
+	}
+}
+
+// GetImage returns a resized, cropped or converted rendition of an image.
+// Only signed URLs with allowed sizes are rendered, and each rendition is
+// stored the first time it is requested.
+func (h *ImageHandler) GetImage(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid media ID",
+		})
+	}
+
+	transform, err := utils.NormalizeImageTransform(h.cfg, utils.ImageTransform{
+		Width:   c.QueryInt("w"),
+		Height:  c.QueryInt("h"),
+		Fit:     c.Query("fit"),
+		Format:  c.Query("fmt"),
+		Quality: c.QueryInt("q"),
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
+	}
+	transform.Watermark = c.QueryBool("wm")
+
+	var media models.Media
+	if err := h.db.Unscoped().First(&media, id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Media not found",
+		})
+	}
+	version := c.Query("v")
+	watermark := cachedWatermarkSettings(h.db).ForMedia(&media)
+	current := imageVersion(&media, watermark)
+	// Proofing clients may see private images, but only watermarked. Stale
+	// URLs are redirected to a watermarked one below.
+	proof := (!watermark.Enabled || transform.Watermark || version != current) && inProofingGallery(h.db, &media)
+	if !canAccessMedia(c, &media) && !proof {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Media not found",
+		})
+	}
+	if !media.IsImage() {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Only images can be transformed",
+		})
+	}
+
+	signature := c.Query("sig")
+	if !h.signer.Verify(media.ID, version, transform, signature) {
+		return c.Status(403).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid image signature",
+		})
+	}
+
+	// The image or its watermark has changed since the URL was issued. The
+	// current URL follows the current watermark setting, so URLs issued before
+	// watermarking was enabled do not keep serving clean renditions.
+	if version != current {
+		transform.Watermark = watermark.Enabled
+		return c.Redirect(h.signer.URL(media.ID, current, transform), fiber.StatusFound)
+	}
+	if !watermark.Enabled {
+		transform.Watermark = false
+	}
+
+	file, info, err := h.renderImage(&media, imageStorageKey(media.ID, version, transform), transform, watermark)
+	if errors.Is(err, errImageNotDecodable) {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"success": false,
+			"message": "This image format cannot be transformed",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to render image",
+		})
+	}
+
+	return serveFile(c, file, deliveredFile{
+		Size:         info.Size,
+		ModTime:      info.ModTime,
+		ContentType:  "image/" + transform.Format,
+		ETag:         `"` + signature + `"`,
+		CacheControl: mediaCacheControl(&media, immutableCacheControl),
+	})
+}
+
+// SignImageURL returns a signed URL for a custom rendition, for layouts the
+// presets do not cover. Renditions are watermarked like the presets unless
+// "watermark" is false, such as for paid client downloads.
+func (h *ImageHandler) SignImageURL(c *fiber.Ctx) error {
+	var req struct {
+		MediaID   uint   `json:"media_id"`
+		Width     int    `json:"w"`
+		Height    int    `json:"h"`
+		Fit       string `json:"fit"`
+		Format    string `json:"fmt"`
+		Quality   int    `json:"q"`
+		Watermark *bool  `json:"watermark"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	transform, err := utils.NormalizeImageTransform(h.cfg, utils.ImageTransform{
+		Width:   req.Width,
+		Height:  req.Height,
+		Fit:     req.Fit,
+		Format:  req.Format,
+		Quality: req.Quality,
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
+	}
+
+	var media models.Media
+	if err := h.db.First(&media, req.MediaID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Media not found",
+		})
+	}
+	if !media.IsImage() {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Only images can be transformed",
+		})
+	}
+
+	watermark := cachedWatermarkSettings(h.db).ForMedia(&media)
+	transform.Watermark = watermark.Enabled
+	if req.Watermark != nil {
+		transform.Watermark = transform.Watermark && *req.Watermark
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"url": h.signer.URL(media.ID, imageVersion(&media, watermark), transform),
+		},
+	})
+}
+
+// renderImage returns a stored rendition, rendering and storing it first if needed
+func (h *ImageHandler) renderImage(media *models.Media, key string, transform utils.ImageTransform, watermark models.Watermark) (io.ReadSeekCloser, *storage.FileInfo, error) {
+	if file, info, err := h.store.Open(key); err == nil {
+		return file, info, nil
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	lock := &imageRenderLocks[hash.Sum32()%uint32(len(imageRenderLocks))]
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Another request may have rendered it while this one waited
+	if file, info, err := h.store.Open(key); err == nil {
+		return file, info, nil
+	}
+
+	imageRenderSlots <- struct{}{}
+	defer func() { <-imageRenderSlots }()
+
+	src, err := openUploadedFile(media.FileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, _, err := image.Decode(src)
+	src.Close()
+	if err != nil {
+		return nil, nil, errImageNotDecodable
+	}
+
+	if transform.Fit == utils.FitCover {
+		transform.Focus = mediaFocalPoint(media, img)
+	}
+	rendered, err := utils.TransformImage(img, transform)
+	if err != nil {
+		return nil, nil, err
+	}
+	if transform.Watermark {
+		var logo image.Image
+		if watermark.LogoKey != "" {
+			if logo, err = h.watermarkLogo(watermark.LogoKey); err != nil {
+				return nil, nil, err
+			}
+		}
+		utils.ApplyWatermark(rendered, watermark, logo)
+	}
+
+	var buf bytes.Buffer
+	if err := utils.EncodeImage(&buf, rendered, transform.Format, transform.Quality); err != nil {
+		return nil, nil, err
+	}
+	if err := h.store.Put(key, &buf); err != nil {
+		return nil, nil, err
+	}
+	return h.store.Open(key)
+}
+
+// imageURLs returns signed URLs of the preset renditions of an image, or nil
+// for videos and images that cannot be transformed. The presets are public
+// renditions and carry the media item's watermark.
+func imageURLs(signer *utils.ImageURLSigner, media *models.Media, watermarks models.WatermarkSettings) map[string]string {
+	if !canTransformImage(media) {
+		return nil
+	}
+
+	// Keep transparency in PNG and GIF sources
+	format := utils.FormatJPEG
+	if media.MimeType == "image/png" || media.MimeType == "image/gif" {
+		format = utils.FormatPNG
+	}
+
+	watermark := watermarks.ForMedia(media)
+	version := imageVersion(media, watermark)
+	urls := make(map[string]string, len(imagePresets))
+	for name, preset := range imagePresets {
+		preset.Format = format
+		preset.Watermark = watermark.Enabled
+		if format == utils.FormatJPEG {
+			preset.Quality = utils.DefaultImageQuality
+		}
+		urls[name] = signer.URL(media.ID, version, preset)
+	}
+	return urls
+}
+
+// setPublicImageURLs adds the signed rendition URLs to a media item for
+// users who cannot read media. The original file would bypass the watermark,
+// so the file URLs of watermarked images point at renditions instead.
+func setPublicImageURLs(signer *utils.ImageURLSigner, media *models.Media, watermarks models.WatermarkSettings) {
+	media.Images = imageURLs(signer, media, watermarks)
+	if !hasWatermark(media, watermarks) {
+		return
+	}
+	media.S3URL = media.Images["large"]
+	media.ThumbnailURL = media.Images["thumb"]
+	media.FileName = ""
+}
+
+// hasWatermark reports whether the public renditions of a media item are watermarked
+func hasWatermark(media *models.Media, watermarks models.WatermarkSettings) bool {
+	return canTransformImage(media) && watermarks.ForMedia(media).Enabled
+}
+
+// canTransformImage reports whether renditions can be rendered from a media item
+func canTransformImage(media *models.Media) bool {
+	return media.IsImage() && media.MimeType != "image/webp"
+}
+
+// imageVersion changes whenever a rendition of the image would look
+// different, including when its focal point or watermark changes
+func imageVersion(media *models.Media, watermark models.Watermark) string {
+	version := mediaContentVersion(media)
+	// Estimated focal points follow from the content
+	if media.FocalSource == models.FocalSourceManual {
+		version += fmt.Sprintf("-f%gx%g", media.FocalX, media.FocalY)
+	}
+	if wm := watermark.Version(); wm != "" {
+		version += "-" + wm
+	}
+	return version
+}
+
+// imageStorageRoot is the storage directory of all image renditions
+const imageStorageRoot = "img/"
+
+// imageStoragePrefix is where all renditions of an image are stored
+func imageStoragePrefix(mediaID uint) string {
+	return fmt.Sprintf("%s%d/", imageStorageRoot, mediaID)
+}
+
+// imageStorageKey names a rendition after its parameters
+func imageStorageKey(mediaID uint, version string, t utils.ImageTransform) string {
+	ext := "jpg"
+	if t.Format == utils.FormatPNG {
+		ext = "png"
+	}
+	suffix := ""
+	if t.Watermark {
+		suffix = "-wm"
+	}
+	return fmt.Sprintf("%s%s/%dx%d-%s-q%d%s.%s", imageStoragePrefix(mediaID), version, t.Width, t.Height, t.Fit, t.Quality, suffix, ext)
+}
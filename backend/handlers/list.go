@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Kinds of values a list can be sorted by, which decide how cursors encode them
+const (
+	sortTime = iota
+	sortText
+	sortNumber
+)
+
+// sortField is a column a list can be sorted by
+type sortField struct {
+	Column string
+	Kind   int
+}
+
+// listSpec describes the page sizes and sort fields a list endpoint allows
+type listSpec struct {
+	DefaultLimit int
+	MaxLimit     int
+	Sorts        map[string]sortField
+	DefaultSort  string
+	DefaultOrder string
+}
+
+// Lists of media, newest first by default
+var (
+	mediaSorts = map[string]sortField{
+		"created_at":  {Column: "created_at", Kind: sortTime},
+		"uploaded_at": {Column: "uploaded_at", Kind: sortTime},
+		"title":       {Column: "title", Kind: sortText},
+		"sort_order":  {Column: "sort_order", Kind: sortNumber},
+	}
+	galleryListSpec = listSpec{
+		DefaultLimit: 20,
+		MaxLimit:     100,
+		Sorts:        mediaSorts,
+		DefaultSort:  "created_at",
+		DefaultOrder: "desc",
+	}
+	adminMediaListSpec = listSpec{
+		DefaultLimit: 12,
+		MaxLimit:     100,
+		Sorts:        mediaSorts,
+		DefaultSort:  "created_at",
+		DefaultOrder: "desc",
+	}
+)
+
+// Lists of contact messages, newest first by default
+var (
+	contactSorts = map[string]sortField{
+		"created_at": {Column: "created_at", Kind: sortTime},
+		"name":       {Column: "name", Kind: sortText},
+		"subject":    {Column: "subject", Kind: sortText},
+	}
+	contactListSpec = listSpec{
+		DefaultLimit: 10,
+		MaxLimit:     100,
+		Sorts:        contactSorts,
+		DefaultSort:  "created_at",
+		DefaultOrder: "desc",
+	}
+	inboxListSpec = listSpec{
+		DefaultLimit: 20,
+		MaxLimit:     100,
+		Sorts:        contactSorts,
+		DefaultSort:  "created_at",
+		DefaultOrder: "desc",
+	}
+)
+
+// listCursor is the position after the last item of a page: the sort value
+// and ID of that item, and the sort the cursor belongs to
+type listCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// listQuery is a validated page request of a list endpoint
+type listQuery struct {
+	Page   int
+	Limit  int
+	Sort   string
+	Order  string
+	field  sortField
+	cursor *listCursor
+}
+
+// newListQuery validates the pagination and sort of a filter against a
+// list spec. Missing or invalid page sizes get the default and larger ones
+// the maximum. The returned error can be shown to users.
+func newListQuery(spec listSpec, page, limit int, sortBy, sortOrder, cursor string) (*listQuery, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = spec.DefaultLimit
+	}
+	limit = min(limit, spec.MaxLimit)
+
+	if sortBy == "" {
+		sortBy = spec.DefaultSort
+	}
+	field, ok := spec.Sorts[sortBy]
+	if !ok {
+		names := make([]string, 0, len(spec.Sorts))
+		for name := range spec.Sorts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("sort_by must be one of %s", strings.Join(names, ", "))
+	}
+	sortOrder = strings.ToLower(sortOrder)
+	if sortOrder == "" {
+		sortOrder = spec.DefaultOrder
+	}
+	if sortOrder != "asc" && sortOrder != "desc" {
+		return nil, errors.New("sort_order must be asc or desc")
+	}
+
+	list := &listQuery{Page: page, Limit: limit, Sort: sortBy, Order: sortOrder, field: field}
+	if cursor != "" {
+		decoded, err := decodeListCursor(cursor)
+		if err != nil || decoded.Sort != sortBy || decoded.Order != sortOrder {
+			return nil, errors.New("cursor does not belong to this list and sort")
+		}
+		list.cursor = decoded
+		list.Page = 0
+	}
+	return list, nil
+}
+
+// parsePageParams reads ?page= and ?limit=, falling back to ?page_size=.
+// Invalid values are returned as 0 and replaced by the list defaults in
+// newListQuery.
+func parsePageParams(c *fiber.Ctx) (int, int) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil {
+		limit, _ = strconv.Atoi(c.Query("page_size"))
+	}
+	return page, limit
+}
+
+// Offset is the number of items before the requested page
+func (l *listQuery) Offset() int {
+	if l.Page < 1 {
+		return 0
+	}
+	return (l.Page - 1) * l.Limit
+}
+
+// Find loads the requested page of the query into dest, a pointer to a
+// slice of models, and describes it. Page-numbered and cursor requests both
+// get a cursor to the next page, so infinite scroll can start from either.
+func (l *listQuery) Find(query *gorm.DB, dest interface{}) (models.Pagination, error) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return models.Pagination{}, err
+	}
+
+	direction := "DESC"
+	comparison := "<"
+	if l.Order == "asc" {
+		direction, comparison = "ASC", ">"
+	}
+	page := query.Session(&gorm.Session{}).
+		Order(fmt.Sprintf("%s %s, id %s", l.field.Column, direction, direction))
+	if l.cursor != nil {
+		value, err := l.cursorValue()
+		if err != nil {
+			return models.Pagination{}, err
+		}
+		page = page.Where(fmt.Sprintf("(%s, id) %s (?, ?)", l.field.Column, comparison), value, l.cursor.ID)
+	} else {
+		page = page.Offset(l.Offset())
+	}
+
+	// One more item than requested tells if there is a next page
+	tx := page.Limit(l.Limit + 1).Find(dest)
+	if tx.Error != nil {
+		return models.Pagination{}, tx.Error
+	}
+
+	items := reflect.ValueOf(dest).Elem()
+	pagination := l.Paginate(total)
+	pagination.HasMore = items.Len() > l.Limit
+	if pagination.HasMore {
+		items.Set(items.Slice(0, l.Limit))
+		cursor, err := l.nextCursor(tx, items.Index(l.Limit-1))
+		if err != nil {
+			return models.Pagination{}, err
+		}
+		pagination.NextCursor = cursor
+	}
+	return pagination, nil
+}
+
+// Paginate describes a page of a list with total items, for lists that
+// are not loaded with Find
+func (l *listQuery) Paginate(total int64) models.Pagination {
+	return models.Pagination{
+		Total:   total,
+		Page:    l.Page,
+		Limit:   l.Limit,
+		Pages:   (total + int64(l.Limit) - 1) / int64(l.Limit),
+		HasMore: int64(l.Offset()+l.Limit) < total,
+	}
+}
+
+// cursorValue converts the cursor's sort value back to its column type
+func (l *listQuery) cursorValue() (interface{}, error) {
+	switch l.field.Kind {
+	case sortTime:
+		return time.Parse(time.RFC3339Nano, l.cursor.Value)
+	case sortNumber:
+		return strconv.ParseInt(l.cursor.Value, 10, 64)
+	default:
+		return l.cursor.Value, nil
+	}
+}
+
+// nextCursor returns the cursor after an item loaded by tx
+func (l *listQuery) nextCursor(tx *gorm.DB, item reflect.Value) (string, error) {
+	schema := tx.Statement.Schema
+	field := schema.LookUpField(l.field.Column)
+	if field == nil || schema.PrioritizedPrimaryField == nil {
+		return "", fmt.Errorf("cannot build a cursor on %s", l.field.Column)
+	}
+	ctx := tx.Statement.Context
+	value, _ := field.ValueOf(ctx, item)
+	id, _ := schema.PrioritizedPrimaryField.ValueOf(ctx, item)
+
+	cursor := listCursor{Sort: l.Sort, Order: l.Order}
+	switch v := value.(type) {
+	case time.Time:
+		cursor.Value = v.UTC().Format(time.RFC3339Nano)
+	default:
+		cursor.Value = fmt.Sprint(v)
+	}
+	if cursor.ID, _ = id.(uint); cursor.ID == 0 {
+		return "", errors.New("cannot build a cursor without an ID")
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeListCursor reads a cursor returned as next_cursor
+func decodeListCursor(value string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// listResponse is the data of a list response: the items under key and the
+// pagination fields next to them
+func listResponse(key string, items interface{}, pagination models.Pagination) fiber.Map {
+	data := fiber.Map{
+		key:        items,
+		"total":    pagination.Total,
+		"limit":    pagination.Limit,
+		"pages":    pagination.Pages,
+		"has_more": pagination.HasMore,
+	}
+	if pagination.Page > 0 {
+		data["page"] = pagination.Page
+	}
+	if pagination.NextCursor != "" {
+		data["next_cursor"] = pagination.NextCursor
+	}
+	return data
+}
+
+// sendListError answers a list request with the status and message of a
+// *fiber.Error, or a 500 with the error's message
+func sendListError(c *fiber.Ctx, err error) error {
+	status, message := 500, err.Error()
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status, message = fiberErr.Code, fiberErr.Message
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"success": false,
+		"message": message,
+	})
+}
+
+// parseMediaFilter reads the media list filters and sort from the query
+func parseMediaFilter(c *fiber.Ctx) models.MediaFilter {
+	page, limit := parsePageParams(c)
+	filter := models.MediaFilter{
+		Category:  models.MediaCategory(c.Query("category")),
+		Type:      models.MediaType(c.Query("type")),
+		Tags:      parseTagList(c.Query("tags")),
+		Search:    strings.TrimSpace(c.Query("search")),
+		Page:      page,
+		PageSize:  limit,
+		SortBy:    c.Query("sort_by"),
+		SortOrder: c.Query("sort_order"),
+	}
+	// Only users who may read unpublished media can filter by visibility
+	if value := c.Query("is_public"); value != "" && middleware.HasPermission(c, models.PermMediaRead) {
+		isPublic := c.QueryBool("is_public")
+		filter.IsPublic = &isPublic
+	}
+	if value := c.Query("is_featured"); value != "" {
+		isFeatured := c.QueryBool("is_featured")
+		filter.IsFeatured = &isFeatured
+	}
+	return filter
+}
+
+// applyMediaFilter narrows a media query to the filter. Tags must all be
+// present, and the search is the full-text search of the search endpoints.
+func applyMediaFilter(query *gorm.DB, filter models.MediaFilter) *gorm.DB {
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.IsPublic != nil {
+		query = query.Where("is_public = ?", *filter.IsPublic)
+	}
+	if filter.IsFeatured != nil {
+		query = query.Where("is_featured = ?", *filter.IsFeatured)
+	}
+	if tags := models.NormalizeTags(filter.Tags); len(tags) > 0 {
+		data, _ := json.Marshal(tags)
+		query = query.Where("NULLIF(tags, '')::jsonb @> ?::jsonb", string(data))
+	}
+	if filter.Search != "" {
+		query = query.Where(searchMatch, filter.Search, filter.Search)
+	}
+	return query
+}
+
+// parseContactFilter reads the contact message filters and sort from the
+// query. ?status=read or unread is accepted as well as ?is_read=.
+func parseContactFilter(c *fiber.Ctx) models.ContactFilter {
+	page, limit := parsePageParams(c)
+	filter := models.ContactFilter{
+		Search:    strings.TrimSpace(c.Query("search")),
+		Page:      page,
+		PageSize:  limit,
+		SortBy:    c.Query("sort_by"),
+		SortOrder: c.Query("sort_order"),
+	}
+	switch {
+	case c.Query("is_read") != "":
+		isRead := c.QueryBool("is_read")
+		filter.IsRead = &isRead
+	case c.Query("status") == "read" || c.Query("status") == "unread":
+		isRead := c.Query("status") == "read"
+		filter.IsRead = &isRead
+	}
+	return filter
+}
+
+// applyContactFilter narrows a contact message query to the filter
+func applyContactFilter(query *gorm.DB, filter models.ContactFilter) *gorm.DB {
+	if filter.IsRead != nil {
+		query = query.Where("is_read = ?", *filter.IsRead)
+	}
+	if filter.Search != "" {
+		query = query.Where(searchMatch, filter.Search, filter.Search)
+	}
+	return query
+}
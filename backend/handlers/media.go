@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"os"
 	"path/filepath"
 	"photography-portfolio/config"
+	"photography-portfolio/middleware"
 	"photography-portfolio/models"
+	"photography-portfolio/storage"
+	"photography-portfolio/utils"
 	"strconv"
 	"strings"
 
@@ -17,84 +22,50 @@ import (
 )
 
 type MediaHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db     *gorm.DB
+	cfg    *config.Config
+	store  storage.Storage
+	signer *utils.ImageURLSigner
 }
 
-func NewMediaHandler(db *gorm.DB, cfg *config.Config) *MediaHandler {
+func NewMediaHandler(db *gorm.DB, cfg *config.Config, store storage.Storage, signer *utils.ImageURLSigner) *MediaHandler {
 	return &MediaHandler{
-		db:  db,
-		cfg: cfg,
+		db:     db,
+		cfg:    cfg,
+		store:  store,
+		signer: signer,
 	}
 }
 
 // GetMediaByCategory returns media items for a specific category
 func (h *MediaHandler) GetMediaByCategory(c *fiber.Ctx) error {
 	category := c.Params("category")
-	
-	// Get pagination parameters
-	page, err := strconv.Atoi(c.Query("page", "1"))
-	if err != nil || page < 1 {
-		page = 1
-	}
-	
-	limit, err := strconv.Atoi(c.Query("limit", "20"))
-	if err != nil || limit < 1 || limit > 100 {
-		limit = 20
-	}
-	
-	// Calculate offset
-	offset := (page - 1) * limit
-	
+
 	// Validate category
-	validCategories := []string{"athletes", "food", "nature", "portraits", "action"}
-	isValid := false
-	for _, validCat := range validCategories {
-		if category == validCat {
-			isValid = true
-			break
-		}
-	}
-	
-	if !isValid {
+	if !models.ValidateCategory(category) {
 		return c.Status(400).JSON(fiber.Map{
 			"success": false,
 			"message": "Invalid category",
 		})
 	}
 
-	var media []models.Media
-	var total int64
-	
-	// Get total count
-	h.db.Model(&models.Media{}).Where("category = ?", category).Count(&total)
-	
-	// Get paginated media
-	err = h.db.Where("category = ?", category).
-		Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&media).Error
+	filter := parseMediaFilter(c)
+	filter.Category = models.MediaCategory(category)
+	// The public gallery only lists published media. Trashed media is left
+	// out by the soft delete scope of the query.
+	isPublic := true
+	filter.IsPublic = &isPublic
 
+	media, pagination, err := h.findMedia(c, galleryListSpec, filter)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"success": false,
-			"message": "Failed to fetch media",
-		})
-	}
-
-	// Create GalleryData response structure
-	galleryData := fiber.Map{
-		"category": category,
-		"media":    media,
-		"total":    int(total),
-		"page":     page,
-		"limit":    limit,
+		return sendListError(c, err)
 	}
 
+	data := listResponse("media", media, pagination)
+	data["category"] = category
 	return c.JSON(fiber.Map{
 		"success": true,
-		"data":    galleryData,
+		"data":    data,
 	})
 }
 
@@ -119,6 +90,7 @@ func (h *MediaHandler) GetMediaItem(c *fiber.Ctx) error {
 	// Increment view count
 	media.ViewCount++
 	h.db.Save(&media)
+	setPublicImageURLs(h.signer, &media, cachedWatermarkSettings(h.db))
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -135,16 +107,7 @@ func (h *MediaHandler) UploadMedia(c *fiber.Ctx) error {
 	isFeaturedStr := c.FormValue("is_featured", "false")
 
 	// Validate category
-	validCategories := []string{"athletes", "food", "nature", "portraits", "action"}
-	isValidCategory := false
-	for _, validCat := range validCategories {
-		if category == validCat {
-			isValidCategory = true
-			break
-		}
-	}
-	
-	if !isValidCategory {
+	if !models.ValidateCategory(category) {
 		return c.Status(400).JSON(fiber.Map{
 			"success": false,
 			"message": "Invalid category. Must be one of: athletes, food, nature, portraits, action",
@@ -153,6 +116,14 @@ func (h *MediaHandler) UploadMedia(c *fiber.Ctx) error {
 
 	isFeatured, _ := strconv.ParseBool(isFeaturedStr)
 
+	albumID, err := h.findAlbumID(c.FormValue("album_id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Album not found",
+		})
+	}
+
 	// Get uploaded file
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -163,20 +134,17 @@ func (h *MediaHandler) UploadMedia(c *fiber.Ctx) error {
 	}
 
 	// Validate file type
-	allowedTypes := []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".mp4", ".mov", ".avi"}
 	fileExt := strings.ToLower(filepath.Ext(file.Filename))
-	isValidType := false
-	for _, allowedType := range allowedTypes {
-		if fileExt == allowedType {
-			isValidType = true
-			break
-		}
-	}
-
-	if !isValidType {
+	if !utils.IsAllowedMediaExtension(h.cfg, fileExt) {
 		return c.Status(400).JSON(fiber.Map{
 			"success": false,
-			"message": "Invalid file type. Allowed: jpg, jpeg, png, gif, webp, mp4, mov, avi",
+			"message": h.invalidFileTypeMessage(),
+		})
+	}
+	if limit := utils.MaxMediaFileSize(h.cfg, fileExt); file.Size > limit {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("%s files are limited to %d MB", fileExt, limit/1024/1024),
 		})
 	}
 
@@ -202,41 +170,55 @@ func (h *MediaHandler) UploadMedia(c *fiber.Ctx) error {
 		})
 	}
 
-	// Determine media type
-	var mediaType models.MediaType = models.MediaTypeImage
-	if fileExt == ".mp4" || fileExt == ".mov" || fileExt == ".avi" {
-		mediaType = models.MediaTypeVideo
+	// Check the content matches the claimed type
+	info, err := h.validateStoredFile(filePath, fileExt)
+	if err != nil {
+		return c.Status(mediaFileErrorStatus(err)).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
 	}
 
-	// Create media record
-	media := models.Media{
-		Title:        title,
-		Description:  description,
-		S3URL:        fmt.Sprintf("/uploads/%s", filename),
-		ThumbnailURL: fmt.Sprintf("/uploads/%s", filename), // For now, same as URL
-		Category:     models.MediaCategory(category),
-		Type:         mediaType,
-		IsFeatured:   isFeatured,
-		FileName:     filename,
-		FileSize:     file.Size,
-		ViewCount:    0,
-		UserID:       1, // TODO: Get from auth context
+	duplicate, reject := h.checkDuplicate(info, c.FormValue("allow_duplicate") == "true")
+	if reject {
+		os.Remove(filePath)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"message": duplicateMessage(duplicate),
+			"data":    fiber.Map{"duplicate_of": duplicate.ID},
+		})
 	}
 
-	if err := h.db.Create(&media).Error; err != nil {
-		// Clean up uploaded file if database insert fails
-		os.Remove(filePath)
+	userID, _ := middleware.GetUserIDFromContext(c)
+	media, err := h.createMedia(c, storedMedia{
+		Title:       title,
+		Description: description,
+		Category:    category,
+		Tags:        parseTagList(c.FormValue("tags")),
+		AlbumID:     albumID,
+		IsFeatured:  isFeatured,
+		FileName:    filename,
+		FileSize:    file.Size,
+		Info:        info,
+		UserID:      userID,
+	})
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"success": false,
 			"message": "Failed to save media record",
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"success": true,
 		"message": "Media uploaded successfully",
 		"data":    media,
-	})
+	}
+	if duplicate != nil {
+		response["warning"] = duplicateMessage(duplicate)
+		response["duplicate_of"] = duplicate.ID
+	}
+	return c.JSON(response)
 }
 
 // UpdateMedia updates an existing media record
@@ -257,12 +239,19 @@ func (h *MediaHandler) UpdateMedia(c *fiber.Ctx) error {
 		})
 	}
 
+	before := media
+
 	// Parse request body
 	var updateData struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Category    string `json:"category"`
-		IsFeatured  bool   `json:"is_featured"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Category    string   `json:"category"`
+		IsFeatured  bool     `json:"is_featured"`
+		IsPublic    *bool    `json:"is_public"`
+		Watermark   *string  `json:"watermark"` // "on", "off" or "" to follow the category
+		FocalX      *float64 `json:"focal_x"`   // Percent of the width, set together with focal_y
+		FocalY      *float64 `json:"focal_y"`
+		FocalAuto   bool     `json:"focal_auto"` // Go back to the estimated focal point
 	}
 
 	if err := c.BodyParser(&updateData); err != nil {
@@ -299,6 +288,34 @@ func (h *MediaHandler) UpdateMedia(c *fiber.Ctx) error {
 		media.Description = updateData.Description
 	}
 	media.IsFeatured = updateData.IsFeatured
+	if updateData.IsPublic != nil {
+		media.IsPublic = *updateData.IsPublic
+	}
+	if updateData.Watermark != nil {
+		switch *updateData.Watermark {
+		case models.WatermarkInherit, models.WatermarkOn, models.WatermarkOff:
+			media.Watermark = *updateData.Watermark
+		default:
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "watermark must be on, off or empty",
+			})
+		}
+	}
+	if updateData.FocalAuto {
+		media.FocalSource = ""
+	} else if updateData.FocalX != nil || updateData.FocalY != nil {
+		if updateData.FocalX == nil || updateData.FocalY == nil ||
+			*updateData.FocalX < 0 || *updateData.FocalX > 100 || *updateData.FocalY < 0 || *updateData.FocalY > 100 {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "focal_x and focal_y must both be between 0 and 100",
+			})
+		}
+		media.FocalX = math.Round(*updateData.FocalX*10) / 10
+		media.FocalY = math.Round(*updateData.FocalY*10) / 10
+		media.FocalSource = models.FocalSourceManual
+	}
 
 	if err := h.db.Save(&media).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -307,6 +324,18 @@ func (h *MediaHandler) UpdateMedia(c *fiber.Ctx) error {
 		})
 	}
 
+	invalidateFeatured()
+	recordAudit(h.db, c, "media.update", models.AuditEntityMedia, media.ID, before, media)
+
+	// Renditions with the previous watermark are no longer used
+	watermarks := cachedWatermarkSettings(h.db)
+	if imageVersion(&before, watermarks.ForMedia(&before)) != imageVersion(&media, watermarks.ForMedia(&media)) {
+		go h.store.DeletePrefix(imageStoragePrefix(media.ID))
+	}
+	if media.FocalSource == "" && media.IsImage() {
+		go h.analyzeImage(media.ID)
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Media updated successfully",
@@ -314,7 +343,7 @@ func (h *MediaHandler) UpdateMedia(c *fiber.Ctx) error {
 	})
 }
 
-// DeleteMedia deletes a media record and its file
+// DeleteMedia moves a media item to the trash. The file is kept until the item is purged.
 func (h *MediaHandler) DeleteMedia(c *fiber.Ctx) error {
 	id := c.Params("id")
 
@@ -332,16 +361,7 @@ func (h *MediaHandler) DeleteMedia(c *fiber.Ctx) error {
 		})
 	}
 
-	// Delete file from filesystem
-	if media.FileName != "" {
-		filePath := filepath.Join("uploads/media", media.FileName)
-		if err := os.Remove(filePath); err != nil {
-			// Log error but don't fail the request
-			fmt.Printf("Warning: Failed to delete file %s: %v\n", filePath, err)
-		}
-	}
-
-	// Delete database record
+	// Soft delete database record
 	if err := h.db.Delete(&media).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"success": false,
@@ -349,65 +369,64 @@ func (h *MediaHandler) DeleteMedia(c *fiber.Ctx) error {
 		})
 	}
 
+	invalidateFeatured()
+	recordAudit(h.db, c, "media.delete", models.AuditEntityMedia, media.ID, media, nil)
+
 	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "Media deleted successfully",
+		"message": "Media moved to trash",
 	})
 }
 
 // GetAllMediaAdmin returns all media with pagination for admin
 func (h *MediaHandler) GetAllMediaAdmin(c *fiber.Ctx) error {
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "12"))
-	category := c.Query("category", "")
-	mediaType := c.Query("type", "")
-
-	offset := (page - 1) * limit
-
-	var media []models.Media
-	var total int64
+	media, pagination, err := h.findMedia(c, adminMediaListSpec, parseMediaFilter(c))
+	if err != nil {
+		return sendListError(c, err)
+	}
 
-	query := h.db.Model(&models.Media{})
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    listResponse("media", media, pagination),
+	})
+}
 
-	// Filter by category if specified
-	if category != "" {
-		query = query.Where("category = ?", category)
+// findMedia loads the page of media matched by a filter, sorted by the filter
+// or, with ?color=, by the nearest color
+func (h *MediaHandler) findMedia(c *fiber.Ctx, spec listSpec, filter models.MediaFilter) ([]models.Media, models.Pagination, error) {
+	list, err := newListQuery(spec, filter.Page, filter.PageSize, filter.SortBy, filter.SortOrder, c.Query("cursor"))
+	if err != nil {
+		return nil, models.Pagination{}, fiber.NewError(400, err.Error())
 	}
-
-	// Filter by media type if specified
-	if mediaType != "" {
-		query = query.Where("type = ?", mediaType)
+	colors, err := parseColorFilter(c)
+	if err != nil {
+		return nil, models.Pagination{}, fiber.NewError(400, err.Error())
 	}
 
-	// Get total count
-	query.Count(&total)
-
-	// Get paginated results
-	err := query.Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&media).Error
+	query := applyMediaFilter(h.db.Model(&models.Media{}), filter)
 
+	var media []models.Media
+	var pagination models.Pagination
+	if colors != nil {
+		if list.cursor != nil {
+			return nil, models.Pagination{}, fiber.NewError(400, "cursor cannot be combined with color, which is paged by number")
+		}
+		// Nearest colors first
+		var total int64
+		media, total, err = findByColor(h.db, query, colors, list.Offset(), list.Limit)
+		pagination = list.Paginate(total)
+	} else {
+		pagination, err = list.Find(query, &media)
+	}
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"success": false,
-			"message": "Failed to fetch media",
-		})
+		return nil, models.Pagination{}, fiber.NewError(500, "Failed to fetch media")
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"data": fiber.Map{
-			"media": media,
-			"total": total,
-			"page":  page,
-			"limit": limit,
-			"pages": (total + int64(limit) - 1) / int64(limit),
-		},
-	})
+	h.setImageURLs(c, media)
+	return media, pagination, nil
 }
 
-// BulkDeleteMedia deletes multiple media items
+// BulkDeleteMedia moves multiple media items to the trash
 func (h *MediaHandler) BulkDeleteMedia(c *fiber.Ctx) error {
 	var requestData struct {
 		IDs []uint `json:"ids"`
@@ -427,7 +446,7 @@ func (h *MediaHandler) BulkDeleteMedia(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get all media records first to delete files
+	// Get all media records first for the audit log
 	var mediaList []models.Media
 	if err := h.db.Where("id IN ?", requestData.IDs).Find(&mediaList).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -436,17 +455,7 @@ func (h *MediaHandler) BulkDeleteMedia(c *fiber.Ctx) error {
 		})
 	}
 
-	// Delete files from filesystem
-	for _, media := range mediaList {
-		if media.FileName != "" {
-			filePath := filepath.Join("uploads/media", media.FileName)
-			if err := os.Remove(filePath); err != nil {
-				fmt.Printf("Warning: Failed to delete file %s: %v\n", filePath, err)
-			}
-		}
-	}
-
-	// Delete database records
+	// Soft delete database records
 	result := h.db.Where("id IN ?", requestData.IDs).Delete(&models.Media{})
 	if result.Error != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -455,13 +464,146 @@ func (h *MediaHandler) BulkDeleteMedia(c *fiber.Ctx) error {
 		})
 	}
 
+	invalidateFeatured()
+	for _, media := range mediaList {
+		recordAudit(h.db, c, "media.bulk_delete", models.AuditEntityMedia, media.ID, media, nil)
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
-		"message": fmt.Sprintf("Successfully deleted %d media items", result.RowsAffected),
+		"message": fmt.Sprintf("Moved %d media items to trash", result.RowsAffected),
 		"deleted_count": result.RowsAffected,
 	})
 }
 
+// invalidFileTypeMessage lists the configured image and video types
+func (h *MediaHandler) invalidFileTypeMessage() string {
+	return "Invalid file type. Allowed: " + strings.Join(utils.AllowedMediaExtensions(h.cfg), ", ")
+}
+
+// validateStoredFile sniffs and verifies a saved upload. Rejected files are removed.
+func (h *MediaHandler) validateStoredFile(path, fileExt string) (*utils.MediaFileInfo, error) {
+	info, err := utils.ValidateMediaFile(h.cfg, path, fileExt)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return info, nil
+}
+
+// mediaFileErrorStatus returns the response status for a rejected file
+func mediaFileErrorStatus(err error) int {
+	if errors.Is(err, utils.ErrFileTooLarge) {
+		return fiber.StatusRequestEntityTooLarge
+	}
+	return fiber.StatusUnsupportedMediaType
+}
+
+// storedMedia describes a file saved under uploads/media that needs a media record
+type storedMedia struct {
+	Title       string
+	Description string
+	Category    string
+	Tags        []string
+	AlbumID     *uint
+	IsFeatured  bool
+	FileName    string
+	FileSize    int64
+	Info        *utils.MediaFileInfo // Detected content type and dimensions
+	UserID      uint                 // Uploader
+}
+
+// createMedia creates the media record for a stored file and records it in
+// the audit log. The file is removed if the record cannot be saved.
+func (h *MediaHandler) createMedia(c *fiber.Ctx, stored storedMedia) (*models.Media, error) {
+	media, err := h.insertMedia(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(h.db, c, "media.create", models.AuditEntityMedia, media.ID, nil, media)
+	return media, nil
+}
+
+// insertMedia creates the media record for a stored file. It does not use the
+// request context so batch uploads can call it from worker goroutines.
+func (h *MediaHandler) insertMedia(stored storedMedia) (*models.Media, error) {
+	// Determine media type
+	var mediaType models.MediaType = models.MediaTypeImage
+	if stored.Info.IsVideo {
+		mediaType = models.MediaTypeVideo
+	}
+
+	// Create media record
+	media := models.Media{
+		Title:        stored.Title,
+		Description:  stored.Description,
+		S3URL:        fmt.Sprintf("/uploads/%s", stored.FileName),
+		ThumbnailURL: fmt.Sprintf("/uploads/%s", stored.FileName), // For now, same as URL
+		Category:     models.MediaCategory(stored.Category),
+		Type:         mediaType,
+		IsFeatured:   stored.IsFeatured,
+		FileName:     stored.FileName,
+		FileSize:     stored.FileSize,
+		MimeType:     stored.Info.MimeType,
+		Width:        stored.Info.Width,
+		Height:       stored.Info.Height,
+		ViewCount:    0,
+		ContentHash:  stored.Info.ContentHash,
+		PHash:        stored.Info.PerceptualHash,
+		UserID:       stored.UserID,
+		AlbumID:      stored.AlbumID,
+	}
+	media.SetTags(stored.Tags)
+	if focus := stored.Info.FocalPoint; focus != nil {
+		media.FocalX, media.FocalY = focus.X, focus.Y
+		media.FocalSource = models.FocalSourceAuto
+	}
+	if colors := stored.Info.Colors; colors != nil {
+		media.SetPalette(colors.Palette)
+		media.AverageColor = colors.Average
+		media.BlurHash = colors.BlurHash
+	}
+	if stored.Info.Exif != nil {
+		media.SetExif(stored.Info.Exif)
+	}
+
+	if err := h.db.Create(&media).Error; err != nil {
+		// Clean up uploaded file if database insert fails
+		os.Remove(mediaFilePath(&media))
+		return nil, err
+	}
+
+	if media.IsFeatured {
+		invalidateFeatured()
+	}
+
+	// Probing and transcoding can take minutes for long videos
+	if media.IsVideo() {
+		go h.processVideo(media.ID)
+	}
+	return &media, nil
+}
+
+// setImageURLs adds the signed rendition URLs to a list of media. Only
+// users who can read media see the original files of watermarked images.
+func (h *MediaHandler) setImageURLs(c *fiber.Ctx, media []models.Media) {
+	watermarks := cachedWatermarkSettings(h.db)
+	originals := middleware.HasPermission(c, models.PermMediaRead)
+	for i := range media {
+		if originals {
+			media[i].Images = imageURLs(h.signer, &media[i], watermarks)
+		} else {
+			setPublicImageURLs(h.signer, &media[i], watermarks)
+		}
+	}
+}
+
+// mediaFilePath returns the path of a media item's file on disk
+func mediaFilePath(media *models.Media) string {
+	return filepath.Join("uploads/media", media.FileName)
+}
+
 // Helper function to save uploaded file
 func saveUploadedFile(file *multipart.FileHeader, dst string) error {
 	src, err := file.Open()
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ChangePassword changes the current user's password and logs out their other sessions
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "User not found",
+		})
+	}
+
+	if !user.CheckPassword(req.CurrentPassword) {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Current password is incorrect",
+		})
+	}
+
+	if err := utils.ValidatePassword(h.cfg, req.NewPassword); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+			"policy":  utils.PasswordPolicy(h.cfg),
+		})
+	}
+
+	if user.CheckPassword(req.NewPassword) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "New password must be different from the current password",
+		})
+	}
+
+	if err := user.SetPassword(h.db, req.NewPassword); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update password",
+		})
+	}
+
+	// Keep the current session, log out everywhere else
+	sessionID, _ := middleware.GetSessionIDFromContext(c)
+	h.db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", user.ID, sessionID).
+		Update("revoked_at", time.Now())
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Password changed successfully",
+	})
+}
+
+// ForgotPassword emails a password reset link. The response is the same whether or
+// not the account exists so it cannot be used to discover email addresses.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req models.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	response := fiber.Map{
+		"success": true,
+		"message": "If an account exists for this email, a password reset link has been sent",
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	var user models.User
+	if err := h.db.Where("LOWER(email) = ? AND is_active = ?", email, true).First(&user).Error; err != nil {
+		return c.JSON(response)
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate reset token",
+		})
+	}
+
+	now := time.Now()
+	reset := models.PasswordResetToken{
+		UserID:      user.ID,
+		TokenHash:   utils.HashToken(token),
+		ExpiresAt:   now.Add(h.cfg.PasswordResetExpiresIn),
+		RequestedIP: c.IP(),
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link stays valid
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&reset).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create reset token",
+		})
+	}
+
+	url := fmt.Sprintf("%s/reset-password/%s", h.cfg.CorsOrigin, token)
+	body := fmt.Sprintf(
+		"A password reset was requested for your portfolio admin account.\n\nReset your password here: %s\n\nThis link expires in %s and can only be used once. If you did not request this, you can ignore this email.\n",
+		url, h.cfg.PasswordResetExpiresIn,
+	)
+	if err := utils.SendEmail(h.cfg, user.Email, "Reset your password", body); err != nil {
+		log.Printf("Failed to email password reset for user %d: %v", user.ID, err)
+	}
+
+	return c.JSON(response)
+}
+
+// ResetPassword sets a new password using an emailed reset token
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	var reset models.PasswordResetToken
+	if err := h.db.Where("token_hash = ?", utils.HashToken(req.Token)).First(&reset).Error; err != nil || !reset.IsValid() {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Reset link is invalid or has expired",
+		})
+	}
+
+	if err := utils.ValidatePassword(h.cfg, req.Password); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+			"policy":  utils.PasswordPolicy(h.cfg),
+		})
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ? AND is_active = ?", reset.UserID, true).First(&user).Error; err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Reset link is invalid or has expired",
+		})
+	}
+
+	now := time.Now()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Consume the token first so concurrent requests cannot both use it
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := user.SetPassword(tx, req.Password); err != nil {
+			return err
+		}
+
+		// A successful reset unlocks the account and logs out every session
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"failed_login_attempts": 0,
+			"locked_until":          nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", now).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Reset link is invalid or has expired",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to reset password",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Password reset successfully. You can now log in.",
+	})
+}
+
+// GetPasswordPolicy returns the configured password policy
+func (h *AuthHandler) GetPasswordPolicy(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    utils.PasswordPolicy(h.cfg),
+	})
+}
+
+// registerFailedLogin records a failed attempt and locks the account once the
+// configured limit is reached. It reports whether the account is now locked.
+func (h *AuthHandler) registerFailedLogin(c *fiber.Ctx, user *models.User, reason string) bool {
+	h.recordLoginAttempt(c, user.Email, &user.ID, false, reason)
+
+	if h.cfg.MaxFailedLogins <= 0 {
+		return false
+	}
+
+	h.db.Model(&models.User{}).
+		Where("id = ?", user.ID).
+		UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1"))
+
+	var attempts int
+	h.db.Model(&models.User{}).Where("id = ?", user.ID).Pluck("failed_login_attempts", &attempts)
+	if attempts < h.cfg.MaxFailedLogins {
+		return false
+	}
+
+	lockedUntil := time.Now().Add(h.cfg.LockoutDuration)
+	h.db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          lockedUntil,
+	})
+	user.LockedUntil = &lockedUntil
+	log.Printf("🔒 Account %s locked until %s after %d failed login attempts", user.Email, lockedUntil.Format(time.RFC3339), attempts)
+
+	return true
+}
+
+// clearFailedLogins resets the failed attempt counter after a successful login
+func (h *AuthHandler) clearFailedLogins(user *models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+	h.db.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	})
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+}
+
+// recordLoginAttempt writes a login attempt to the audit trail
+func (h *AuthHandler) recordLoginAttempt(c *fiber.Ctx, email string, userID *uint, success bool, reason string) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) > 255 {
+		email = email[:255]
+	}
+
+	attempt := models.LoginAttempt{
+		Email:     email,
+		UserID:    userID,
+		Success:   success,
+		Reason:    reason,
+		IPAddress: c.IP(),
+		UserAgent: requestUserAgent(c),
+	}
+	if err := h.db.Create(&attempt).Error; err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+}
+
+// accountLockedError writes the response for a temporarily locked account
+func accountLockedError(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+		"success": false,
+		"message": "Account is temporarily locked after too many failed login attempts. Try again later or reset your password.",
+	})
+}
+
+// requestUserAgent returns the request's User-Agent header truncated to fit the database column
+func requestUserAgent(c *fiber.Ctx) string {
+	userAgent := c.Get("User-Agent")
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+	return userAgent
+}
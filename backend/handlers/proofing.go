@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"fmt"
+
+	"photography-portfolio/config"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Proofing galleries share an album with a client through a secret link. The
+// client sees every image in the album, including unpublished ones, but only
+// as watermarked renditions; the originals stay private.
+type ProofingHandler struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	signer *utils.ImageURLSigner
+}
+
+func NewProofingHandler(db *gorm.DB, cfg *config.Config, signer *utils.ImageURLSigner) *ProofingHandler {
+	return &ProofingHandler{
+		db:     db,
+		cfg:    cfg,
+		signer: signer,
+	}
+}
+
+// ShareProofingGallery creates a proofing link for an album. Sharing an album
+// again replaces its link, so earlier links stop working.
+func (h *ProofingHandler) ShareProofingGallery(c *fiber.Ctx) error {
+	var album models.Album
+	if err := h.db.First(&album, c.Params("id")).Error; err != nil {
+		return albumLookupError(c, err)
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate proofing link",
+		})
+	}
+	if err := h.db.Model(&album).Update("proofing_token", token).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to share album",
+		})
+	}
+
+	recordAudit(h.db, c, "album.proofing_share", models.AuditEntityAlbum, album.ID, nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Proofing gallery shared",
+		"data": fiber.Map{
+			"token": token,
+			"url":   fmt.Sprintf("%s/proofing/%s", h.cfg.CorsOrigin, token),
+		},
+	})
+}
+
+// UnshareProofingGallery removes the proofing link of an album
+func (h *ProofingHandler) UnshareProofingGallery(c *fiber.Ctx) error {
+	var album models.Album
+	if err := h.db.First(&album, c.Params("id")).Error; err != nil {
+		return albumLookupError(c, err)
+	}
+
+	if err := h.db.Model(&album).Update("proofing_token", nil).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to unshare album",
+		})
+	}
+
+	recordAudit(h.db, c, "album.proofing_unshare", models.AuditEntityAlbum, album.ID, nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Proofing gallery unshared",
+	})
+}
+
+// GetProofingGallery returns the album shared under a proofing token with
+// the watermarked renditions of its images
+func (h *ProofingHandler) GetProofingGallery(c *fiber.Ctx) error {
+	var album models.Album
+	if err := h.db.Where("proofing_token = ?", c.Params("token")).First(&album).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Proofing gallery not found",
+		})
+	}
+
+	var media []models.Media
+	if err := h.db.Where("album_id = ? AND type = ?", album.ID, models.MediaTypeImage).
+		Order("sort_order ASC, created_at ASC").
+		Find(&media).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch media",
+		})
+	}
+
+	// Images without renditions could only be shown as their originals
+	watermarks := cachedWatermarkSettings(h.db)
+	proofs := make([]models.Media, 0, len(media))
+	for i := range media {
+		setProofingImageURLs(h.signer, &media[i], watermarks)
+		if media[i].Images != nil {
+			proofs = append(proofs, media[i])
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"title":       album.Title,
+			"description": album.Description,
+			"media":       proofs,
+		},
+	})
+}
+
+// setProofingImageURLs adds the signed rendition URLs to a proofing image and
+// points its file URLs at them, whether or not it is watermarked
+func setProofingImageURLs(signer *utils.ImageURLSigner, media *models.Media, watermarks models.WatermarkSettings) {
+	media.Images = imageURLs(signer, media, watermarks)
+	media.S3URL = media.Images["large"]
+	media.ThumbnailURL = media.Images["thumb"]
+	media.FileName = ""
+}
+
+// inProofingGallery checks whether a media item is in an album shared as a
+// proofing gallery
+func inProofingGallery(db *gorm.DB, media *models.Media) bool {
+	if media.AlbumID == nil || media.DeletedAt.Valid || !media.IsImage() {
+		return false
+	}
+	var count int64
+	db.Model(&models.Album{}).Where("id = ? AND proofing_token IS NOT NULL", *media.AlbumID).Count(&count)
+	return count > 0
+}
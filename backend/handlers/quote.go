@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"photography-portfolio/config"
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/checkout/session"
+	"gorm.io/gorm"
+)
+
+var (
+	// errQuoteNotAcceptable is returned when a quote was accepted, declined
+	// or expired while it was being accepted
+	errQuoteNotAcceptable = errors.New("quote can no longer be accepted")
+)
+
+type QuoteHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewQuoteHandler(db *gorm.DB, cfg *config.Config) *QuoteHandler {
+	return &QuoteHandler{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// CreateQuote creates a new draft quote with line items (admin only)
+func (h *QuoteHandler) CreateQuote(c *fiber.Ctx) error {
+	var req models.QuoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	req.ClientName = strings.TrimSpace(req.ClientName)
+	req.ClientEmail = strings.TrimSpace(req.ClientEmail)
+
+	if req.Title == "" || req.ClientName == "" || req.ClientEmail == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Title, client name and client email are required",
+		})
+	}
+
+	// Checks the constraints in the QuoteRequest validate tags. The client
+	// email and title end up in mail headers.
+	if !utils.IsValidEmail(req.ClientEmail) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "A valid client email is required",
+		})
+	}
+	if len(req.Title) > 255 || len(req.ClientName) > 255 || len(req.ClientPhone) > 50 || len(req.Location) > 500 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Title, client name, client phone or location is too long",
+		})
+	}
+	if req.Duration < 0 || req.Duration > 24 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Duration must be between 0 and 24 hours",
+		})
+	}
+
+	if !models.ValidateServiceType(string(req.ServiceType)) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid service type",
+		})
+	}
+
+	if len(req.Items) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "At least one line item is required",
+		})
+	}
+
+	items := make([]models.QuoteItem, 0, len(req.Items))
+	for i, item := range req.Items {
+		description := strings.TrimSpace(item.Description)
+		if description == "" || len(description) > 500 || item.Quantity < 0 || item.UnitPrice < 0 {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": fmt.Sprintf("Invalid line item at position %d", i+1),
+			})
+		}
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+		items = append(items, models.QuoteItem{
+			Description: description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			SortOrder:   i,
+		})
+	}
+
+	var scheduledDate *time.Time
+	if req.ScheduledDate != "" {
+		date, err := parseISODate(req.ScheduledDate)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "Invalid scheduled date format. Use ISO format (YYYY-MM-DD or YYYY-MM-DDTHH:MM:SSZ)",
+			})
+		}
+		scheduledDate = &date
+	}
+
+	expiresAt := time.Now().Add(models.DefaultQuoteValidity)
+	if req.ExpiresAt != "" {
+		date, err := parseISODate(req.ExpiresAt)
+		if err != nil || date.Before(time.Now()) {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "Expiry date must be a future ISO date",
+			})
+		}
+		expiresAt = date
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate quote token",
+		})
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+
+	quote := models.Quote{
+		Token:         token,
+		Title:         req.Title,
+		ClientName:    req.ClientName,
+		ClientEmail:   req.ClientEmail,
+		ClientPhone:   strings.TrimSpace(req.ClientPhone),
+		ServiceType:   req.ServiceType,
+		Description:   req.Description,
+		Location:      req.Location,
+		ScheduledDate: scheduledDate,
+		Duration:      req.Duration,
+		Terms:         req.Terms,
+		ExpiresAt:     expiresAt,
+		Status:        models.QuoteStatusDraft,
+		UserID:        userID,
+		Items:         items,
+	}
+	quote.Total = quote.CalculateTotal()
+
+	if quote.Total <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Quote total must be greater than zero",
+		})
+	}
+
+	if err := h.db.Create(&quote).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create quote",
+		})
+	}
+
+	recordAudit(h.db, c, "quote.create", models.AuditEntityQuote, quote.ID, nil, quote.ToResponse())
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Quote created successfully",
+		"data": fiber.Map{
+			"quote": quote.ToResponse(),
+			"url":   h.quoteURL(&quote),
+		},
+	})
+}
+
+// GetQuotes returns all quotes, optionally filtered by status (admin only)
+func (h *QuoteHandler) GetQuotes(c *fiber.Ctx) error {
+	status := c.Query("status", "")
+
+	query := h.db.Model(&models.Quote{}).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var quotes []models.Quote
+	if err := query.Order("created_at DESC").Find(&quotes).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch quotes",
+		})
+	}
+
+	responses := make([]models.QuoteResponse, 0, len(quotes))
+	for _, quote := range quotes {
+		responses = append(responses, quote.ToResponse())
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    responses,
+	})
+}
+
+// GetQuote returns a single quote with its public URL (admin only)
+func (h *QuoteHandler) GetQuote(c *fiber.Ctx) error {
+	quote, err := h.findQuote(h.db.Where("id = ?", c.Params("id")))
+	if err != nil {
+		return quoteLookupError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"quote": quote.ToResponse(),
+			"url":   h.quoteURL(quote),
+		},
+	})
+}
+
+// SendQuote marks a quote as sent and emails the client a link to it (admin only)
+func (h *QuoteHandler) SendQuote(c *fiber.Ctx) error {
+	quote, err := h.findQuote(h.db.Where("id = ?", c.Params("id")))
+	if err != nil {
+		return quoteLookupError(c, err)
+	}
+
+	if quote.Status != models.QuoteStatusDraft && quote.Status != models.QuoteStatusSent {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("Quote has already been %s", quote.Status),
+		})
+	}
+
+	if quote.IsExpired() {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Quote has expired",
+		})
+	}
+
+	// Accepting the quote books the session, which needs a date
+	if quote.ScheduledDate == nil {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Quote needs a scheduled date before it is sent",
+		})
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nYour quote \"%s\" for $%.2f is ready.\n\nReview and accept it here: %s\n\nThis quote is valid until %s.\n",
+		quote.ClientName, quote.Title, quote.Total, h.quoteURL(quote), quote.ExpiresAt.Format("January 2, 2006"),
+	)
+	if err := utils.SendEmail(h.cfg, quote.ClientEmail, "Your photography quote: "+quote.Title, body); err != nil {
+		log.Printf("Failed to email quote %d: %v", quote.ID, err)
+		return c.Status(502).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to send quote email",
+		})
+	}
+
+	now := time.Now()
+	quote.Status = models.QuoteStatusSent
+	quote.SentAt = &now
+	if err := h.db.Model(quote).Updates(map[string]interface{}{
+		"status":  quote.Status,
+		"sent_at": now,
+	}).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update quote",
+		})
+	}
+
+	recordAudit(h.db, c, "quote.send", models.AuditEntityQuote, quote.ID, nil, fiber.Map{"status": quote.Status})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Quote sent successfully",
+		"data": fiber.Map{
+			"quote": quote.ToResponse(),
+			"url":   h.quoteURL(quote),
+		},
+	})
+}
+
+// GetQuoteByToken returns the public view of a quote
+func (h *QuoteHandler) GetQuoteByToken(c *fiber.Ctx) error {
+	quote, err := h.findQuote(h.db.Where("token = ?", c.Params("token")))
+	if err != nil {
+		return quoteLookupError(c, err)
+	}
+
+	if quote.Status == models.QuoteStatusDraft {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Quote not found",
+		})
+	}
+
+	if quote.ViewedAt == nil {
+		now := time.Now()
+		quote.ViewedAt = &now
+		h.db.Model(quote).Update("viewed_at", now)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    quote.ToResponse(),
+	})
+}
+
+// AcceptQuote accepts a quote, creates the booking and starts a Stripe checkout for the quoted amount
+func (h *QuoteHandler) AcceptQuote(c *fiber.Ctx) error {
+	quote, err := h.findQuote(h.db.Where("token = ?", c.Params("token")))
+	if err != nil {
+		return quoteLookupError(c, err)
+	}
+
+	if !quote.CanBeAccepted() {
+		message := "Quote can no longer be accepted"
+		if quote.IsExpired() {
+			message = "Quote has expired"
+		}
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": message,
+		})
+	}
+
+	// Quotes sent before a date was required cannot become a booking yet
+	if quote.ScheduledDate == nil {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Quote has no scheduled date yet, please contact us to set one",
+		})
+	}
+
+	now := time.Now()
+	booking := models.Booking{
+		ClientName:    quote.ClientName,
+		ClientEmail:   quote.ClientEmail,
+		ClientPhone:   quote.ClientPhone,
+		ServiceType:   quote.ServiceType,
+		Description:   quote.Title,
+		Location:      quote.Location,
+		ScheduledDate: *quote.ScheduledDate,
+		Duration:      quote.Duration,
+		Price:         quote.Total,
+		Status:        models.BookingStatusPending,
+		Notes:         fmt.Sprintf("Accepted quote #%d", quote.ID),
+		PaymentStatus: "pending",
+		UserID:        quote.UserID,
+	}
+
+	// The quote is only accepted once. Stripe is called after the booking is
+	// committed, so no database transaction waits on it, and a failed checkout
+	// reopens the quote to accept again.
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Quote{}).
+			Where("id = ? AND status = ? AND expires_at > ?", quote.ID, models.QuoteStatusSent, now).
+			Updates(map[string]interface{}{
+				"status":      models.QuoteStatusAccepted,
+				"accepted_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errQuoteNotAcceptable
+		}
+		if err := tx.Create(&booking).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Quote{}).Where("id = ?", quote.ID).Update("booking_id", booking.ID).Error
+	})
+	if errors.Is(err, errQuoteNotAcceptable) {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Quote can no longer be accepted",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to accept quote",
+		})
+	}
+
+	sess, err := h.createCheckout(quote, &booking)
+	if err != nil {
+		log.Printf("Failed to create checkout session for quote %d: %v", quote.ID, err)
+		if err := h.reopenQuote(quote, &booking); err != nil {
+			log.Printf("Failed to reopen quote %d after its checkout failed: %v", quote.ID, err)
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create checkout session",
+		})
+	}
+
+	quote.Status = models.QuoteStatusAccepted
+	quote.AcceptedAt = &now
+	quote.BookingID = &booking.ID
+
+	recordExternalAudit(h.db, c, models.AuditActorClient, quote.ClientName, "quote.accept", models.AuditEntityQuote, quote.ID, nil, fiber.Map{
+		"status":     quote.Status,
+		"booking_id": booking.ID,
+	})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Quote accepted",
+		"data": fiber.Map{
+			"checkout_url": sess.URL,
+			"session_id":   sess.ID,
+			"booking_id":   booking.ID,
+			"quote":        quote.ToResponse(),
+		},
+	})
+}
+
+// createCheckout creates the Stripe checkout session paying for a quote's
+// booking and stores it on the booking. The idempotency key lets Stripe return
+// the same session if the request is retried.
+func (h *QuoteHandler) createCheckout(quote *models.Quote, booking *models.Booking) (*stripe.CheckoutSession, error) {
+	params := h.checkoutParams(quote, booking.ID)
+	params.SetIdempotencyKey(fmt.Sprintf("booking-%d-checkout", booking.ID))
+	sess, err := session.New(params)
+	if err != nil {
+		return nil, err
+	}
+
+	booking.StripeSessionID = sess.ID
+	if err := h.db.Model(booking).Update("stripe_session_id", sess.ID).Error; err != nil {
+		// The booking is about to be removed, so nobody may pay for it
+		if _, expireErr := session.Expire(sess.ID, nil); expireErr != nil {
+			log.Printf("Failed to expire checkout session %s: %v", sess.ID, expireErr)
+		}
+		return nil, err
+	}
+	return sess, nil
+}
+
+// reopenQuote undoes an acceptance whose checkout could not be started,
+// removing its booking and making the quote acceptable again
+func (h *QuoteHandler) reopenQuote(quote *models.Quote, booking *models.Booking) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Quote{}).
+			Where("id = ? AND status = ? AND booking_id = ?", quote.ID, models.QuoteStatusAccepted, booking.ID).
+			Updates(map[string]interface{}{
+				"status":      models.QuoteStatusSent,
+				"accepted_at": nil,
+				"booking_id":  nil,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(booking).Error
+	})
+}
+
+// checkoutParams builds the Stripe checkout session paying for a quote's booking
+func (h *QuoteHandler) checkoutParams(quote *models.Quote, bookingID uint) *stripe.CheckoutSessionParams {
+	return &stripe.CheckoutSessionParams{
+		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+					Currency: stripe.String(string(stripe.CurrencyUSD)),
+					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+						Name: stripe.String(quote.Title),
+					},
+					UnitAmount: stripe.Int64(int64(math.Round(quote.Total * 100))),
+				},
+				Quantity: stripe.Int64(1),
+			},
+		},
+		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
+		SuccessURL:        stripe.String(fmt.Sprintf("%s/booking/success?session_id={CHECKOUT_SESSION_ID}", h.cfg.CorsOrigin)),
+		CancelURL:         stripe.String(fmt.Sprintf("%s/booking/cancel", h.cfg.CorsOrigin)),
+		ClientReferenceID: stripe.String(fmt.Sprintf("%d", bookingID)),
+		CustomerEmail:     stripe.String(quote.ClientEmail),
+		Metadata: map[string]string{
+			"booking_id":   fmt.Sprintf("%d", bookingID),
+			"quote_id":     fmt.Sprintf("%d", quote.ID),
+			"service_type": string(quote.ServiceType),
+			"client_name":  quote.ClientName,
+		},
+	}
+}
+
+// DeclineQuote lets the client decline a quote
+func (h *QuoteHandler) DeclineQuote(c *fiber.Ctx) error {
+	quote, err := h.findQuote(h.db.Where("token = ?", c.Params("token")))
+	if err != nil {
+		return quoteLookupError(c, err)
+	}
+
+	if quote.Status != models.QuoteStatusSent {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Quote can no longer be declined",
+		})
+	}
+
+	now := time.Now()
+	quote.Status = models.QuoteStatusDeclined
+	quote.DeclinedAt = &now
+	if err := h.db.Model(quote).Updates(map[string]interface{}{
+		"status":      quote.Status,
+		"declined_at": now,
+	}).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to decline quote",
+		})
+	}
+
+	recordExternalAudit(h.db, c, models.AuditActorClient, quote.ClientName, "quote.decline", models.AuditEntityQuote, quote.ID, nil, fiber.Map{"status": quote.Status})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Quote declined",
+		"data":    quote.ToResponse(),
+	})
+}
+
+// findQuote loads a single quote with its ordered line items
+func (h *QuoteHandler) findQuote(query *gorm.DB) (*models.Quote, error) {
+	var quote models.Quote
+	err := query.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).First(&quote).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// quoteURL returns the client-facing URL for a quote
+func (h *QuoteHandler) quoteURL(quote *models.Quote) string {
+	return fmt.Sprintf("%s/quotes/%s", h.cfg.CorsOrigin, quote.Token)
+}
+
+// quoteLookupError converts a quote lookup error into a JSON response
+func quoteLookupError(c *fiber.Ctx, err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Quote not found",
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"success": false,
+		"message": "Database error",
+	})
+}
+
+// parseISODate parses a date in YYYY-MM-DDTHH:MM:SSZ or YYYY-MM-DD format
+func parseISODate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02T15:04:05Z", value); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
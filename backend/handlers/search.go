@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchLength    = 200
+)
+
+// searchMatch matches rows whose search_vector contains the words of a
+// web-style query (quoted phrases, "or" and -exclusions). The english query
+// matches stemmed words, the simple one names and e-mail addresses as typed.
+const searchMatch = "search_vector @@ (websearch_to_tsquery('english', ?) || websearch_to_tsquery('simple', ?))"
+
+// Result types returned by the search
+const (
+	searchTypeMedia   = "media"
+	searchTypeAlbum   = "album"
+	searchTypeMessage = "message"
+	searchTypeBooking = "booking"
+)
+
+// searchSource is a table searched by the search endpoints. Title is the
+// column shown as the result title, Text the text highlights are cut from
+// and Where the rows that can be found.
+type searchSource struct {
+	Type  string
+	Table string
+	Title string
+	Text  string
+	Where string
+}
+
+var (
+	publicMediaSource = searchSource{
+		Type:  searchTypeMedia,
+		Table: "media",
+		Title: "title",
+		Text:  "concat_ws(' ', title, description, alt)",
+		Where: "deleted_at IS NULL AND is_public = true",
+	}
+	publicAlbumSource = searchSource{
+		Type:  searchTypeAlbum,
+		Table: "albums",
+		Title: "title",
+		Text:  "concat_ws(' ', title, description)",
+		Where: "deleted_at IS NULL AND is_public = true",
+	}
+	mediaSource = searchSource{
+		Type:  searchTypeMedia,
+		Table: "media",
+		Title: "title",
+		Text:  "concat_ws(' ', title, description, alt)",
+		Where: "deleted_at IS NULL",
+	}
+	albumSource = searchSource{
+		Type:  searchTypeAlbum,
+		Table: "albums",
+		Title: "title",
+		Text:  "concat_ws(' ', title, description)",
+		Where: "deleted_at IS NULL",
+	}
+	messageSource = searchSource{
+		Type:  searchTypeMessage,
+		Table: "contact_messages",
+		Title: "subject",
+		Text:  "concat_ws(' ', subject, name, email, message)",
+		Where: "deleted_at IS NULL",
+	}
+	bookingSource = searchSource{
+		Type:  searchTypeBooking,
+		Table: "bookings",
+		Title: "client_name",
+		Text:  "concat_ws(' ', client_name, client_email, location, description, notes)",
+		Where: "deleted_at IS NULL",
+	}
+)
+
+// SearchResult is a search match with the matched text highlighted in <mark>
+// tags. The rest of the highlight is HTML-escaped.
+type SearchResult struct {
+	Type      string      `json:"type"`
+	ID        uint        `json:"id"`
+	Title     string      `json:"title"`
+	Highlight string      `json:"highlight"`
+	Rank      float64     `json:"rank"`
+	Item      interface{} `json:"item" gorm:"-"`
+}
+
+type SearchHandler struct {
+	db     *gorm.DB
+	signer *utils.ImageURLSigner
+}
+
+func NewSearchHandler(db *gorm.DB, signer *utils.ImageURLSigner) *SearchHandler {
+	return &SearchHandler{db: db, signer: signer}
+}
+
+// Search finds public media and albums
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	return h.search(c, []searchSource{publicMediaSource, publicAlbumSource}, true)
+}
+
+// AdminSearch finds media, albums, contact messages and bookings, limited
+// to what the user's role may read
+func (h *SearchHandler) AdminSearch(c *fiber.Ctx) error {
+	var sources []searchSource
+	if middleware.HasPermission(c, models.PermMediaRead) {
+		sources = append(sources, mediaSource, albumSource)
+	}
+	if middleware.HasPermission(c, models.PermMessagesRead) {
+		sources = append(sources, messageSource)
+	}
+	if middleware.HasPermission(c, models.PermBookingsRead) {
+		sources = append(sources, bookingSource)
+	}
+	if len(sources) == 0 {
+		return c.Status(403).JSON(fiber.Map{
+			"success": false,
+			"message": "Insufficient permissions",
+		})
+	}
+	return h.search(c, sources, false)
+}
+
+// search ranks the matches of ?q= in the sources, optionally limited to one
+// ?type=, and returns a page of them with per-type counts. Public searches
+// do not show the original files of watermarked images.
+func (h *SearchHandler) search(c *fiber.Ctx, sources []searchSource, public bool) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Search query is required",
+		})
+	}
+	if len(query) > maxSearchLength {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("Search query must be at most %d characters", maxSearchLength),
+		})
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultSearchLimit)))
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	searched := sources
+	if resultType := c.Query("type"); resultType != "" {
+		searched = nil
+		for _, source := range sources {
+			if source.Type == resultType {
+				searched = append(searched, source)
+			}
+		}
+		if len(searched) == 0 {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "Invalid result type",
+			})
+		}
+	}
+
+	// Counts cover every source so the type filters can show them
+	counts := make(map[string]int64, len(sources))
+	for _, source := range sources {
+		counts[source.Type] = 0
+	}
+	var countRows []struct {
+		Type  string
+		Count int64
+	}
+	if err := h.db.Raw(countSearchSQL(sources), map[string]interface{}{"q": query}).Scan(&countRows).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to search",
+		})
+	}
+	for _, row := range countRows {
+		counts[row.Type] = row.Count
+	}
+	var total int64
+	for _, source := range searched {
+		total += counts[source.Type]
+	}
+
+	var results []SearchResult
+	if err := h.db.Raw(searchSQL(searched), map[string]interface{}{
+		"q":      query,
+		"limit":  limit,
+		"offset": (page - 1) * limit,
+	}).Scan(&results).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to search",
+		})
+	}
+	if err := h.loadSearchItems(results, public); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to search",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"query":   query,
+			"results": results,
+			"counts":  counts,
+			"total":   total,
+			"page":    page,
+			"limit":   limit,
+			"pages":   (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// searchSQL ranks the matches of @q in the sources and highlights the page
+// of them selected by @limit and @offset. Highlights are only cut for the
+// page, as ts_headline is slow.
+func searchSQL(sources []searchSource) string {
+	selects := make([]string, len(sources))
+	for i, source := range sources {
+		selects[i] = fmt.Sprintf(`SELECT '%s' AS type, id, %s AS title, %s AS body,
+			ts_rank_cd(search_vector, terms.query) AS rank
+		FROM %s, terms
+		WHERE search_vector @@ terms.query AND %s`,
+			source.Type, source.Title, source.Text, source.Table, source.Where)
+	}
+	return fmt.Sprintf(`WITH terms AS (
+		SELECT websearch_to_tsquery('english', @q) || websearch_to_tsquery('simple', @q) AS query
+	), ranked AS (
+		%s
+		ORDER BY rank DESC, id DESC
+		LIMIT @limit OFFSET @offset
+	)
+	SELECT type, id, title, rank,
+		ts_headline('english', replace(replace(replace(coalesce(body, ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+			terms.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10') AS highlight
+	FROM ranked, terms
+	ORDER BY rank DESC, id DESC`, strings.Join(selects, "\n\t\tUNION ALL\n\t\t"))
+}
+
+// countSearchSQL counts the matches of @q in each source
+func countSearchSQL(sources []searchSource) string {
+	selects := make([]string, len(sources))
+	for i, source := range sources {
+		selects[i] = fmt.Sprintf(`SELECT '%s' AS type, count(*) AS count FROM %s, terms
+		WHERE search_vector @@ terms.query AND %s`, source.Type, source.Table, source.Where)
+	}
+	return fmt.Sprintf(`WITH terms AS (
+		SELECT websearch_to_tsquery('english', @q) || websearch_to_tsquery('simple', @q) AS query
+	)
+	%s`, strings.Join(selects, "\n\tUNION ALL\n\t"))
+}
+
+// loadSearchItems sets the item of each search result
+func (h *SearchHandler) loadSearchItems(results []SearchResult, public bool) error {
+	ids := make(map[string][]uint)
+	for _, result := range results {
+		ids[result.Type] = append(ids[result.Type], result.ID)
+	}
+	items := make(map[string]map[uint]interface{})
+
+	if len(ids[searchTypeMedia]) > 0 {
+		var media []models.Media
+		if err := h.db.Where("id IN ?", ids[searchTypeMedia]).Find(&media).Error; err != nil {
+			return err
+		}
+		watermarks := cachedWatermarkSettings(h.db)
+		items[searchTypeMedia] = make(map[uint]interface{}, len(media))
+		for i := range media {
+			if public {
+				setPublicImageURLs(h.signer, &media[i], watermarks)
+			} else {
+				media[i].Images = imageURLs(h.signer, &media[i], watermarks)
+			}
+			items[searchTypeMedia][media[i].ID] = media[i]
+		}
+	}
+	if len(ids[searchTypeAlbum]) > 0 {
+		var albums []models.Album
+		if err := h.db.Where("id IN ?", ids[searchTypeAlbum]).Find(&albums).Error; err != nil {
+			return err
+		}
+		items[searchTypeAlbum] = make(map[uint]interface{}, len(albums))
+		for _, album := range albums {
+			items[searchTypeAlbum][album.ID] = album
+		}
+	}
+	if len(ids[searchTypeMessage]) > 0 {
+		var messages []models.ContactMessage
+		if err := h.db.Where("id IN ?", ids[searchTypeMessage]).Find(&messages).Error; err != nil {
+			return err
+		}
+		items[searchTypeMessage] = make(map[uint]interface{}, len(messages))
+		for _, message := range messages {
+			items[searchTypeMessage][message.ID] = message
+		}
+	}
+	if len(ids[searchTypeBooking]) > 0 {
+		var bookings []models.Booking
+		if err := h.db.Where("id IN ?", ids[searchTypeBooking]).Find(&bookings).Error; err != nil {
+			return err
+		}
+		items[searchTypeBooking] = make(map[uint]interface{}, len(bookings))
+		for _, booking := range bookings {
+			items[searchTypeBooking][booking.ID] = booking.ToResponse()
+		}
+	}
+
+	for i := range results {
+		results[i].Item = items[results[i].Type][results[i].ID]
+	}
+	return nil
+}
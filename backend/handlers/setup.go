@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"strings"
+	"sync"
+
+	"photography-portfolio/config"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// SetupRequest represents the request payload for creating the first owner account
+type SetupRequest struct {
+	SetupToken string `json:"setup_token"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+}
+
+type SetupHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewSetupHandler creates the setup handler. If no users exist yet, the setup token
+// is taken from SETUP_TOKEN or generated and printed once to the log.
+func NewSetupHandler(db *gorm.DB, cfg *config.Config) *SetupHandler {
+	h := &SetupHandler{
+		db:  db,
+		cfg: cfg,
+	}
+
+	required, err := models.SetupRequired(db)
+	if err != nil {
+		log.Printf("⚠️  Warning: Failed to check setup status: %v", err)
+		return h
+	}
+	if !required {
+		return h
+	}
+
+	if cfg.SetupToken != "" {
+		h.token = cfg.SetupToken
+		log.Println("🔐 First-run setup required. Use the SETUP_TOKEN from the environment with POST /api/setup")
+		return h
+	}
+
+	token, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		log.Printf("⚠️  Warning: Failed to generate setup token: %v", err)
+		return h
+	}
+	h.token = token
+	log.Println("🔐 First-run setup required. Create the owner account with POST /api/setup using this one-time token:")
+	log.Printf("   Setup token: %s", token)
+
+	return h
+}
+
+// GetStatus reports whether the first-run setup still needs to be completed
+func (h *SetupHandler) GetStatus(c *fiber.Ctx) error {
+	required, err := models.SetupRequired(h.db)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Database error",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"setup_required": required,
+		},
+	})
+}
+
+// CompleteSetup consumes the setup token and creates the first owner account
+func (h *SetupHandler) CompleteSetup(c *fiber.Ctx) error {
+	var req SetupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	required, err := models.SetupRequired(h.db)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Database error",
+		})
+	}
+	if !required || h.token == "" {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Setup has already been completed",
+		})
+	}
+
+	if subtle.ConstantTimeCompare([]byte(req.SetupToken), []byte(h.token)) != 1 {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid setup token",
+		})
+	}
+
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Email == "" || !strings.Contains(req.Email, "@") {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "A valid email is required",
+		})
+	}
+
+	if err := utils.ValidatePassword(h.cfg, req.Password); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+			"policy":  utils.PasswordPolicy(h.cfg),
+		})
+	}
+
+	owner := models.User{
+		Email:     req.Email,
+		Password:  req.Password, // Hashed by the BeforeCreate hook
+		FirstName: strings.TrimSpace(req.FirstName),
+		LastName:  strings.TrimSpace(req.LastName),
+		Role:      models.RoleOwner,
+		IsActive:  true,
+	}
+
+	if err := h.db.Create(&owner).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create owner account",
+		})
+	}
+
+	// The token is single use
+	h.token = ""
+	log.Printf("👤 Setup completed, owner account created for %s", owner.Email)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Setup completed. You can now log in.",
+		"data":    owner.ToResponse(),
+	})
+}
@@ -268,16 +268,37 @@ func (h *StripeHandler) handleCheckoutSessionCompleted(session *stripe.CheckoutS
 		return fmt.Errorf("booking not found: %v", err)
 	}
 
-	// Update booking status
+	// Only the payment columns are written, so a contract signed while the
+	// webhook was in flight is not overwritten with the stale status read above
+	before := booking
 	now := time.Now()
-	booking.Status = models.BookingStatusConfirmed
-	booking.PaymentStatus = "paid"
-	booking.PaidAt = &now
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&booking).Updates(map[string]interface{}{
+			"payment_status": "paid",
+			"paid_at":        now,
+		}).Error; err != nil {
+			return err
+		}
 
-	if err := h.db.Save(&booking).Error; err != nil {
+		// Bookings with an unsigned contract stay pending until the contract is signed
+		return tx.Model(&models.Booking{}).
+			Where("id = ? AND status = ? AND (contract_status IS NULL OR contract_status <> ?)",
+				booking.ID, models.BookingStatusPending, models.ContractStatusPending).
+			Update("status", models.BookingStatusConfirmed).Error
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update booking: %v", err)
 	}
+	if err := h.db.First(&booking, booking.ID).Error; err != nil {
+		return fmt.Errorf("failed to reload booking: %v", err)
+	}
+
+	recordExternalAudit(h.db, nil, models.AuditActorWebhook, "stripe", "booking.payment_completed", models.AuditEntityBooking, booking.ID, before, booking)
 
-	log.Printf("Booking %d confirmed and marked as paid", booking.ID)
+	if booking.IsConfirmed() {
+		log.Printf("Booking %d confirmed and marked as paid", booking.ID)
+	} else {
+		log.Printf("Booking %d marked as paid, awaiting contract signature", booking.ID)
+	}
 	return nil
 } 
\ No newline at end of file
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"photography-portfolio/config"
+	"photography-portfolio/models"
+	"photography-portfolio/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// trashKind describes a soft-deletable model that can be restored from the trash
+type trashKind struct {
+	name    string
+	action  string // Audit action prefix
+	entity  string // Audit entity type
+	model   interface{}
+	newList func() interface{}
+	purge   func(tx *gorm.DB, id uint) ([]string, error) // Removes related rows and returns files to delete
+	stored  func(id uint) []string                       // Storage prefixes of generated files to delete
+}
+
+var (
+	trashMedia = trashKind{
+		name:    "media",
+		action:  "media",
+		entity:  models.AuditEntityMedia,
+		model:   &models.Media{},
+		newList: func() interface{} { return &[]models.Media{} },
+		purge:   purgeMediaRecords,
+		stored:  mediaStoragePrefixes,
+	}
+	trashMessages = trashKind{
+		name:    "messages",
+		action:  "message",
+		entity:  models.AuditEntityMessage,
+		model:   &models.ContactMessage{},
+		newList: func() interface{} { return &[]models.ContactMessage{} },
+	}
+	trashBookings = trashKind{
+		name:    "bookings",
+		action:  "booking",
+		entity:  models.AuditEntityBooking,
+		model:   &models.Booking{},
+		newList: func() interface{} { return &[]models.Booking{} },
+		purge:   purgeBookingRecords,
+	}
+)
+
+type TrashHandler struct {
+	db    *gorm.DB
+	cfg   *config.Config
+	store storage.Storage
+}
+
+func NewTrashHandler(db *gorm.DB, cfg *config.Config, store storage.Storage) *TrashHandler {
+	return &TrashHandler{
+		db:    db,
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+// GetMediaTrash returns deleted media items
+func (h *TrashHandler) GetMediaTrash(c *fiber.Ctx) error {
+	return h.list(c, trashMedia)
+}
+
+// RestoreMedia restores a deleted media item
+func (h *TrashHandler) RestoreMedia(c *fiber.Ctx) error {
+	return h.restore(c, trashMedia)
+}
+
+// PurgeMedia permanently deletes a media item in the trash and its file
+func (h *TrashHandler) PurgeMedia(c *fiber.Ctx) error {
+	return h.purge(c, trashMedia)
+}
+
+// GetMessageTrash returns deleted contact messages
+func (h *TrashHandler) GetMessageTrash(c *fiber.Ctx) error {
+	return h.list(c, trashMessages)
+}
+
+// RestoreMessage restores a deleted contact message
+func (h *TrashHandler) RestoreMessage(c *fiber.Ctx) error {
+	return h.restore(c, trashMessages)
+}
+
+// PurgeMessage permanently deletes a contact message in the trash
+func (h *TrashHandler) PurgeMessage(c *fiber.Ctx) error {
+	return h.purge(c, trashMessages)
+}
+
+// GetBookingTrash returns deleted bookings
+func (h *TrashHandler) GetBookingTrash(c *fiber.Ctx) error {
+	return h.list(c, trashBookings)
+}
+
+// RestoreBooking restores a deleted booking
+func (h *TrashHandler) RestoreBooking(c *fiber.Ctx) error {
+	return h.restore(c, trashBookings)
+}
+
+// PurgeBooking permanently deletes a booking in the trash with its contracts
+func (h *TrashHandler) PurgeBooking(c *fiber.Ctx) error {
+	return h.purge(c, trashBookings)
+}
+
+// StartPurge periodically purges items that have been in the trash longer
+// than the retention period
+func (h *TrashHandler) StartPurge(checkEvery time.Duration) {
+	if h.cfg.TrashRetention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(checkEvery)
+		defer ticker.Stop()
+		for {
+			h.PurgeExpired()
+			<-ticker.C
+		}
+	}()
+}
+
+// PurgeExpired permanently deletes all items whose retention period has passed
+func (h *TrashHandler) PurgeExpired() {
+	cutoff := time.Now().Add(-h.cfg.TrashRetention)
+	for _, kind := range []trashKind{trashMedia, trashMessages, trashBookings} {
+		var ids []uint
+		if err := h.db.Unscoped().Model(kind.model).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Pluck("id", &ids).Error; err != nil {
+			log.Printf("⚠️  Warning: Failed to find expired %s in trash: %v", kind.name, err)
+			continue
+		}
+
+		for _, id := range ids {
+			if err := h.purgeItem(kind, id); err != nil {
+				log.Printf("⚠️  Warning: Failed to purge %s %d: %v", kind.name, id, err)
+				continue
+			}
+			recordExternalAudit(h.db, nil, models.AuditActorSystem, "trash purge", kind.action+".purge", kind.entity, id, nil, nil)
+		}
+		if len(ids) > 0 {
+			log.Printf("🗑️  Purged %d %s from trash", len(ids), kind.name)
+		}
+	}
+}
+
+// list returns the deleted items of a kind, most recently deleted first
+func (h *TrashHandler) list(c *fiber.Ctx, kind trashKind) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := h.db.Unscoped().Model(kind.model).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	query.Count(&total)
+
+	items := kind.newList()
+	if err := query.Order("deleted_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(items).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch trash",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"items":          items,
+			"retention_days": int(h.cfg.TrashRetention.Hours() / 24),
+			"total_count":    total,
+			"page":           page,
+			"page_size":      pageSize,
+			"total_pages":    int((total + int64(pageSize) - 1) / int64(pageSize)),
+		},
+	})
+}
+
+// restore clears the deleted_at timestamp of an item in the trash
+func (h *TrashHandler) restore(c *fiber.Ctx, kind trashKind) error {
+	result := h.db.Unscoped().Model(kind.model).
+		Where("id = ? AND deleted_at IS NOT NULL", c.Params("id")).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to restore item",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Item not found in trash",
+		})
+	}
+
+	// Restored media may be featured again
+	invalidateFeatured()
+	recordAudit(h.db, c, kind.action+".restore", kind.entity, c.Params("id"), nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Item restored",
+	})
+}
+
+// purge permanently deletes an item in the trash before its retention period ends
+func (h *TrashHandler) purge(c *fiber.Ctx, kind trashKind) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid ID",
+		})
+	}
+
+	var count int64
+	h.db.Unscoped().Model(kind.model).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count)
+	if count == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Item not found in trash",
+		})
+	}
+
+	if err := h.purgeItem(kind, uint(id)); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to delete item",
+		})
+	}
+
+	recordAudit(h.db, c, kind.action+".purge", kind.entity, id, nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Item permanently deleted",
+	})
+}
+
+// purgeItem permanently deletes a soft-deleted row and anything that depends on
+// it. Files are only removed once the rows are gone.
+func (h *TrashHandler) purgeItem(kind trashKind, id uint) error {
+	var files []string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if kind.purge != nil {
+			var err error
+			if files, err = kind.purge(tx, id); err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(kind.model).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️  Warning: Failed to delete file %s: %v", file, err)
+		}
+	}
+	if kind.stored != nil {
+		for _, prefix := range kind.stored(id) {
+			if err := h.store.DeletePrefix(prefix); err != nil {
+				log.Printf("⚠️  Warning: Failed to delete stored files %s: %v", prefix, err)
+			}
+		}
+	}
+	return nil
+}
+
+// purgeMediaRecords returns the media item's file, wherever it was uploaded
+// to, and its generated video files
+func purgeMediaRecords(tx *gorm.DB, id uint) ([]string, error) {
+	var media models.Media
+	if err := tx.Unscoped().First(&media, id).Error; err != nil {
+		return nil, err
+	}
+
+	if media.FileName == "" {
+		return nil, nil
+	}
+	files := []string{mediaFilePath(&media), legacyUploadPath(media.FileName)}
+	return append(files, mediaDerivedFiles(&media)...), nil
+}
+
+// purgeBookingRecords deletes a booking's contracts, detaches quotes that were
+// accepted into it and returns the signed contract PDFs
+func purgeBookingRecords(tx *gorm.DB, id uint) ([]string, error) {
+	var contracts []models.Contract
+	if err := tx.Unscoped().Where("booking_id = ?", id).Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, contract := range contracts {
+		if contract.SignedPDFPath != "" {
+			files = append(files, contract.SignedPDFPath)
+		}
+	}
+
+	if err := tx.Unscoped().Where("booking_id = ?", id).Delete(&models.Contract{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Quote{}).Where("booking_id = ?", id).Update("booking_id", nil).Error; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// challengeTokenExpiresIn is how long a user has to complete the second login step
+	challengeTokenExpiresIn = 5 * time.Minute
+
+	// totpIssuer is shown as the account name in authenticator apps
+	totpIssuer = "Photography Portfolio"
+)
+
+// challengeResponse answers a password login that still needs a two-factor step
+func (h *AuthHandler) challengeResponse(c *fiber.Ctx, user *models.User, purpose, message string) error {
+	token, err := utils.GenerateChallengeToken(user.ID, purpose, h.keys, challengeTokenExpiresIn)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": message,
+		"data": fiber.Map{
+			"two_factor_required":       purpose == utils.ChallengeTwoFactorVerify,
+			"two_factor_setup_required": purpose == utils.ChallengeTwoFactorEnroll,
+			"challenge_token":           token,
+			"expires_in":                int(challengeTokenExpiresIn.Seconds()),
+		},
+	})
+}
+
+// VerifyTwoFactor completes a login with a TOTP code or a recovery code
+func (h *AuthHandler) VerifyTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	claims, err := utils.ParseChallengeToken(req.ChallengeToken, utils.ChallengeTwoFactorVerify, h.keys)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Login challenge is invalid or has expired. Please log in again.",
+		})
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ? AND is_active = ?", claims.UserID, true).First(&user).Error; err != nil || !user.TOTPEnabled {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Login challenge is invalid or has expired. Please log in again.",
+		})
+	}
+
+	if user.IsLocked() {
+		h.recordLoginAttempt(c, user.Email, &user.ID, false, models.LoginFailureAccountLocked)
+		return accountLockedError(c)
+	}
+
+	verified := false
+	usedRecoveryCode := false
+	if req.Code != "" {
+		verified = h.verifyTOTP(&user, req.Code)
+	} else if req.RecoveryCode != "" {
+		verified = h.useRecoveryCode(&user, req.RecoveryCode)
+		usedRecoveryCode = verified
+	}
+
+	if !verified {
+		if h.registerFailedLogin(c, &user, models.LoginFailureBadTwoFactor) {
+			return accountLockedError(c)
+		}
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid authentication code",
+		})
+	}
+
+	user.UpdateLastLogin(h.db)
+
+	tokens, err := h.createSession(c, &user)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate token",
+		})
+	}
+
+	if usedRecoveryCode {
+		var remaining int64
+		h.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+		tokens["recovery_codes_remaining"] = remaining
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Login successful",
+		"data":    tokens,
+	})
+}
+
+// SetupTwoFactor generates a new TOTP secret and provisioning URI for enrollment.
+// It accepts either an authenticated session or an enrollment challenge token.
+func (h *AuthHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+	}
+	c.BodyParser(&req)
+
+	user, err := h.enrollingUser(c, req.ChallengeToken)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Authentication required",
+		})
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Two-factor authentication is already enabled",
+		})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate secret",
+		})
+	}
+
+	if err := h.db.Model(user).Update("totp_secret", secret).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to save secret",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Scan the QR code with your authenticator app, then confirm with a code",
+		"data": fiber.Map{
+			"secret":           secret,
+			"provisioning_uri": utils.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+		},
+	})
+}
+
+// EnableTwoFactor confirms enrollment with a TOTP code and returns recovery codes.
+// During enforced enrollment it also completes the login.
+func (h *AuthHandler) EnableTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	user, err := h.enrollingUser(c, req.ChallengeToken)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Authentication required",
+		})
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Two-factor authentication is already enabled",
+		})
+	}
+
+	if user.TOTPSecret == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Start two-factor setup first",
+		})
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, time.Now())
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid authentication code",
+		})
+	}
+
+	now := time.Now()
+	var recoveryCodes []string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":        true,
+			"totp_enabled_at":     now,
+			"totp_last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		recoveryCodes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to enable two-factor authentication",
+		})
+	}
+
+	data := fiber.Map{
+		"recovery_codes": recoveryCodes,
+	}
+
+	// Enforced enrollment happens before a session exists, so log the user in now
+	if _, hasSession := middleware.GetSessionIDFromContext(c); !hasSession {
+		user.UpdateLastLogin(h.db)
+		tokens, err := h.createSession(c, user)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"success": false,
+				"message": "Failed to generate token",
+			})
+		}
+		for key, value := range tokens {
+			data[key] = value
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Two-factor authentication enabled. Store your recovery codes somewhere safe.",
+		"data":    data,
+	})
+}
+
+// DisableTwoFactor turns off two-factor authentication after confirming the password and a code
+func (h *AuthHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorDisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if models.GetBoolSetting(h.db, models.SettingRequireTwoFactor, false) {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Two-factor authentication is required for all users",
+		})
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "User not found",
+		})
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Two-factor authentication is not enabled",
+		})
+	}
+
+	if !user.CheckPassword(req.Password) || !h.verifyTOTP(&user, req.Code) {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid password or authentication code",
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":        false,
+			"totp_enabled_at":     nil,
+			"totp_secret":         "",
+			"totp_last_used_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to disable two-factor authentication",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after confirming a TOTP code
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil || !user.TOTPEnabled {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Two-factor authentication is not enabled",
+		})
+	}
+
+	if !h.verifyTOTP(&user, req.Code) {
+		return c.Status(401).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid authentication code",
+		})
+	}
+
+	codes, err := replaceRecoveryCodes(h.db, user.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate recovery codes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// enrollingUser resolves the user for 2FA enrollment from the session or an enrollment challenge token
+func (h *AuthHandler) enrollingUser(c *fiber.Ctx, challengeToken string) (*models.User, error) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		claims, err := utils.ParseChallengeToken(challengeToken, utils.ChallengeTwoFactorEnroll, h.keys)
+		if err != nil {
+			return nil, err
+		}
+		userID = claims.UserID
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ? AND is_active = ?", userID, true).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// verifyTOTP checks a code and records its time step so it cannot be replayed
+func (h *AuthHandler) verifyTOTP(user *models.User, code string) bool {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false
+	}
+
+	result := h.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_used_step < ?", user.ID, step).
+		UpdateColumn("totp_last_used_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	user.TOTPLastUsedStep = step
+	return true
+}
+
+// useRecoveryCode consumes an unused recovery code
+func (h *AuthHandler) useRecoveryCode(user *models.User, code string) bool {
+	result := h.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// replaceRecoveryCodes deletes existing recovery codes and returns a new set in plain text
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, models.RecoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, models.RecoveryCodeCount)
+	for i := 0; i < models.RecoveryCodeCount; i++ {
+		raw, err := utils.GenerateRandomToken(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode lowercases a recovery code and strips separators
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
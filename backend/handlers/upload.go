@@ -0,0 +1,579 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Resumable uploads implement the tus 1.0.0 protocol (https://tus.io) with the
+// creation, checksum, termination and expiration extensions.
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,checksum,termination,expiration"
+	tusChecksums  = "sha1,sha256"
+	uploadTempDir = "uploads/tmp"
+
+	statusChecksumMismatch = 460
+)
+
+// uploadError is returned from inside upload transactions to choose the response status
+type uploadError struct {
+	status  int
+	message string
+}
+
+func (e *uploadError) Error() string {
+	return e.message
+}
+
+// UploadOptions describes the server's tus support
+func (h *MediaHandler) UploadOptions(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	c.Set("Tus-Checksum-Algorithm", tusChecksums)
+	c.Set("Tus-Max-Size", strconv.FormatInt(h.cfg.MaxUploadSize, 10))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateUpload starts a resumable upload. Upload-Metadata must include the
+// filename and category, and may include title, description, tags (comma
+// separated), album_id, is_featured and sha256 (hex digest of the whole file,
+// verified once the upload completes).
+func (h *MediaHandler) CreateUpload(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Upload-Length must be a positive number of bytes",
+		})
+	}
+	if length > h.cfg.MaxUploadSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("Uploads are limited to %d bytes", h.cfg.MaxUploadSize),
+		})
+	}
+
+	metadata, err := parseUploadMetadata(c.Get("Upload-Metadata"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid Upload-Metadata header",
+		})
+	}
+
+	fileName := metadata["filename"]
+	fileExt := strings.ToLower(filepath.Ext(fileName))
+	if fileName == "" || !utils.IsAllowedMediaExtension(h.cfg, fileExt) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": h.invalidFileTypeMessage(),
+		})
+	}
+	if limit := utils.MaxMediaFileSize(h.cfg, fileExt); length > limit {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("%s files are limited to %d MB", fileExt, limit/1024/1024),
+		})
+	}
+	if !models.ValidateCategory(metadata["category"]) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid category. Must be one of: athletes, food, nature, portraits, action",
+		})
+	}
+	if _, err := h.findAlbumID(metadata["album_id"]); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Album not found",
+		})
+	}
+	if sum := metadata["sha256"]; sum != "" {
+		if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": "sha256 metadata must be a hex encoded SHA-256 digest",
+			})
+		}
+	}
+
+	if err := os.MkdirAll(uploadTempDir, 0755); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create upload directory",
+		})
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	upload := models.Upload{
+		ID:        uuid.New().String(),
+		Length:    length,
+		FileName:  filepath.Base(fileName),
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(h.cfg.UploadExpiresIn),
+		UserID:    userID,
+	}
+
+	file, err := os.Create(uploadTempPath(&upload))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create upload",
+		})
+	}
+	file.Close()
+
+	if err := h.db.Create(&upload).Error; err != nil {
+		os.Remove(uploadTempPath(&upload))
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create upload",
+		})
+	}
+
+	c.Set("Location", fmt.Sprintf("%s/api/media/uploads/%s", c.BaseURL(), upload.ID))
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// GetUploadOffset reports how many bytes of an upload have been received so a
+// client can resume after a dropped connection
+func (h *MediaHandler) GetUploadOffset(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	c.Set("Cache-Control", "no-store")
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	upload, err := h.findUpload(c)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(upload.BytesReceived, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.CompletedAt == nil {
+		c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// GetUpload returns the state of an upload and the media item once it has completed
+func (h *MediaHandler) GetUpload(c *fiber.Ctx) error {
+	upload, err := h.findUpload(c)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Upload not found",
+		})
+	}
+
+	data := fiber.Map{
+		"upload": upload,
+	}
+	if upload.MediaID != nil {
+		var media models.Media
+		if err := h.db.First(&media, *upload.MediaID).Error; err == nil {
+			data["media"] = media
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// PatchUpload appends a chunk at the current offset. The chunk is verified
+// against the Upload-Checksum header when one is sent. The media item is
+// created when the last chunk arrives.
+func (h *MediaHandler) PatchUpload(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	if c.Get(fiber.HeaderContentType) != "application/offset+octet-stream" {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"success": false,
+			"message": "Content-Type must be application/offset+octet-stream",
+		})
+	}
+
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid Upload-Offset header",
+		})
+	}
+
+	chunk := c.Body()
+	if header := c.Get("Upload-Checksum"); header != "" {
+		if err := verifyChunkChecksum(header, chunk); err != nil {
+			return uploadErrorResponse(c, err)
+		}
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	var upload models.Upload
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the upload so concurrent chunks cannot interleave
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", c.Params("id"), userID).
+			First(&upload).Error; err != nil {
+			return &uploadError{fiber.StatusNotFound, "Upload not found"}
+		}
+		if upload.CompletedAt != nil || upload.Completing {
+			return &uploadError{fiber.StatusConflict, "Upload is already complete"}
+		}
+		if upload.IsExpired() {
+			return &uploadError{fiber.StatusGone, "Upload has expired"}
+		}
+		if offset != upload.BytesReceived {
+			return &uploadError{fiber.StatusConflict, "Upload-Offset does not match the received bytes"}
+		}
+		if offset+int64(len(chunk)) > upload.Length {
+			return &uploadError{fiber.StatusRequestEntityTooLarge, "Chunk exceeds Upload-Length"}
+		}
+
+		if err := writeUploadChunk(&upload, offset, chunk); err != nil {
+			return err
+		}
+
+		// The request that receives the last byte completes the upload, later
+		// requests are turned away while it does
+		upload.BytesReceived = offset + int64(len(chunk))
+		upload.Completing = upload.IsComplete()
+		return tx.Model(&upload).Updates(map[string]interface{}{
+			"bytes_received": upload.BytesReceived,
+			"completing":     upload.Completing,
+		}).Error
+	})
+	if err != nil {
+		return uploadErrorResponse(c, err)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(upload.BytesReceived, 10))
+	if !upload.IsComplete() {
+		c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	media, err := h.completeUpload(c, &upload)
+	if err != nil {
+		// Let the client retry completing an upload that was not removed
+		h.db.Model(&models.Upload{}).Where("id = ? AND completed_at IS NULL", upload.ID).Update("completing", false)
+		return uploadErrorResponse(c, err)
+	}
+
+	c.Set("X-Media-ID", strconv.FormatUint(uint64(media.ID), 10))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteUpload cancels an upload and removes the partial file
+func (h *MediaHandler) DeleteUpload(c *fiber.Ctx) error {
+	setTusHeaders(c)
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	upload, err := h.findUpload(c)
+	if err != nil || upload.CompletedAt != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if upload.Completing {
+		return c.SendStatus(fiber.StatusConflict)
+	}
+
+	if err := h.removeUpload(upload); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to delete upload",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// StartUploadCleanup periodically removes expired partial uploads
+func (h *MediaHandler) StartUploadCleanup(checkEvery time.Duration) {
+	go func() {
+		ticker := time.NewTicker(checkEvery)
+		defer ticker.Stop()
+		for range ticker.C {
+			h.CleanupUploads()
+		}
+	}()
+}
+
+// CleanupUploads removes partial files of incomplete uploads that have expired
+// and forgets completed uploads and batches after the same period
+func (h *MediaHandler) CleanupUploads() {
+	if err := h.db.Where("expires_at < ?", time.Now()).Delete(&models.UploadBatch{}).Error; err != nil {
+		log.Printf("⚠️  Warning: Failed to remove expired upload batches: %v", err)
+	}
+
+	var uploads []models.Upload
+	if err := h.db.Where("expires_at < ?", time.Now()).Find(&uploads).Error; err != nil {
+		log.Printf("⚠️  Warning: Failed to find expired uploads: %v", err)
+		return
+	}
+
+	for i := range uploads {
+		if err := h.removeUpload(&uploads[i]); err != nil {
+			log.Printf("⚠️  Warning: Failed to remove upload %s: %v", uploads[i].ID, err)
+		}
+	}
+	if len(uploads) > 0 {
+		log.Printf("🧹 Removed %d expired uploads", len(uploads))
+	}
+}
+
+// completeUpload verifies the assembled file, moves it into the media
+// directory and creates its media record
+func (h *MediaHandler) completeUpload(c *fiber.Ctx, upload *models.Upload) (*models.Media, error) {
+	tempPath := uploadTempPath(upload)
+
+	if expected := upload.Metadata["sha256"]; expected != "" {
+		actual, err := utils.FileSHA256(tempPath)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(actual, expected) {
+			// The received bytes are wrong somewhere, so the upload cannot be resumed
+			h.removeUpload(upload)
+			return nil, &uploadError{statusChecksumMismatch, "File does not match the sha256 checksum, please upload it again"}
+		}
+	}
+
+	// Check the assembled content matches the claimed type
+	fileExt := strings.ToLower(filepath.Ext(upload.FileName))
+	info, err := utils.ValidateMediaFile(h.cfg, tempPath, fileExt)
+	if err != nil {
+		h.removeUpload(upload)
+		return nil, &uploadError{mediaFileErrorStatus(err), err.Error()}
+	}
+
+	duplicate, reject := h.checkDuplicate(info, upload.Metadata["allow_duplicate"] == "true")
+	if reject {
+		h.removeUpload(upload)
+		return nil, &uploadError{fiber.StatusConflict, duplicateMessage(duplicate)}
+	}
+	if duplicate != nil {
+		c.Set("X-Duplicate-Of", strconv.FormatUint(uint64(duplicate.ID), 10))
+	}
+
+	if err := os.MkdirAll("uploads/media", 0755); err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("%s%s", uuid.New().String(), fileExt)
+	if err := os.Rename(tempPath, filepath.Join("uploads/media", filename)); err != nil {
+		return nil, err
+	}
+
+	title := upload.Metadata["title"]
+	if title == "" {
+		title = strings.TrimSuffix(upload.FileName, filepath.Ext(upload.FileName))
+	}
+	isFeatured, _ := strconv.ParseBool(upload.Metadata["is_featured"])
+	albumID, _ := h.findAlbumID(upload.Metadata["album_id"])
+
+	media, err := h.createMedia(c, storedMedia{
+		Title:       title,
+		Description: upload.Metadata["description"],
+		Category:    upload.Metadata["category"],
+		Tags:        parseTagList(upload.Metadata["tags"]),
+		AlbumID:     albumID,
+		IsFeatured:  isFeatured,
+		FileName:    filename,
+		FileSize:    upload.Length,
+		Info:        info,
+		UserID:      upload.UserID,
+	})
+	if err != nil {
+		// createMedia removed the file, so the upload cannot be completed again
+		h.db.Delete(upload)
+		return nil, err
+	}
+
+	now := time.Now()
+	upload.MediaID = &media.ID
+	upload.CompletedAt = &now
+	// Completed uploads are kept so clients can look up the media item
+	upload.ExpiresAt = now.Add(h.cfg.UploadExpiresIn)
+	if err := h.db.Model(upload).Updates(map[string]interface{}{
+		"media_id":     media.ID,
+		"completed_at": now,
+		"expires_at":   upload.ExpiresAt,
+	}).Error; err != nil {
+		log.Printf("Failed to mark upload %s as complete: %v", upload.ID, err)
+	}
+
+	return media, nil
+}
+
+// findUpload loads one of the current user's uploads
+func (h *MediaHandler) findUpload(c *fiber.Ctx) (*models.Upload, error) {
+	userID, _ := middleware.GetUserIDFromContext(c)
+
+	var upload models.Upload
+	if err := h.db.Where("id = ? AND user_id = ?", c.Params("id"), userID).First(&upload).Error; err != nil {
+		return nil, err
+	}
+	if upload.IsExpired() {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &upload, nil
+}
+
+// removeUpload deletes an upload and its partial file
+func (h *MediaHandler) removeUpload(upload *models.Upload) error {
+	if err := os.Remove(uploadTempPath(upload)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return h.db.Delete(upload).Error
+}
+
+// setTusHeaders sets the headers included in every tus response
+func setTusHeaders(c *fiber.Ctx) {
+	c.Set("Tus-Resumable", tusVersion)
+}
+
+// checkTusResumable rejects clients speaking an unsupported tus version
+func checkTusResumable(c *fiber.Ctx) error {
+	if c.Get("Tus-Resumable") == tusVersion {
+		return nil
+	}
+
+	c.Set("Tus-Version", tusVersion)
+	return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+		"success": false,
+		"message": "Unsupported Tus-Resumable version, expected " + tusVersion,
+	})
+}
+
+// uploadErrorResponse writes the response for an error from an upload request
+func uploadErrorResponse(c *fiber.Ctx, err error) error {
+	var uploadErr *uploadError
+	if errors.As(err, &uploadErr) {
+		return c.Status(uploadErr.status).JSON(fiber.Map{
+			"success": false,
+			"message": uploadErr.message,
+		})
+	}
+
+	log.Printf("Upload failed: %v", err)
+	return c.Status(500).JSON(fiber.Map{
+		"success": false,
+		"message": "Failed to process upload",
+	})
+}
+
+// parseUploadMetadata decodes the Upload-Metadata header, a comma separated
+// list of keys and base64 encoded values
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, errors.New("invalid metadata pair")
+		}
+
+		value := ""
+		if len(parts) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			value = string(decoded)
+		}
+		metadata[parts[0]] = value
+	}
+	return metadata, nil
+}
+
+// verifyChunkChecksum checks a chunk against an Upload-Checksum header such as "sha1 <base64 digest>"
+func verifyChunkChecksum(header string, chunk []byte) error {
+	parts := strings.Fields(header)
+	if len(parts) != 2 {
+		return &uploadError{400, "Invalid Upload-Checksum header"}
+	}
+
+	var hasher hash.Hash
+	switch parts[0] {
+	case "sha1":
+		hasher = sha1.New()
+	case "sha256":
+		hasher = sha256.New()
+	default:
+		return &uploadError{400, "Unsupported checksum algorithm, use one of: " + tusChecksums}
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return &uploadError{400, "Invalid Upload-Checksum header"}
+	}
+
+	hasher.Write(chunk)
+	if !bytes.Equal(hasher.Sum(nil), expected) {
+		return &uploadError{statusChecksumMismatch, "Checksum mismatch"}
+	}
+	return nil
+}
+
+// writeUploadChunk writes a chunk at the offset, dropping anything a failed
+// earlier request left past it
+func writeUploadChunk(upload *models.Upload, offset int64, chunk []byte) error {
+	file, err := os.OpenFile(uploadTempPath(upload), os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(chunk, offset); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// uploadTempPath returns the path of an upload's partial file
+func uploadTempPath(upload *models.Upload) string {
+	return filepath.Join(uploadTempDir, upload.ID)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseUploadMetadata(t *testing.T) {
+	encode := func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty header", "", map[string]string{}, false},
+		{"blank header", "   ", map[string]string{}, false},
+		{"one pair", "filename " + encode("photo.jpg"), map[string]string{"filename": "photo.jpg"}, false},
+		{
+			"several pairs",
+			"filename " + encode("photo.jpg") + ",category " + encode("nature") + ", tags " + encode("a, b"),
+			map[string]string{"filename": "photo.jpg", "category": "nature", "tags": "a, b"},
+			false,
+		},
+		{"key without value", "is_featured", map[string]string{"is_featured": ""}, false},
+		{"empty value", "title ", map[string]string{"title": ""}, false},
+
+		{"invalid base64", "filename not-base64!", nil, true},
+		{"too many fields", "filename " + encode("a") + " " + encode("b"), nil, true},
+		{"empty pair", "filename " + encode("a") + ",,category " + encode("nature"), nil, true},
+		{"trailing comma", "filename " + encode("a") + ",", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUploadMetadata(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUploadMetadata(%q) error = %v, want error %v", tt.header, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseUploadMetadata(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyChunkChecksum(t *testing.T) {
+	chunk := []byte("chunk")
+	sha1Sum := sha1.Sum(chunk)
+	sha256Sum := sha256.Sum256(chunk)
+	emptySum := sha256.Sum256(nil)
+	otherSum := sha256.Sum256([]byte("other chunk"))
+
+	tests := []struct {
+		name       string
+		header     string
+		chunk      []byte
+		wantStatus int // 0 when the checksum matches
+	}{
+		{"sha1", "sha1 " + base64.StdEncoding.EncodeToString(sha1Sum[:]), chunk, 0},
+		{"sha256", "sha256 " + base64.StdEncoding.EncodeToString(sha256Sum[:]), chunk, 0},
+		{"empty chunk", "sha256 " + base64.StdEncoding.EncodeToString(emptySum[:]), nil, 0},
+
+		{"mismatch", "sha256 " + base64.StdEncoding.EncodeToString(otherSum[:]), chunk, statusChecksumMismatch},
+		{"digest of another algorithm", "sha256 " + base64.StdEncoding.EncodeToString(sha1Sum[:]), chunk, statusChecksumMismatch},
+		{"changed chunk", "sha1 " + base64.StdEncoding.EncodeToString(sha1Sum[:]), []byte("chunk!"), statusChecksumMismatch},
+
+		{"unsupported algorithm", "md5 " + base64.StdEncoding.EncodeToString(sha1Sum[:]), chunk, 400},
+		{"algorithm case", "SHA1 " + base64.StdEncoding.EncodeToString(sha1Sum[:]), chunk, 400},
+		{"missing digest", "sha1", chunk, 400},
+		{"extra field", "sha1 " + base64.StdEncoding.EncodeToString(sha1Sum[:]) + " x", chunk, 400},
+		{"invalid base64", "sha1 not-base64!", chunk, 400},
+		{"empty header", "", chunk, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChunkChecksum(tt.header, tt.chunk)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("verifyChunkChecksum(%q) = %v, want nil", tt.header, err)
+				}
+				return
+			}
+
+			var uploadErr *uploadError
+			if !errors.As(err, &uploadErr) {
+				t.Fatalf("verifyChunkChecksum(%q) = %v, want an upload error", tt.header, err)
+			}
+			if uploadErr.status != tt.wantStatus {
+				t.Errorf("verifyChunkChecksum(%q) status = %d, want %d", tt.header, uploadErr.status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,593 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"photography-portfolio/config"
+	"photography-portfolio/middleware"
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// errInvitationNotPending is returned when an invitation was accepted or
+// expired while it was being accepted
+var errInvitationNotPending = errors.New("invitation is no longer pending")
+
+type UserHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewUserHandler(db *gorm.DB, cfg *config.Config) *UserHandler {
+	return &UserHandler{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// GetUsers returns all users and pending invitations (owner only)
+func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
+	var users []models.User
+	if err := h.db.Order("created_at ASC").Find(&users).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch users",
+		})
+	}
+
+	var invitations []models.UserInvitation
+	h.db.Where("accepted_at IS NULL AND expires_at > ?", time.Now()).
+		Order("created_at DESC").
+		Find(&invitations)
+
+	responses := make([]models.UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, user.ToResponse())
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"users":       responses,
+			"invitations": invitations,
+			"roles":       models.GetValidRoles(),
+		},
+	})
+}
+
+// InviteUser creates an invitation and emails the sign-up link (owner only)
+func (h *UserHandler) InviteUser(c *fiber.Ctx) error {
+	var req models.InviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Email == "" || !strings.Contains(req.Email, "@") {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "A valid email is required",
+		})
+	}
+
+	if !models.ValidateRole(string(req.Role)) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid role",
+		})
+	}
+
+	var existing int64
+	h.db.Model(&models.User{}).Where("LOWER(email) = ?", req.Email).Count(&existing)
+	if existing > 0 {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "A user with this email already exists",
+		})
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to generate invitation token",
+		})
+	}
+
+	inviterID, _ := middleware.GetUserIDFromContext(c)
+	invitation := models.UserInvitation{
+		Email:       req.Email,
+		FirstName:   strings.TrimSpace(req.FirstName),
+		LastName:    strings.TrimSpace(req.LastName),
+		Role:        req.Role,
+		TokenHash:   utils.HashToken(token),
+		ExpiresAt:   time.Now().Add(models.InvitationValidity),
+		InvitedByID: inviterID,
+	}
+
+	if err := h.db.Create(&invitation).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create invitation",
+		})
+	}
+
+	recordAudit(h.db, c, "invitation.create", models.AuditEntityInvitation, invitation.ID, nil, invitation)
+
+	url := fmt.Sprintf("%s/invite/%s", h.cfg.CorsOrigin, token)
+	body := fmt.Sprintf(
+		"You have been invited to the portfolio admin as %s.\n\nSet your password here: %s\n\nThis link expires on %s.\n",
+		invitation.Role, url, invitation.ExpiresAt.Format("January 2, 2006"),
+	)
+	if err := utils.SendEmail(h.cfg, invitation.Email, "You're invited to the portfolio admin", body); err != nil {
+		log.Printf("Failed to email invitation %d: %v", invitation.ID, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Invitation sent successfully",
+		"data": fiber.Map{
+			"invitation": invitation,
+			"url":        url,
+		},
+	})
+}
+
+// AcceptInvitation creates the user account for a pending invitation
+func (h *UserHandler) AcceptInvitation(c *fiber.Ctx) error {
+	var req models.AcceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if err := utils.ValidatePassword(h.cfg, req.Password); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+			"policy":  utils.PasswordPolicy(h.cfg),
+		})
+	}
+
+	var invitation models.UserInvitation
+	if err := h.db.Where("token_hash = ?", utils.HashToken(c.Params("token"))).First(&invitation).Error; err != nil || !invitation.IsPending() {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Invitation not found or expired",
+		})
+	}
+
+	user := models.User{
+		Email:     invitation.Email,
+		Password:  req.Password, // Hashed by the BeforeCreate hook
+		FirstName: invitation.FirstName,
+		LastName:  invitation.LastName,
+		Role:      invitation.Role,
+		IsActive:  true,
+	}
+	if name := strings.TrimSpace(req.FirstName); name != "" {
+		user.FirstName = name
+	}
+	if name := strings.TrimSpace(req.LastName); name != "" {
+		user.LastName = name
+	}
+
+	// Claim the invitation first, so concurrent requests with the same token
+	// cannot create more than one account
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.UserInvitation{}).
+			Where("id = ? AND accepted_at IS NULL AND expires_at > ?", invitation.ID, now).
+			Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errInvitationNotPending
+		}
+		return tx.Create(&user).Error
+	})
+	if err == errInvitationNotPending {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Invitation not found or expired",
+		})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to create account",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Account created successfully. You can now log in.",
+		"data":    user.ToResponse(),
+	})
+}
+
+// RevokeInvitation deletes a pending invitation (owner only)
+func (h *UserHandler) RevokeInvitation(c *fiber.Ctx) error {
+	result := h.db.Where("id = ? AND accepted_at IS NULL", c.Params("id")).Delete(&models.UserInvitation{})
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to revoke invitation",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Invitation not found",
+		})
+	}
+
+	recordAudit(h.db, c, "invitation.revoke", models.AuditEntityInvitation, c.Params("id"), nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Invitation revoked",
+	})
+}
+
+// ChangeRole changes a user's role (owner only)
+func (h *UserHandler) ChangeRole(c *fiber.Ctx) error {
+	var req struct {
+		Role models.Role `json:"role"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if !models.ValidateRole(string(req.Role)) {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid role",
+		})
+	}
+
+	user, err := h.findUser(c.Params("id"))
+	if err != nil {
+		return userLookupError(c, err)
+	}
+
+	if user.IsAdmin() && req.Role != models.RoleOwner && h.isLastOwner(user) {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Cannot change the role of the last active owner",
+		})
+	}
+
+	before := fiber.Map{"role": user.Role}
+	user.Role = req.Role
+	if err := h.db.Model(user).Update("role", req.Role).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update role",
+		})
+	}
+
+	recordAudit(h.db, c, "user.role_change", models.AuditEntityUser, user.ID, before, fiber.Map{"role": user.Role})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Role updated successfully",
+		"data":    user.ToResponse(),
+	})
+}
+
+// DeactivateUser disables a user's account (owner only)
+func (h *UserHandler) DeactivateUser(c *fiber.Ctx) error {
+	user, err := h.findUser(c.Params("id"))
+	if err != nil {
+		return userLookupError(c, err)
+	}
+
+	if currentID, _ := middleware.GetUserIDFromContext(c); currentID == user.ID {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "You cannot deactivate your own account",
+		})
+	}
+
+	if user.IsAdmin() && h.isLastOwner(user) {
+		return c.Status(409).JSON(fiber.Map{
+			"success": false,
+			"message": "Cannot deactivate the last active owner",
+		})
+	}
+
+	// Deactivated users are logged out everywhere
+	h.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Update("revoked_at", time.Now())
+
+	return h.setActive(c, user, false)
+}
+
+// ActivateUser re-enables a deactivated user's account (owner only)
+func (h *UserHandler) ActivateUser(c *fiber.Ctx) error {
+	user, err := h.findUser(c.Params("id"))
+	if err != nil {
+		return userLookupError(c, err)
+	}
+
+	return h.setActive(c, user, true)
+}
+
+// UnlockUser clears a temporary lockout after failed logins (owner only)
+func (h *UserHandler) UnlockUser(c *fiber.Ctx) error {
+	user, err := h.findUser(c.Params("id"))
+	if err != nil {
+		return userLookupError(c, err)
+	}
+
+	if err := h.db.Model(user).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to unlock user",
+		})
+	}
+	user.LockedUntil = nil
+
+	recordAudit(h.db, c, "user.unlock", models.AuditEntityUser, user.ID, nil, nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "User unlocked successfully",
+		"data":    user.ToResponse(),
+	})
+}
+
+// GetLoginAttempts returns the login attempt audit trail (owner only)
+func (h *UserHandler) GetLoginAttempts(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "50"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	query := h.db.Model(&models.LoginAttempt{})
+	if email := c.Query("email"); email != "" {
+		query = query.Where("email = ?", strings.ToLower(strings.TrimSpace(email)))
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if ip := c.Query("ip_address"); ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+	if success := c.Query("success"); success != "" {
+		query = query.Where("success = ?", success == "true")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var attempts []models.LoginAttempt
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&attempts).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch login attempts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"attempts":    attempts,
+			"total_count": total,
+			"page":        page,
+			"page_size":   pageSize,
+			"total_pages": int((total + int64(pageSize) - 1) / int64(pageSize)),
+		},
+	})
+}
+
+// GetActiveSessions returns the active sessions of all users (owner only)
+func (h *UserHandler) GetActiveSessions(c *fiber.Ctx) error {
+	currentID, _ := middleware.GetSessionIDFromContext(c)
+
+	query := h.db.Preload("User").Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var sessions []models.Session
+	if err := query.Order("last_used_at DESC").Find(&sessions).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to fetch sessions",
+		})
+	}
+
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, session.ToResponse(currentID))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    responses,
+	})
+}
+
+// RevokeUserSession revokes any user's session (owner only)
+func (h *UserHandler) RevokeUserSession(c *fiber.Ctx) error {
+	result := h.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", c.Params("id")).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to revoke session",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "Session not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Session revoked",
+	})
+}
+
+// GetSecuritySettings returns the account security settings (owner only)
+func (h *UserHandler) GetSecuritySettings(c *fiber.Ctx) error {
+	var withoutTwoFactor int64
+	h.db.Model(&models.User{}).Where("is_active = ? AND totp_enabled = ?", true, false).Count(&withoutTwoFactor)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"require_two_factor":       models.GetBoolSetting(h.db, models.SettingRequireTwoFactor, false),
+			"users_without_two_factor": withoutTwoFactor,
+		},
+	})
+}
+
+// UpdateSecuritySettings changes the account security settings (owner only).
+// Enforcing two-factor authentication logs out every user who has not enrolled yet.
+func (h *UserHandler) UpdateSecuritySettings(c *fiber.Ctx) error {
+	var req struct {
+		RequireTwoFactor bool `json:"require_two_factor"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if req.RequireTwoFactor {
+		currentID, _ := middleware.GetUserIDFromContext(c)
+		var current models.User
+		if err := h.db.First(&current, currentID).Error; err != nil || !current.TOTPEnabled {
+			return c.Status(409).JSON(fiber.Map{
+				"success": false,
+				"message": "Enable two-factor authentication on your own account first",
+			})
+		}
+	}
+
+	value := "false"
+	if req.RequireTwoFactor {
+		value = "true"
+	}
+	previous := models.GetSetting(h.db, models.SettingRequireTwoFactor, "false")
+	if err := models.SetSetting(h.db, models.SettingRequireTwoFactor, value); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update settings",
+		})
+	}
+
+	recordAudit(h.db, c, "setting.update", models.AuditEntitySetting, models.SettingRequireTwoFactor,
+		fiber.Map{"value": previous}, fiber.Map{"value": value})
+
+	if req.RequireTwoFactor {
+		result := h.db.Model(&models.Session{}).
+			Where("revoked_at IS NULL AND user_id IN (?)",
+				h.db.Model(&models.User{}).Select("id").Where("totp_enabled = ?", false)).
+			Update("revoked_at", time.Now())
+		if result.RowsAffected > 0 {
+			log.Printf("🔐 Two-factor authentication enforced, revoked %d sessions without 2FA", result.RowsAffected)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Security settings updated",
+		"data": fiber.Map{
+			"require_two_factor": req.RequireTwoFactor,
+		},
+	})
+}
+
+// setActive updates the user's active flag and writes the response
+func (h *UserHandler) setActive(c *fiber.Ctx, user *models.User, active bool) error {
+	user.IsActive = active
+	if err := h.db.Model(user).Update("is_active", active).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update user",
+		})
+	}
+
+	action := "user.activate"
+	message := "User activated successfully"
+	if !active {
+		action = "user.deactivate"
+		message = "User deactivated successfully"
+	}
+	recordAudit(h.db, c, action, models.AuditEntityUser, user.ID, fiber.Map{"is_active": !active}, fiber.Map{"is_active": active})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": message,
+		"data":    user.ToResponse(),
+	})
+}
+
+// findUser loads a user by ID
+func (h *UserHandler) findUser(id string) (*models.User, error) {
+	var user models.User
+	if err := h.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// isLastOwner checks if the user is the only remaining active owner
+func (h *UserHandler) isLastOwner(user *models.User) bool {
+	var count int64
+	h.db.Model(&models.User{}).
+		Where("role IN ? AND is_active = ? AND id <> ?", []models.Role{models.RoleOwner, models.RoleLegacyAdmin}, true, user.ID).
+		Count(&count)
+	return count == 0
+}
+
+// userLookupError converts a user lookup error into a JSON response
+func userLookupError(c *fiber.Ctx, err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(404).JSON(fiber.Map{
+			"success": false,
+			"message": "User not found",
+		})
+	}
+	return c.Status(500).JSON(fiber.Map{
+		"success": false,
+		"message": "Database error",
+	})
+}
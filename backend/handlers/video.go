@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+)
+
+// maxPosterOffset is the latest point a poster frame is taken from, so it
+// shows the opening shot rather than a fade in
+const maxPosterOffset = 3.0
+
+var (
+	// videoProcessingSlots limits how many videos are probed at once
+	videoProcessingSlots = make(chan struct{}, 2)
+	// hlsTranscodeSlots limits how many videos are transcoded at once, so a long
+	// showreel does not delay posters for new uploads
+	hlsTranscodeSlots = make(chan struct{}, 1)
+)
+
+// processVideo probes a video, creates its poster frame and preview loop and
+// then transcodes it for HLS streaming. Without ffmpeg the video keeps its
+// upload metadata and is served whole.
+func (h *MediaHandler) processVideo(mediaID uint) {
+	if !utils.FFmpegAvailable(h.cfg) {
+		return
+	}
+
+	var media models.Media
+	if err := h.db.First(&media, mediaID).Error; err != nil || !media.IsVideo() {
+		return
+	}
+
+	if media.VideoCodec == "" {
+		videoProcessingSlots <- struct{}{}
+		err := h.probeVideo(&media)
+		<-videoProcessingSlots
+		if err != nil {
+			log.Printf("Failed to probe video %d: %v", media.ID, err)
+			return
+		}
+	}
+
+	if h.cfg.VideoHLS && media.HLSURL == "" {
+		hlsTranscodeSlots <- struct{}{}
+		err := h.transcodeHLS(&media)
+		<-hlsTranscodeSlots
+		if err != nil {
+			log.Printf("Failed to transcode video %d for streaming: %v", media.ID, err)
+		}
+	}
+}
+
+// probeVideo saves a video's duration, resolution, codec and frame rate and
+// creates its poster frame and preview loop
+func (h *MediaHandler) probeVideo(media *models.Media) error {
+	src := mediaFilePath(media)
+	info, err := utils.ProbeVideo(h.cfg, src)
+	if err != nil {
+		return err
+	}
+
+	media.Duration = int(math.Round(info.Duration))
+	media.Width = info.Width
+	media.Height = info.Height
+	media.VideoCodec = info.Codec
+	media.FrameRate = info.FrameRate
+
+	base := strings.TrimSuffix(media.FileName, filepath.Ext(media.FileName))
+
+	posterName := base + "_poster.jpg"
+	posterAt := math.Min(info.Duration*0.1, maxPosterOffset)
+	if err := utils.ExtractPosterFrame(h.cfg, src, mediaDerivedPath(posterName), posterAt); err != nil {
+		log.Printf("Failed to extract poster frame for video %d: %v", media.ID, err)
+	} else {
+		media.PosterURL = fmt.Sprintf("/uploads/%s", posterName)
+		media.ThumbnailURL = media.PosterURL
+	}
+
+	// Start the preview a quarter in when the video is long enough
+	previewName := base + "_preview.mp4"
+	length := h.cfg.VideoPreviewLength.Seconds()
+	start := 0.0
+	if info.Duration > length*2 {
+		start = info.Duration * 0.25
+	}
+	if err := utils.GeneratePreviewClip(h.cfg, src, mediaDerivedPath(previewName), start, length); err != nil {
+		log.Printf("Failed to generate preview clip for video %d: %v", media.ID, err)
+	} else {
+		media.PreviewURL = fmt.Sprintf("/uploads/%s", previewName)
+	}
+
+	return h.db.Model(media).UpdateColumns(map[string]interface{}{
+		"duration":      media.Duration,
+		"width":         media.Width,
+		"height":        media.Height,
+		"video_codec":   media.VideoCodec,
+		"frame_rate":    media.FrameRate,
+		"poster_url":    media.PosterURL,
+		"preview_url":   media.PreviewURL,
+		"thumbnail_url": media.ThumbnailURL,
+	}).Error
+}
+
+// transcodeHLS encodes a video into an HLS ladder and stores the renditions
+// with a master playlist under hls/<media id>/<version>/
+func (h *MediaHandler) transcodeHLS(media *models.Media) error {
+	ladder := utils.HLSLadder(media.Width, media.Height)
+	if len(ladder) == 0 {
+		return errors.New("video dimensions are unknown")
+	}
+
+	workDir, err := os.MkdirTemp("", "hls-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	src := mediaFilePath(media)
+	for _, rendition := range ladder {
+		dir := filepath.Join(workDir, rendition.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := utils.TranscodeHLSRendition(h.cfg, src, dir, rendition); err != nil {
+			return fmt.Errorf("%s rendition: %w", rendition.Name, err)
+		}
+	}
+
+	// Renditions from an earlier transcode of the same video are replaced
+	if err := h.store.DeletePrefix(hlsStoragePrefix(media.ID)); err != nil {
+		return err
+	}
+
+	prefix := hlsStoragePrefix(media.ID) + mediaContentVersion(media) + "/"
+	err = filepath.WalkDir(workDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return h.store.Put(prefix+filepath.ToSlash(rel), file)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The master playlist is stored last so players never see a missing rendition
+	masterKey := prefix + "master.m3u8"
+	if err := h.store.Put(masterKey, strings.NewReader(utils.HLSMasterPlaylist(ladder))); err != nil {
+		return err
+	}
+
+	media.HLSURL = h.store.URL(masterKey)
+	return h.db.Model(media).UpdateColumn("hls_url", media.HLSURL).Error
+}
+
+// ProcessPendingVideos processes videos that have not been probed or
+// transcoded yet, such as videos uploaded before ffmpeg was installed
+func (h *MediaHandler) ProcessPendingVideos() {
+	if !utils.FFmpegAvailable(h.cfg) {
+		log.Printf("⚠️  ffmpeg or ffprobe not found, video posters, previews and streaming are disabled")
+		return
+	}
+
+	query := h.db.Model(&models.Media{}).Where("type = ?", models.MediaTypeVideo)
+	if h.cfg.VideoHLS {
+		query = query.Where("video_codec = '' OR video_codec IS NULL OR hls_url = '' OR hls_url IS NULL")
+	} else {
+		query = query.Where("video_codec = '' OR video_codec IS NULL")
+	}
+
+	var ids []uint
+	query.Pluck("id", &ids)
+
+	for _, id := range ids {
+		h.processVideo(id)
+	}
+	if len(ids) > 0 {
+		log.Printf("🎬 Processed %d videos", len(ids))
+	}
+}
+
+// hlsStoragePrefix is where all HLS renditions of a media item are stored
+func hlsStoragePrefix(mediaID uint) string {
+	return fmt.Sprintf("hls/%d/", mediaID)
+}
+
+// mediaContentVersion names generated files after the media content, so they
+// can be cached forever and a replaced file gets new URLs
+func mediaContentVersion(media *models.Media) string {
+	if len(media.ContentHash) >= 12 {
+		return media.ContentHash[:12]
+	}
+	return fmt.Sprintf("%x", media.CreatedAt.Unix())
+}
+
+// mediaStoragePrefixes returns the storage prefixes of files generated for a media item
+func mediaStoragePrefixes(mediaID uint) []string {
+	return []string{hlsStoragePrefix(mediaID), imageStoragePrefix(mediaID)}
+}
+
+// mediaDerivedFiles returns the poster and preview files generated for a video
+func mediaDerivedFiles(media *models.Media) []string {
+	var files []string
+	for _, url := range []string{media.PosterURL, media.PreviewURL} {
+		if url != "" {
+			files = append(files, mediaDerivedPath(filepath.Base(url)))
+		}
+	}
+	return files
+}
+
+// mediaDerivedPath returns the path of a generated file next to the media files
+func mediaDerivedPath(name string) string {
+	return filepath.Join("uploads/media", name)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"photography-portfolio/models"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// watermarkSettingsTTL is how long loaded watermark settings are reused,
+	// so changes made through another instance are picked up
+	watermarkSettingsTTL = time.Minute
+	// maxWatermarkLogoSize limits uploaded logos, which are decoded on every render
+	maxWatermarkLogoSize = 2 * 1024 * 1024
+)
+
+// watermarkCache keeps the watermark settings and decoded logos between
+// requests. Logo keys are named after their content, so logos never go stale.
+var watermarkCache struct {
+	sync.Mutex
+	settings *models.WatermarkSettings
+	loadedAt time.Time
+	logos    map[string]image.Image
+}
+
+// cachedWatermarkSettings returns the watermark settings, loading them at most once a minute
+func cachedWatermarkSettings(db *gorm.DB) models.WatermarkSettings {
+	watermarkCache.Lock()
+	defer watermarkCache.Unlock()
+	if watermarkCache.settings == nil || time.Since(watermarkCache.loadedAt) > watermarkSettingsTTL {
+		settings := models.GetWatermarkSettings(db)
+		watermarkCache.settings = &settings
+		watermarkCache.loadedAt = time.Now()
+	}
+	return *watermarkCache.settings
+}
+
+// GetWatermarkSettings returns the default watermark and the per category watermarks
+func (h *ImageHandler) GetWatermarkSettings(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    models.GetWatermarkSettings(h.db),
+	})
+}
+
+// UpdateWatermarkSettings replaces the watermark settings and removes the
+// stored renditions of every image whose watermark changed. URLs issued
+// before the change redirect to renditions with the new watermark.
+func (h *ImageHandler) UpdateWatermarkSettings(c *fiber.Ctx) error {
+	var req models.WatermarkSettings
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Invalid request body",
+		})
+	}
+
+	if err := h.validateWatermark(&req.Default); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": err.Error(),
+		})
+	}
+	for category, watermark := range req.Categories {
+		if !models.ValidateCategory(string(category)) {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": fmt.Sprintf("Invalid category: %s", category),
+			})
+		}
+		if err := h.validateWatermark(&watermark); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"success": false,
+				"message": fmt.Sprintf("%s: %s", category, err.Error()),
+			})
+		}
+		req.Categories[category] = watermark
+	}
+
+	previous := models.GetWatermarkSettings(h.db)
+	if err := models.SetWatermarkSettings(h.db, req); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to update settings",
+		})
+	}
+
+	watermarkCache.Lock()
+	watermarkCache.settings = nil
+	watermarkCache.Unlock()
+	// The featured response carries rendition URLs versioned by the watermark
+	invalidateFeatured()
+
+	recordAudit(h.db, c, "setting.update", models.AuditEntitySetting, models.SettingWatermark, previous, req)
+
+	var changed []models.MediaCategory
+	for _, category := range models.GetValidCategories() {
+		if previous.ForCategory(category) != req.ForCategory(category) {
+			changed = append(changed, category)
+		}
+	}
+	if len(changed) > 0 {
+		go h.purgeRenditions(changed)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Watermark settings updated successfully",
+		"data":    req,
+	})
+}
+
+// UploadWatermarkLogo stores a PNG logo and returns the key to use as a
+// watermark's logo_key
+func (h *ImageHandler) UploadWatermarkLogo(c *fiber.Ctx) error {
+	file, err := c.FormFile("logo")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "No logo uploaded",
+		})
+	}
+	if file.Size > maxWatermarkLogoSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"success": false,
+			"message": fmt.Sprintf("Logos are limited to %d MB", maxWatermarkLogoSize/1024/1024),
+		})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to read logo",
+		})
+	}
+	data, err := io.ReadAll(io.LimitReader(src, maxWatermarkLogoSize+1))
+	src.Close()
+	if err != nil || len(data) > maxWatermarkLogoSize {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to read logo",
+		})
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"success": false,
+			"message": "The logo must be a PNG image",
+		})
+	}
+
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("watermark/%s.png", hex.EncodeToString(sum[:8]))
+	if err := h.store.Put(key, bytes.NewReader(data)); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"success": false,
+			"message": "Failed to store logo",
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"success": true,
+		"message": "Logo uploaded successfully",
+		"data": fiber.Map{
+			"logo_key": key,
+			"url":      h.store.URL(key),
+		},
+	})
+}
+
+// validateWatermark checks a watermark and that its logo has been uploaded
+func (h *ImageHandler) validateWatermark(watermark *models.Watermark) error {
+	if err := watermark.Validate(); err != nil {
+		return err
+	}
+	if watermark.LogoKey != "" {
+		if _, err := h.watermarkLogo(watermark.LogoKey); err != nil {
+			return fmt.Errorf("logo_key must be a logo uploaded to /api/admin/watermark/logo")
+		}
+	}
+	return nil
+}
+
+// watermarkLogo loads and decodes an uploaded logo, keeping it for later renders
+func (h *ImageHandler) watermarkLogo(key string) (image.Image, error) {
+	watermarkCache.Lock()
+	logo, ok := watermarkCache.logos[key]
+	watermarkCache.Unlock()
+	if ok {
+		return logo, nil
+	}
+
+	file, _, err := h.store.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	logo, err = png.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	watermarkCache.Lock()
+	if watermarkCache.logos == nil || len(watermarkCache.logos) >= 8 {
+		watermarkCache.logos = make(map[string]image.Image)
+	}
+	watermarkCache.logos[key] = logo
+	watermarkCache.Unlock()
+	return logo, nil
+}
+
+// purgeRenditions removes the stored renditions of the images in the given
+// categories, whose watermark has changed
+func (h *ImageHandler) purgeRenditions(categories []models.MediaCategory) {
+	var ids []uint
+	h.db.Unscoped().Model(&models.Media{}).
+		Where("type = ? AND category IN ?", models.MediaTypeImage, categories).
+		Pluck("id", &ids)
+
+	for _, id := range ids {
+		if err := h.store.DeletePrefix(imageStoragePrefix(id)); err != nil {
+			log.Printf("Failed to remove renditions of media %d: %v", id, err)
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("🖼️  Removed stored renditions of %d images after a watermark change", len(ids))
+	}
+}
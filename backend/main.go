@@ -4,11 +4,14 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"photography-portfolio/config"
 	"photography-portfolio/handlers"
 	"photography-portfolio/middleware"
 	"photography-portfolio/models"
+	"photography-portfolio/storage"
+	"photography-portfolio/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -37,6 +40,20 @@ func main() {
 	// Initialize configuration
 	cfg := config.LoadConfig()
 
+	// Refuse to start with insecure settings in production
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	if cfg.JWTSecret == "" && cfg.UsesJWTSecret() {
+		secret, err := utils.GenerateRandomToken(32)
+		if err != nil {
+			log.Fatal("Failed to generate JWT secret:", err)
+		}
+		cfg.JWTSecret = secret
+		log.Println("⚠️  Warning: JWT_SECRET not set, using a random secret. Tokens will not survive a restart.")
+	}
+
 	// Initialize database
 	db, err := config.InitDatabase(cfg)
 	if err != nil {
@@ -48,6 +65,34 @@ func main() {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// Load JWT signing keys and rotate them on schedule
+	keys, err := utils.NewKeyManager(db, cfg)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	keys.StartRotation(time.Hour)
+	log.Printf("🔑 Signing access tokens with %s", keys.Algorithm())
+
+	// Storage for generated files such as video renditions
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+
+	// Signs /api/img URLs so only renditions issued by the server are rendered
+	imageSigner, err := utils.NewImageURLSigner(db, cfg)
+	if err != nil {
+		log.Fatal("Failed to load image URL secret:", err)
+	}
+
+	// Refuse to run production with the old default admin credentials
+	if models.HasDefaultAdminCredentials(db) {
+		if cfg.IsProduction() {
+			log.Fatal("Refusing to start: the default admin account still uses its default password. Change it before running in production.")
+		}
+		log.Println("⚠️  Warning: the default admin account still uses its default password. Change it before deploying.")
+	}
+
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
 		ErrorHandler: middleware.ErrorHandler,
@@ -63,13 +108,14 @@ func main() {
 	// CORS middleware
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CorsOrigin,
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-API-Key, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Checksum",
+		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS",
+		ExposeHeaders:    "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Tus-Checksum-Algorithm, Upload-Offset, Upload-Length, Upload-Expires, X-Media-ID, X-Duplicate-Of",
 		AllowCredentials: true,
 	}))
 
 	// Rate limiting middleware
-	app.Use(middleware.RateLimit())
+	app.Use(middleware.RateLimit("/uploads/", "/api/img/", cfg.StorageURL+"/"))
 
 	// Health check endpoints
 	app.Get("/api/health", func(c *fiber.Ctx) error {
@@ -115,43 +161,137 @@ func main() {
 	})
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, cfg)
-	mediaHandler := handlers.NewMediaHandler(db, cfg)
+	authHandler := handlers.NewAuthHandler(db, cfg, keys)
+	mediaHandler := handlers.NewMediaHandler(db, cfg, store, imageSigner)
 	contactHandler := handlers.NewContactHandler(db, cfg)
 	stripeHandler := handlers.NewStripeHandler(db, cfg)
 	adminHandler := handlers.NewAdminHandler(db)
+	quoteHandler := handlers.NewQuoteHandler(db, cfg)
+	contractHandler := handlers.NewContractHandler(db, cfg)
+	userHandler := handlers.NewUserHandler(db, cfg)
+	setupHandler := handlers.NewSetupHandler(db, cfg)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db, cfg)
+	auditHandler := handlers.NewAuditHandler(db)
+	trashHandler := handlers.NewTrashHandler(db, cfg, store)
+	albumHandler := handlers.NewAlbumHandler(db)
+	deliveryHandler := handlers.NewDeliveryHandler(db, store)
+	imageHandler := handlers.NewImageHandler(db, cfg, store, imageSigner)
+	searchHandler := handlers.NewSearchHandler(db, imageSigner)
+	homepageHandler := handlers.NewHomepageHandler(db, imageSigner)
+	proofingHandler := handlers.NewProofingHandler(db, cfg, imageSigner)
+
+	// Permanently delete trashed items once their retention period ends
+	trashHandler.StartPurge(time.Hour)
+
+	// Remove partial uploads that were never finished
+	mediaHandler.StartUploadCleanup(time.Hour)
+
+	// Hash media uploaded before duplicate detection existed
+	go mediaHandler.BackfillHashes()
+
+	// Estimate focal points and read colors and EXIF data of images uploaded before image analysis existed
+	go mediaHandler.BackfillImageAnalysis()
+
+	// Create posters, previews and HLS renditions for videos that have not been processed
+	go mediaHandler.ProcessPendingVideos()
+
+	// Authentication and permission checks for protected routes
+	authRequired := middleware.AuthRequired(keys, db)
+	canReadDashboard := middleware.RequirePermission(models.PermDashboardRead)
+	canReadMedia := middleware.RequirePermission(models.PermMediaRead)
+	canWriteMedia := middleware.RequirePermission(models.PermMediaWrite)
+	canReadBookings := middleware.RequirePermission(models.PermBookingsRead)
+	canWriteBookings := middleware.RequirePermission(models.PermBookingsWrite)
+	canReadMessages := middleware.RequirePermission(models.PermMessagesRead)
+	canWriteMessages := middleware.RequirePermission(models.PermMessagesWrite)
+
+	// Public keys for verifying access tokens
+	app.Get("/.well-known/jwks.json", authHandler.GetJWKS)
 
 	// API routes
 	api := app.Group("/api")
 
-	// Auth routes
-	auth := api.Group("/auth")
-	auth.Post("/login", authHandler.Login)
-	auth.Post("/logout", authHandler.Logout)
-	auth.Get("/me", middleware.AuthRequired(cfg), authHandler.GetProfile)
+	// First-run setup routes
+	api.Get("/setup", setupHandler.GetStatus)
+	api.Post("/setup", middleware.AuthRateLimit(), setupHandler.CompleteSetup)
+
+	// Auth routes (interactive logins only, API keys cannot manage accounts)
+	auth := api.Group("/auth", middleware.RejectAPIKeys())
+	auth.Post("/login", middleware.AuthRateLimit(), authHandler.Login)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Post("/logout", authRequired, authHandler.Logout)
+	auth.Post("/logout-all", authRequired, authHandler.LogoutAll)
+	auth.Get("/me", authRequired, authHandler.GetProfile)
+	auth.Get("/sessions", authRequired, authHandler.GetSessions)
+	auth.Delete("/sessions/:id", authRequired, authHandler.RevokeSession)
+	auth.Post("/invitations/:token/accept", userHandler.AcceptInvitation)
+
+	// API key routes
+	auth.Get("/api-keys", authRequired, apiKeyHandler.GetAPIKeys)
+	auth.Post("/api-keys", authRequired, apiKeyHandler.CreateAPIKey)
+	auth.Delete("/api-keys/:id", authRequired, apiKeyHandler.RevokeAPIKey)
+
+	// Password routes
+	passwordRateLimit := middleware.AuthRateLimit()
+	auth.Get("/password-policy", authHandler.GetPasswordPolicy)
+	auth.Post("/change-password", authRequired, authHandler.ChangePassword)
+	auth.Post("/forgot-password", passwordRateLimit, authHandler.ForgotPassword)
+	auth.Post("/reset-password", passwordRateLimit, authHandler.ResetPassword)
+
+	// Two-factor authentication routes. Setup and enable also accept an
+	// enrollment challenge token when 2FA is enforced before first login.
+	optionalAuth := middleware.OptionalAuth(keys, db)
+	auth.Post("/2fa/verify", middleware.AuthRateLimit(), authHandler.VerifyTwoFactor)
+	auth.Post("/2fa/setup", optionalAuth, authHandler.SetupTwoFactor)
+	auth.Post("/2fa/enable", middleware.AuthRateLimit(), optionalAuth, authHandler.EnableTwoFactor)
+	auth.Post("/2fa/disable", authRequired, authHandler.DisableTwoFactor)
+	auth.Post("/2fa/recovery-codes", authRequired, authHandler.RegenerateRecoveryCodes)
 
 	// Media routes
 	media := api.Group("/media")
+	// Registered before /:category, which would otherwise match it
+	media.Get("/duplicates", authRequired, canReadMedia, mediaHandler.GetDuplicates)
 	media.Get("/:category", mediaHandler.GetMediaByCategory)
 	media.Get("/item/:id", mediaHandler.GetMediaItem)
+	media.Options("/uploads", mediaHandler.UploadOptions)
+
+	// Resized image renditions from signed URLs
+	api.Get("/img/:id", optionalAuth, imageHandler.GetImage)
+
+	// Full-text search of public media and albums
+	api.Get("/search", searchHandler.Search)
+
+	// Featured media and the curated homepage sections
+	api.Get("/featured", homepageHandler.GetFeatured)
 	
-	// Protected media routes (admin only)
-	mediaAdmin := media.Use(middleware.AuthRequired(cfg))
-	mediaAdmin.Post("/upload", mediaHandler.UploadMedia)
-	mediaAdmin.Put("/:id", mediaHandler.UpdateMedia)
-	mediaAdmin.Delete("/:id", mediaHandler.DeleteMedia)
-	mediaAdmin.Delete("/bulk", mediaHandler.BulkDeleteMedia)
-	mediaAdmin.Get("/admin/all", mediaHandler.GetAllMediaAdmin)
+	// Protected media routes
+	mediaAdmin := media.Use(authRequired)
+	mediaAdmin.Post("/upload", canWriteMedia, mediaHandler.UploadMedia)
+	mediaAdmin.Post("/upload/batch", canWriteMedia, mediaHandler.UploadMediaBatch)
+	mediaAdmin.Post("/upload/batches", canWriteMedia, mediaHandler.CreateUploadBatch)
+	mediaAdmin.Post("/upload/batches/:id", canWriteMedia, mediaHandler.UploadBatchFile)
+
+	// Resumable uploads (tus protocol) for files larger than the request body limit
+	mediaAdmin.Post("/uploads", canWriteMedia, mediaHandler.CreateUpload)
+	mediaAdmin.Head("/uploads/:id", canWriteMedia, mediaHandler.GetUploadOffset)
+	mediaAdmin.Get("/uploads/:id", canWriteMedia, mediaHandler.GetUpload)
+	mediaAdmin.Patch("/uploads/:id", canWriteMedia, mediaHandler.PatchUpload)
+	mediaAdmin.Delete("/uploads/:id", canWriteMedia, mediaHandler.DeleteUpload)
+	mediaAdmin.Delete("/bulk", canWriteMedia, mediaHandler.BulkDeleteMedia)
+	mediaAdmin.Post("/duplicates/merge", canWriteMedia, mediaHandler.MergeDuplicates)
+	mediaAdmin.Put("/:id", canWriteMedia, mediaHandler.UpdateMedia)
+	mediaAdmin.Delete("/:id", canWriteMedia, mediaHandler.DeleteMedia)
+	mediaAdmin.Get("/admin/all", canReadMedia, mediaHandler.GetAllMediaAdmin)
 
 	// Contact routes
 	api.Post("/contact", contactHandler.SubmitContact)
 	
-	// Protected contact routes (admin only)
-	contactAdmin := api.Group("/contact", middleware.AuthRequired(cfg))
-	contactAdmin.Get("/messages", adminHandler.GetContactMessages)
-	contactAdmin.Put("/messages/:id/read", adminHandler.MarkMessageAsRead)
-	contactAdmin.Put("/messages/:id/unread", adminHandler.MarkMessageAsUnread)
-	contactAdmin.Delete("/messages/:id", adminHandler.DeleteMessage)
+	// Protected contact routes
+	contactAdmin := api.Group("/contact", authRequired)
+	contactAdmin.Get("/messages", canReadMessages, adminHandler.GetContactMessages)
+	contactAdmin.Put("/messages/:id/read", canWriteMessages, adminHandler.MarkMessageAsRead)
+	contactAdmin.Put("/messages/:id/unread", canWriteMessages, adminHandler.MarkMessageAsUnread)
+	contactAdmin.Delete("/messages/:id", canWriteMessages, adminHandler.DeleteMessage)
 
 	// Stripe routes
 	stripe := api.Group("/stripe")
@@ -160,13 +300,102 @@ func main() {
 	stripe.Get("/cancel", stripeHandler.HandleCancel)
 	stripe.Post("/webhook", stripeHandler.HandleWebhook)
 
+	// Quote routes (public, token-based)
+	quotes := api.Group("/quotes")
+	quotes.Get("/:token", quoteHandler.GetQuoteByToken)
+	quotes.Post("/:token/accept", quoteHandler.AcceptQuote)
+	quotes.Post("/:token/decline", quoteHandler.DeclineQuote)
+
+	// Proofing galleries (public, token-based)
+	api.Get("/proofing/:token", proofingHandler.GetProofingGallery)
+
+	// Contract routes (public, token-based)
+	contracts := api.Group("/contracts")
+	contracts.Get("/:token", contractHandler.GetContractByToken)
+	contracts.Post("/:token/sign", contractHandler.SignContract)
+	contracts.Get("/:token/pdf", contractHandler.DownloadSignedContract)
+
 	// Admin routes (protected)
-	admin := api.Group("/admin", middleware.AuthRequired(cfg))
-	admin.Get("/dashboard", adminHandler.GetDashboard)
-	admin.Get("/analytics", adminHandler.GetAnalytics)
+	admin := api.Group("/admin", authRequired)
+	admin.Get("/dashboard", canReadDashboard, adminHandler.GetDashboard)
+	admin.Get("/analytics", canReadDashboard, adminHandler.GetAnalytics)
+	// Searches what the user's role may read
+	admin.Get("/search", searchHandler.AdminSearch)
+
+	// Albums (media permissions)
+	admin.Get("/albums", canReadMedia, albumHandler.GetAlbums)
+	admin.Post("/albums", canWriteMedia, albumHandler.CreateAlbum)
+	admin.Put("/albums/:id", canWriteMedia, albumHandler.UpdateAlbum)
+	admin.Delete("/albums/:id", canWriteMedia, albumHandler.DeleteAlbum)
+	admin.Post("/albums/:id/proofing", canWriteMedia, proofingHandler.ShareProofingGallery)
+	admin.Delete("/albums/:id/proofing", canWriteMedia, proofingHandler.UnshareProofingGallery)
+	admin.Get("/homepage/sections", canReadMedia, homepageHandler.GetSections)
+	admin.Post("/homepage/sections", canWriteMedia, homepageHandler.CreateSection)
+	// Registered before /:id, which would otherwise match it
+	admin.Put("/homepage/sections/order", canWriteMedia, homepageHandler.ReorderSections)
+	admin.Put("/homepage/sections/:id", canWriteMedia, homepageHandler.UpdateSection)
+	admin.Delete("/homepage/sections/:id", canWriteMedia, homepageHandler.DeleteSection)
+	admin.Post("/img/sign", canReadMedia, imageHandler.SignImageURL)
+	admin.Get("/watermark", canReadMedia, imageHandler.GetWatermarkSettings)
+	admin.Put("/watermark", canWriteMedia, imageHandler.UpdateWatermarkSettings)
+	admin.Post("/watermark/logo", canWriteMedia, imageHandler.UploadWatermarkLogo)
+
+	// Quotes and contracts (bookings permissions)
+	admin.Get("/quotes", canReadBookings, quoteHandler.GetQuotes)
+	admin.Post("/quotes", canWriteBookings, quoteHandler.CreateQuote)
+	admin.Get("/quotes/:id", canReadBookings, quoteHandler.GetQuote)
+	admin.Post("/quotes/:id/send", canWriteBookings, quoteHandler.SendQuote)
+	admin.Get("/contract-templates", canReadBookings, contractHandler.GetTemplates)
+	admin.Post("/contract-templates", canWriteBookings, contractHandler.CreateTemplate)
+	admin.Put("/contract-templates/:id", canWriteBookings, contractHandler.UpdateTemplate)
+	admin.Get("/bookings/:id/contracts", canReadBookings, contractHandler.GetBookingContracts)
+	admin.Post("/bookings/:id/contract", canWriteBookings, contractHandler.IssueContract)
+	admin.Delete("/bookings/:id", canWriteBookings, adminHandler.DeleteBooking)
+
+	// Trash (restore or permanently delete before the retention period ends)
+	trash := admin.Group("/trash")
+	trash.Get("/media", canReadMedia, trashHandler.GetMediaTrash)
+	trash.Post("/media/:id/restore", canWriteMedia, trashHandler.RestoreMedia)
+	trash.Delete("/media/:id", canWriteMedia, trashHandler.PurgeMedia)
+	trash.Get("/messages", canReadMessages, trashHandler.GetMessageTrash)
+	trash.Post("/messages/:id/restore", canWriteMessages, trashHandler.RestoreMessage)
+	trash.Delete("/messages/:id", canWriteMessages, trashHandler.PurgeMessage)
+	trash.Get("/bookings", canReadBookings, trashHandler.GetBookingTrash)
+	trash.Post("/bookings/:id/restore", canWriteBookings, trashHandler.RestoreBooking)
+	trash.Delete("/bookings/:id", canWriteBookings, trashHandler.PurgeBooking)
+
+	// User management (owner only)
+	users := admin.Group("/users", middleware.RequirePermission(models.PermUsersManage))
+	users.Get("/", userHandler.GetUsers)
+	users.Post("/invite", userHandler.InviteUser)
+	users.Delete("/invitations/:id", userHandler.RevokeInvitation)
+	users.Put("/:id/role", userHandler.ChangeRole)
+	users.Put("/:id/deactivate", userHandler.DeactivateUser)
+	users.Put("/:id/activate", userHandler.ActivateUser)
+	users.Put("/:id/unlock", userHandler.UnlockUser)
+	users.Get("/login-attempts", userHandler.GetLoginAttempts)
+
+	// Session management (owner only)
+	sessions := admin.Group("/sessions", middleware.RequirePermission(models.PermUsersManage))
+	sessions.Get("/", userHandler.GetActiveSessions)
+	sessions.Delete("/:id", userHandler.RevokeUserSession)
+
+	// API key management (owner only)
+	apiKeys := admin.Group("/api-keys", middleware.RequirePermission(models.PermUsersManage))
+	apiKeys.Get("/", apiKeyHandler.GetAllAPIKeys)
+	apiKeys.Delete("/:id", apiKeyHandler.AdminRevokeAPIKey)
+
+	// Security settings (owner only)
+	admin.Get("/security", middleware.RequirePermission(models.PermUsersManage), userHandler.GetSecuritySettings)
+	admin.Put("/security", middleware.RequirePermission(models.PermUsersManage), userHandler.UpdateSecuritySettings)
+
+	// Audit log (owner only)
+	admin.Get("/audit", middleware.RequirePermission(models.PermUsersManage), auditHandler.GetAuditEvents)
 
-	// Static files - serve uploaded media
-	app.Static("/uploads", "./uploads")
+	// Uploaded media and generated files such as HLS playlists and segments.
+	// Signed-in users who can read media also get unpublished and trashed files.
+	app.Get("/uploads/:file", optionalAuth, deliveryHandler.ServeUpload)
+	app.Get(strings.TrimSuffix(cfg.StorageURL, "/")+"/*", optionalAuth, deliveryHandler.ServeStoredFile)
 
 	// Serve frontend static files in production
 	if cfg.Environment == "production" {
@@ -179,7 +408,7 @@ func main() {
 		// SPA fallback - serve index.html for non-API routes
 		app.Use(func(c *fiber.Ctx) error {
 			// Skip API routes
-			if strings.HasPrefix(c.Path(), "/api/") || strings.HasPrefix(c.Path(), "/uploads/") {
+			if strings.HasPrefix(c.Path(), "/api/") || strings.HasPrefix(c.Path(), "/uploads/") || strings.HasPrefix(c.Path(), cfg.StorageURL+"/") {
 				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 					"error":   "Not Found",
 					"message": "The requested resource was not found",
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// APIKeyHeader is an alternative to "Authorization: Bearer pk_..." for API keys
+const APIKeyHeader = "X-API-Key"
+
+// errAPIKeyInvalid is returned for unknown, revoked, expired or disallowed API keys
+var errAPIKeyInvalid = errors.New("api key is not valid")
+
+// isAPIKey checks if a bearer token is an API key
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, models.APIKeyPrefix)
+}
+
+// splitAPIKey splits "pk_<prefix>_<secret>" into its stored prefix and secret
+func splitAPIKey(key string) (string, string, bool) {
+	rest := strings.TrimPrefix(key, models.APIKeyPrefix)
+	idx := strings.Index(rest, "_")
+	if idx <= 0 || idx == len(rest)-1 {
+		return "", "", false
+	}
+	return models.APIKeyPrefix + rest[:idx], rest[idx+1:], true
+}
+
+// loadAPIKey returns the active API key for the presented key and client IP
+func loadAPIKey(db *gorm.DB, key, ip string) (*models.APIKey, error) {
+	prefix, secret, ok := splitAPIKey(key)
+	if !ok {
+		return nil, errAPIKeyInvalid
+	}
+
+	var apiKey models.APIKey
+	if err := db.Joins("User").Where("api_keys.prefix = ?", prefix).First(&apiKey).Error; err != nil {
+		return nil, errAPIKeyInvalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(apiKey.SecretHash)) != 1 {
+		return nil, errAPIKeyInvalid
+	}
+
+	if !apiKey.IsActive() || !apiKey.User.IsActive || !apiKey.AllowsIP(ip) {
+		return nil, errAPIKeyInvalid
+	}
+
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > sessionTouchInterval || apiKey.LastUsedIP != ip {
+		db.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).UpdateColumns(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"last_used_ip": ip,
+		})
+	}
+
+	return &apiKey, nil
+}
+
+// setAPIKeyLocals stores the key's owner and scopes in the context
+func setAPIKeyLocals(c *fiber.Ctx, apiKey *models.APIKey) {
+	c.Locals("userID", apiKey.UserID)
+	c.Locals("userEmail", apiKey.User.Email)
+	c.Locals("userRole", string(apiKey.User.Role))
+	c.Locals("apiKey", apiKey)
+}
+
+// GetAPIKeyFromContext returns the API key used to authenticate the request, if any
+func GetAPIKeyFromContext(c *fiber.Ctx) (*models.APIKey, bool) {
+	apiKey, ok := c.Locals("apiKey").(*models.APIKey)
+	return apiKey, ok
+}
+
+// RejectAPIKeys blocks API keys from account management routes such as
+// password changes and session handling, which need an interactive login.
+func RejectAPIKeys() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get(APIKeyHeader) != "" || isAPIKey(strings.TrimPrefix(c.Get("Authorization"), "Bearer ")) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "Forbidden",
+				"message": "API keys cannot be used for this endpoint",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// authenticateAPIKey validates an API key and continues the request as its owner
+func authenticateAPIKey(c *fiber.Ctx, db *gorm.DB, key string) error {
+	apiKey, err := loadAPIKey(db, key, c.IP())
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Unauthorized",
+			"message": "Invalid, expired or revoked API key",
+		})
+	}
+
+	setAPIKeyLocals(c, apiKey)
+	return c.Next()
+}
@@ -3,16 +3,21 @@ package middleware
 import (
 	"strings"
 
-	"photography-portfolio/config"
+	"photography-portfolio/models"
 	"photography-portfolio/utils"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-// AuthRequired middleware checks for valid JWT token
-func AuthRequired(cfg *config.Config) fiber.Handler {
+// AuthRequired middleware checks for a valid JWT token whose session is still active,
+// or an API key sent as a bearer token or in the X-API-Key header
+func AuthRequired(keys *utils.KeyManager, db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if key := c.Get(APIKeyHeader); key != "" {
+			return authenticateAPIKey(c, db, key)
+		}
+
 		// Get token from Authorization header
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -23,25 +28,20 @@ func AuthRequired(cfg *config.Config) fiber.Handler {
 		}
 
 		// Extract token from "Bearer <token>" format
-		tokenString := ""
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-		} else {
+		tokenString, ok := bearerToken(authHeader)
+		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error":   "Unauthorized",
 				"message": "Invalid authorization header format",
 			})
 		}
 
-		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid signing method")
-			}
-			return []byte(cfg.JWTSecret), nil
-		})
+		if isAPIKey(tokenString) {
+			return authenticateAPIKey(c, db, tokenString)
+		}
 
+		// Parse and validate token signature, issuer and expiry
+		claims, err := utils.ParseJWT(tokenString, keys)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error":   "Unauthorized",
@@ -50,32 +50,23 @@ func AuthRequired(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		// Extract claims
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Store user info in context
-			c.Locals("userID", claims["user_id"])
-			c.Locals("userEmail", claims["email"])
-			c.Locals("userRole", claims["role"])
-
-			// Validate token expiration
-			if !utils.ValidateTokenExpiration(claims) {
-				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-					"error":   "Unauthorized",
-					"message": "Token has expired",
-				})
-			}
-
-			return c.Next()
+		// Reject tokens whose session was revoked or whose user was deactivated
+		session, err := loadSession(db, claims)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   "Unauthorized",
+				"message": "Session has been revoked or expired",
+			})
 		}
 
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error":   "Unauthorized",
-			"message": "Invalid token claims",
-		})
+		// Store user info in context
+		setSessionLocals(c, session)
+
+		return c.Next()
 	}
 }
 
-// AdminRequired middleware checks if user has admin role
+// AdminRequired middleware checks if user has the owner role
 func AdminRequired() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userRole := c.Locals("userRole")
@@ -87,7 +78,7 @@ func AdminRequired() fiber.Handler {
 		}
 
 		role, ok := userRole.(string)
-		if !ok || role != "admin" {
+		if !ok || models.Role(role).Normalize() != models.RoleOwner {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error":   "Forbidden",
 				"message": "Admin access required",
@@ -98,37 +89,50 @@ func AdminRequired() fiber.Handler {
 	}
 }
 
-// OptionalAuth middleware that extracts user info if token is present but doesn't require it
-func OptionalAuth(cfg *config.Config) fiber.Handler {
+// RequirePermission middleware checks if the user's role grants the given permission
+func RequirePermission(permission models.Permission) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
-			return c.Next()
+		role, ok := GetUserRoleFromContext(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   "Unauthorized",
+				"message": "User role not found in token",
+			})
 		}
 
-		tokenString := ""
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-		} else {
-			return c.Next()
+		if !models.Role(role).HasPermission(permission) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "Forbidden",
+				"message": "You do not have permission to perform this action",
+				"details": string(permission),
+			})
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.NewError(fiber.StatusUnauthorized, "Invalid signing method")
-			}
-			return []byte(cfg.JWTSecret), nil
-		})
+		// API keys are limited to their scopes on top of the owner's role
+		if apiKey, ok := GetAPIKeyFromContext(c); ok && !apiKey.HasScope(permission) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "Forbidden",
+				"message": "API key does not have the required scope",
+				"details": string(permission),
+			})
+		}
 
-		if err != nil {
-			return c.Next() // Continue without auth if token is invalid
+		return c.Next()
+	}
+}
+
+// OptionalAuth middleware that extracts user info if token is present but doesn't require it
+func OptionalAuth(keys *utils.KeyManager, db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString, ok := bearerToken(c.Get("Authorization"))
+		if !ok {
+			return c.Next()
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			if utils.ValidateTokenExpiration(claims) {
-				c.Locals("userID", claims["user_id"])
-				c.Locals("userEmail", claims["email"])
-				c.Locals("userRole", claims["role"])
+		// Continue without auth if the token is invalid
+		if claims, err := utils.ParseJWT(tokenString, keys); err == nil {
+			if session, err := loadSession(db, claims); err == nil {
+				setSessionLocals(c, session)
 			}
 		}
 
@@ -136,6 +140,15 @@ func OptionalAuth(cfg *config.Config) fiber.Handler {
 	}
 }
 
+// bearerToken extracts the token from a "Bearer <token>" Authorization header
+func bearerToken(authHeader string) (string, bool) {
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	return token, token != ""
+}
+
 // GetUserIDFromContext extracts user ID from fiber context
 func GetUserIDFromContext(c *fiber.Ctx) (uint, bool) {
 	userID := c.Locals("userID")
@@ -184,8 +197,21 @@ func IsAuthenticatedUser(c *fiber.Ctx) bool {
 	return exists
 }
 
-// IsAdminUser checks if the current user is an admin
+// HasPermission checks if the current user's role, and API key scopes when
+// authenticated with a key, grant the permission
+func HasPermission(c *fiber.Ctx, permission models.Permission) bool {
+	role, ok := GetUserRoleFromContext(c)
+	if !ok || !models.Role(role).HasPermission(permission) {
+		return false
+	}
+	if apiKey, ok := GetAPIKeyFromContext(c); ok && !apiKey.HasScope(permission) {
+		return false
+	}
+	return true
+}
+
+// IsAdminUser checks if the current user is an owner
 func IsAdminUser(c *fiber.Ctx) bool {
 	role, exists := GetUserRoleFromContext(c)
-	return exists && role == "admin"
+	return exists && models.Role(role).Normalize() == models.RoleOwner
 } 
\ No newline at end of file
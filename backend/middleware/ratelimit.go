@@ -1,17 +1,31 @@
 package middleware
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
-// RateLimit creates a rate limiting middleware
-func RateLimit() fiber.Handler {
+// RateLimit creates a rate limiting middleware. GET and HEAD requests under
+// the unlimited prefixes are not counted, as a gallery page or a video stream
+// requests many files.
+func RateLimit(unlimitedPrefixes ...string) fiber.Handler {
 	return limiter.New(limiter.Config{
 		Max:        100,               // Maximum number of requests
 		Expiration: 15 * time.Minute,  // Time window
+		// Resumable upload chunks are authenticated and a large video needs hundreds of them
+		Next: func(c *fiber.Ctx) bool {
+			if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+				for _, prefix := range unlimitedPrefixes {
+					if strings.HasPrefix(c.Path(), prefix) {
+						return true
+					}
+				}
+			}
+			return c.Method() == fiber.MethodPatch && strings.HasPrefix(c.Path(), "/api/media/uploads/")
+		},
 		KeyGenerator: func(c *fiber.Ctx) string {
 			return c.IP() // Use IP address as the key
 		},
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"photography-portfolio/models"
+	"photography-portfolio/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval limits how often last-used timestamps are written
+const sessionTouchInterval = time.Minute
+
+// errSessionInactive is returned when a token's session is revoked, expired or its user is deactivated
+var errSessionInactive = errors.New("session is no longer active")
+
+// loadSession returns the active session referenced by the token claims
+func loadSession(db *gorm.DB, claims *utils.JWTClaims) (*models.Session, error) {
+	if claims.SessionID == 0 {
+		return nil, errSessionInactive
+	}
+
+	var session models.Session
+	err := db.Joins("User").
+		Where("sessions.id = ? AND sessions.user_id = ?", claims.SessionID, claims.UserID).
+		First(&session).Error
+	if err != nil {
+		return nil, errSessionInactive
+	}
+
+	if !session.IsActive() || !session.User.IsActive {
+		return nil, errSessionInactive
+	}
+
+	if time.Since(session.LastUsedAt) > sessionTouchInterval {
+		db.Model(&models.Session{}).Where("id = ?", session.ID).UpdateColumn("last_used_at", time.Now())
+	}
+
+	return &session, nil
+}
+
+// setSessionLocals stores the authenticated user from the session in the context.
+// The role comes from the database so role changes apply immediately.
+func setSessionLocals(c *fiber.Ctx, session *models.Session) {
+	c.Locals("userID", session.UserID)
+	c.Locals("userEmail", session.User.Email)
+	c.Locals("userRole", string(session.User.Role))
+	c.Locals("sessionID", session.ID)
+}
+
+// GetSessionIDFromContext extracts the session ID from fiber context
+func GetSessionIDFromContext(c *fiber.Ctx) (uint, bool) {
+	sessionID, ok := c.Locals("sessionID").(uint)
+	return sessionID, ok
+}
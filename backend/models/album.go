@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Album groups media from one shoot or delivery
+type Album struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Title         string         `json:"title" gorm:"not null;size:255"`
+	Description   string         `json:"description" gorm:"type:text"`
+	IsPublic      bool           `json:"is_public" gorm:"default:true"`
+	CoverMediaID  *uint          `json:"cover_media_id"`
+	MediaCount    int64          `json:"media_count" gorm:"-"`
+	ProofingToken *string        `json:"-" gorm:"size:64;uniqueIndex"` // Shares the album as a client proofing gallery, unset when not shared
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// AlbumRequest represents the request payload for creating or updating an album
+type AlbumRequest struct {
+	Title        string `json:"title" validate:"required,max=255"`
+	Description  string `json:"description" validate:"max=1000"`
+	IsPublic     *bool  `json:"is_public"`
+	CoverMediaID *uint  `json:"cover_media_id"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"net"
+	"time"
+)
+
+// APIKeyPrefix marks a bearer token as an API key rather than a JWT
+const APIKeyPrefix = "pk_"
+
+// APIKey is a personal credential for scripts and CI jobs. The key is shown once
+// on creation; only its lookup prefix and the hash of its secret are stored.
+type APIKey struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	Name       string       `json:"name" gorm:"not null;size:100"`
+	Prefix     string       `json:"prefix" gorm:"uniqueIndex;not null;size:32"`
+	SecretHash string       `json:"-" gorm:"not null;size:64"`
+	Scopes     []Permission `json:"scopes" gorm:"serializer:json"`
+	AllowedIPs []string     `json:"allowed_ips" gorm:"serializer:json"` // IPs or CIDR ranges, empty allows all
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	LastUsedIP string       `json:"last_used_ip" gorm:"size:45"`
+	RevokedAt  *time.Time   `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+
+	// Foreign keys
+	UserID uint `json:"user_id" gorm:"not null;index"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// APIKeyRequest represents the request payload for creating an API key
+type APIKeyRequest struct {
+	Name       string       `json:"name" validate:"required,max=100"`
+	Scopes     []Permission `json:"scopes" validate:"required,min=1"`
+	AllowedIPs []string     `json:"allowed_ips"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+}
+
+// APIKeyResponse represents the response payload for API key data
+type APIKeyResponse struct {
+	ID         uint         `json:"id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []Permission `json:"scopes"`
+	AllowedIPs []string     `json:"allowed_ips"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	LastUsedIP string       `json:"last_used_ip"`
+	RevokedAt  *time.Time   `json:"revoked_at"`
+	IsActive   bool         `json:"is_active"`
+	UserID     uint         `json:"user_id"`
+	UserEmail  string       `json:"user_email,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// GetAPIKeyScopes returns the permissions that can be granted to API keys.
+// User management is reserved for interactive sessions.
+func GetAPIKeyScopes() []Permission {
+	return []Permission{
+		PermDashboardRead,
+		PermMediaRead,
+		PermMediaWrite,
+		PermBookingsRead,
+		PermBookingsWrite,
+		PermMessagesRead,
+		PermMessagesWrite,
+	}
+}
+
+// ValidateAPIKeyScope checks if the permission can be granted to an API key
+func ValidateAPIKeyScope(scope Permission) bool {
+	for _, valid := range GetAPIKeyScopes() {
+		if scope == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateIPAllowlistEntry checks if the entry is a valid IP address or CIDR range
+func ValidateIPAllowlistEntry(entry string) bool {
+	if net.ParseIP(entry) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(entry)
+	return err == nil
+}
+
+// IsActive checks if the key is neither revoked nor expired
+func (k *APIKey) IsActive() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
+
+// HasScope checks if the key was granted the given permission
+func (k *APIKey) HasScope(permission Permission) bool {
+	for _, scope := range k.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP checks the client IP against the key's allowlist
+func (k *APIKey) AllowsIP(ip string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+
+	clientIP := net.ParseIP(ip)
+	if clientIP == nil {
+		return false
+	}
+
+	for _, entry := range k.AllowedIPs {
+		if allowed := net.ParseIP(entry); allowed != nil {
+			if allowed.Equal(clientIP) {
+				return true
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(clientIP) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToResponse converts APIKey to APIKeyResponse
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		AllowedIPs: k.AllowedIPs,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		RevokedAt:  k.RevokedAt,
+		IsActive:   k.IsActive(),
+		UserID:     k.UserID,
+		UserEmail:  k.User.Email,
+		CreatedAt:  k.CreatedAt,
+	}
+}
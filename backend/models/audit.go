@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditActorType identifies who performed an audited action
+type AuditActorType string
+
+const (
+	AuditActorUser    AuditActorType = "user"    // Logged-in admin user
+	AuditActorAPIKey  AuditActorType = "api_key" // Script or CI job using an API key
+	AuditActorClient  AuditActorType = "client"  // Client acting through a quote or contract link
+	AuditActorWebhook AuditActorType = "webhook" // External service such as Stripe
+	AuditActorSystem  AuditActorType = "system"  // Background job
+)
+
+// Audited entity types
+const (
+	AuditEntityMedia            = "media"
+	AuditEntityAlbum            = "album"
+	AuditEntityMessage          = "contact_message"
+	AuditEntityBooking          = "booking"
+	AuditEntityQuote            = "quote"
+	AuditEntityContract         = "contract"
+	AuditEntityContractTemplate = "contract_template"
+	AuditEntityUser             = "user"
+	AuditEntityInvitation       = "invitation"
+	AuditEntityAPIKey           = "api_key"
+	AuditEntitySetting          = "setting"
+	AuditEntityHomepageSection  = "homepage_section"
+)
+
+// AuditChange is the before and after value of a changed field
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEvent records who changed what and when
+type AuditEvent struct {
+	ID         uint                   `json:"id" gorm:"primaryKey"`
+	ActorType  AuditActorType         `json:"actor_type" gorm:"not null;size:20;index"`
+	ActorID    *uint                  `json:"actor_id" gorm:"index"` // User ID for user and API key actors
+	ActorName  string                 `json:"actor_name" gorm:"size:255"`
+	Action     string                 `json:"action" gorm:"not null;size:100;index"`
+	EntityType string                 `json:"entity_type" gorm:"not null;size:50;index:idx_audit_entity"`
+	EntityID   string                 `json:"entity_id" gorm:"size:64;index:idx_audit_entity"`
+	Changes    map[string]AuditChange `json:"changes" gorm:"serializer:json"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty" gorm:"serializer:json"`
+	IPAddress  string                 `json:"ip_address" gorm:"size:45"`
+	UserAgent  string                 `json:"user_agent" gorm:"size:500"`
+	CreatedAt  time.Time              `json:"created_at" gorm:"index"`
+}
+
+// auditIgnoredFields change on every save and would only add noise to diffs
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// DiffForAudit compares the JSON representation of two values and returns the
+// changed fields. Pass nil as before for creations and as after for deletions.
+// Fields hidden from JSON, such as password hashes, are never recorded.
+func DiffForAudit(before, after interface{}) map[string]AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	changes := map[string]AuditChange{}
+	for key, value := range beforeFields {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if other, ok := afterFields[key]; !ok || !reflect.DeepEqual(value, other) {
+			changes[key] = AuditChange{Before: value, After: afterFields[key]}
+		}
+	}
+	for key, value := range afterFields {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = AuditChange{After: value}
+		}
+	}
+	return changes
+}
+
+// auditFields converts a value to a map of its JSON fields
+func auditFields(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if value == nil || (reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil()) {
+		return fields
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+// RecordAuditEvent stores an audit event. Failures are logged and never fail the audited action.
+func RecordAuditEvent(db *gorm.DB, event *AuditEvent) {
+	if err := db.Create(event).Error; err != nil {
+		log.Printf("Failed to record audit event %s on %s %s: %v", event.Action, event.EntityType, event.EntityID, err)
+	}
+}
@@ -46,6 +46,7 @@ type Booking struct {
 	StripeSessionID string        `json:"stripe_session_id" gorm:"size:255"`
 	PaymentStatus  string         `json:"payment_status" gorm:"default:pending;size:20"`
 	PaidAt         *time.Time     `json:"paid_at"`
+	ContractStatus ContractStatus `json:"contract_status" gorm:"size:20"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
@@ -86,6 +87,7 @@ type BookingResponse struct {
 	Notes          string        `json:"notes"`
 	PaymentStatus  string        `json:"payment_status"`
 	PaidAt         *time.Time    `json:"paid_at"`
+	ContractStatus ContractStatus `json:"contract_status"`
 	CreatedAt      time.Time     `json:"created_at"`
 	UpdatedAt      time.Time     `json:"updated_at"`
 	User           UserResponse  `json:"user,omitempty"`
@@ -130,6 +132,7 @@ func (b *Booking) ToResponse() BookingResponse {
 		Notes:         b.Notes,
 		PaymentStatus: b.PaymentStatus,
 		PaidAt:        b.PaidAt,
+		ContractStatus: b.ContractStatus,
 		CreatedAt:     b.CreatedAt,
 		UpdatedAt:     b.UpdatedAt,
 		User:          b.User.ToResponse(),
@@ -151,6 +154,16 @@ func (b *Booking) IsPaid() bool {
 	return b.PaymentStatus == "paid" && b.PaidAt != nil
 }
 
+// RequiresSignature checks if the booking has a contract awaiting signature
+func (b *Booking) RequiresSignature() bool {
+	return b.ContractStatus == ContractStatusPending
+}
+
+// CanBeConfirmed checks if the booking is paid and has no unsigned contract
+func (b *Booking) CanBeConfirmed() bool {
+	return b.IsPaid() && !b.RequiresSignature()
+}
+
 // CanBeCancelled checks if the booking can be cancelled
 func (b *Booking) CanBeCancelled() bool {
 	return b.Status == BookingStatusPending || b.Status == BookingStatusConfirmed
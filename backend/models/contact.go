@@ -48,12 +48,9 @@ type ContactResponse struct {
 
 // ContactListResponse represents the response for contact messages list
 type ContactListResponse struct {
-	Messages   []ContactResponse `json:"messages"`
-	TotalCount int64             `json:"total_count"`
-	UnreadCount int64            `json:"unread_count"`
-	Page       int               `json:"page"`
-	PageSize   int               `json:"page_size"`
-	TotalPages int               `json:"total_pages"`
+	Messages    []ContactResponse `json:"messages"`
+	UnreadCount int64             `json:"unread_count"`
+	Pagination
 }
 
 // ContactFilter represents filters for contact message queries
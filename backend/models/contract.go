@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ContractStatus represents the signing status of a contract
+type ContractStatus string
+
+const (
+	ContractStatusNone    ContractStatus = ""
+	ContractStatusPending ContractStatus = "pending"
+	ContractStatusSigned  ContractStatus = "signed"
+	ContractStatusVoid    ContractStatus = "void"
+)
+
+// ContractTemplate represents a reusable contract with merge fields such as {{client_name}}
+type ContractTemplate struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null;size:255"`
+	ServiceType ServiceType    `json:"service_type" gorm:"size:50;index"` // Empty applies to all services
+	Body        string         `json:"body" gorm:"not null;type:text"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// Contract represents a contract issued for a booking and its signature record
+type Contract struct {
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Token           string         `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	Title           string         `json:"title" gorm:"not null;size:255"`
+	Body            string         `json:"body" gorm:"not null;type:text"` // Rendered body with merge fields applied
+	DocumentHash    string         `json:"document_hash" gorm:"not null;size:64"`
+	Status          ContractStatus `json:"status" gorm:"default:pending;size:20;index"`
+	SignerName      string         `json:"signer_name" gorm:"size:255"`
+	SignerIP        string         `json:"signer_ip" gorm:"size:45"`
+	SignerUserAgent string         `json:"signer_user_agent" gorm:"size:500"`
+	SignedAt        *time.Time     `json:"signed_at"`
+	SignedPDFPath   string         `json:"-" gorm:"size:500"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Foreign keys
+	BookingID  uint `json:"booking_id" gorm:"not null;index"`
+	TemplateID uint `json:"template_id" gorm:"index"`
+
+	// Relationships
+	Booking Booking `json:"-" gorm:"foreignKey:BookingID"`
+}
+
+// ContractTemplateRequest represents the request payload for contract templates
+type ContractTemplateRequest struct {
+	Name        string      `json:"name" validate:"required,max=255"`
+	ServiceType ServiceType `json:"service_type"`
+	Body        string      `json:"body" validate:"required"`
+	IsActive    *bool       `json:"is_active"`
+}
+
+// ContractSignRequest represents the client's signature submission
+type ContractSignRequest struct {
+	SignerName   string `json:"signer_name" validate:"required,max=255"`
+	DocumentHash string `json:"document_hash" validate:"required"`
+	Agree        bool   `json:"agree"`
+}
+
+// ContractResponse represents the response payload for contract data
+type ContractResponse struct {
+	ID           uint           `json:"id"`
+	BookingID    uint           `json:"booking_id"`
+	Title        string         `json:"title"`
+	Body         string         `json:"body"`
+	DocumentHash string         `json:"document_hash"`
+	Status       ContractStatus `json:"status"`
+	SignerName   string         `json:"signer_name,omitempty"`
+	SignedAt     *time.Time     `json:"signed_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// ContractMergeFields returns the merge field values available to templates for a booking
+func ContractMergeFields(b *Booking) map[string]string {
+	return map[string]string{
+		"client_name":    b.ClientName,
+		"client_email":   b.ClientEmail,
+		"client_phone":   b.ClientPhone,
+		"service_type":   string(b.ServiceType),
+		"scheduled_date": b.ScheduledDate.Format("January 2, 2006"),
+		"scheduled_time": b.ScheduledDate.Format("3:04 PM"),
+		"location":       b.Location,
+		"duration":       fmt.Sprintf("%d", b.Duration),
+		"price":          fmt.Sprintf("$%.2f", b.Price),
+		"booking_id":     fmt.Sprintf("%d", b.ID),
+	}
+}
+
+// Render applies booking merge fields to the template body. All fields are
+// replaced in one pass, so client-supplied values that look like merge fields
+// are left as they are.
+func (t *ContractTemplate) Render(b *Booking) string {
+	fields := ContractMergeFields(b)
+	pairs := make([]string, 0, len(fields)*4)
+	for field, value := range fields {
+		pairs = append(pairs, "{{"+field+"}}", value, "{{ "+field+" }}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(t.Body)
+}
+
+// IsSigned checks if the contract has been signed
+func (c *Contract) IsSigned() bool {
+	return c.Status == ContractStatusSigned && c.SignedAt != nil
+}
+
+// ToResponse converts Contract to ContractResponse
+func (c *Contract) ToResponse() ContractResponse {
+	return ContractResponse{
+		ID:           c.ID,
+		BookingID:    c.BookingID,
+		Title:        c.Title,
+		Body:         c.Body,
+		DocumentHash: c.DocumentHash,
+		Status:       c.Status,
+		SignerName:   c.SignerName,
+		SignedAt:     c.SignedAt,
+		CreatedAt:    c.CreatedAt,
+	}
+}
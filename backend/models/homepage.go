@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+)
+
+// HomepageSectionType is the kind of content a homepage section shows
+type HomepageSectionType string
+
+const (
+	// SectionHero is a slideshow of images with captions
+	SectionHero HomepageSectionType = "hero"
+	// SectionFeaturedAlbums shows albums with their cover images
+	SectionFeaturedAlbums HomepageSectionType = "featured_albums"
+	// SectionTestimonials shows client quotes, optionally with a photo
+	SectionTestimonials HomepageSectionType = "testimonials"
+)
+
+// MaxHomepageSectionItems limits the slides, albums or quotes of a section
+const MaxHomepageSectionItems = 20
+
+// HomepageSection is a curated block of the homepage. Active sections are
+// shown in order of Position between StartsAt and EndsAt, when set.
+type HomepageSection struct {
+	ID        uint                  `json:"id" gorm:"primaryKey"`
+	Type      HomepageSectionType   `json:"type" gorm:"not null;size:30"`
+	Title     string                `json:"title" gorm:"size:255"`
+	Subtitle  string                `json:"subtitle" gorm:"size:500"`
+	Position  int                   `json:"position" gorm:"not null;default:0;index"`
+	IsActive  bool                  `json:"is_active" gorm:"not null"`
+	StartsAt  *time.Time            `json:"starts_at"`
+	EndsAt    *time.Time            `json:"ends_at"`
+	IsLive    bool                  `json:"is_live" gorm:"-"` // Shown on the homepage right now
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Items     []HomepageSectionItem `json:"items" gorm:"foreignKey:SectionID;constraint:OnDelete:CASCADE"`
+}
+
+// HomepageSectionItem is a slide, album or testimonial of a homepage section
+type HomepageSectionItem struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	SectionID  uint   `json:"section_id" gorm:"not null;index"`
+	MediaID    *uint  `json:"media_id" gorm:"index"` // Slide, or testimonial photo
+	AlbumID    *uint  `json:"album_id" gorm:"index"`
+	Caption    string `json:"caption" gorm:"size:500"`
+	Quote      string `json:"quote" gorm:"type:text"`
+	Author     string `json:"author" gorm:"size:255"`
+	AuthorRole string `json:"author_role" gorm:"size:255"` // Such as "Wedding client, 2024"
+	LinkURL    string `json:"link_url" gorm:"size:500"`
+	SortOrder  int    `json:"sort_order" gorm:"default:0"`
+}
+
+// HomepageSectionItemRequest represents an item in a homepage section request
+type HomepageSectionItemRequest struct {
+	MediaID    *uint  `json:"media_id"`
+	AlbumID    *uint  `json:"album_id"`
+	Caption    string `json:"caption" validate:"max=500"`
+	Quote      string `json:"quote" validate:"max=2000"`
+	Author     string `json:"author" validate:"max=255"`
+	AuthorRole string `json:"author_role" validate:"max=255"`
+	LinkURL    string `json:"link_url" validate:"max=500"`
+}
+
+// HomepageSectionRequest represents the request payload for creating or
+// replacing a homepage section. Dates are ISO dates and may be empty.
+type HomepageSectionRequest struct {
+	Type     HomepageSectionType          `json:"type" validate:"required"`
+	Title    string                       `json:"title" validate:"max=255"`
+	Subtitle string                       `json:"subtitle" validate:"max=500"`
+	Position *int                         `json:"position"`
+	IsActive *bool                        `json:"is_active"`
+	StartsAt string                       `json:"starts_at"`
+	EndsAt   string                       `json:"ends_at"`
+	Items    []HomepageSectionItemRequest `json:"items" validate:"max=20"`
+}
+
+// ValidateHomepageSectionType checks if the section type is valid
+func ValidateHomepageSectionType(sectionType string) bool {
+	switch HomepageSectionType(sectionType) {
+	case SectionHero, SectionFeaturedAlbums, SectionTestimonials:
+		return true
+	}
+	return false
+}
+
+// IsLiveAt reports whether the section is shown on the homepage at a time
+func (s *HomepageSection) IsLiveAt(at time.Time) bool {
+	if !s.IsActive {
+		return false
+	}
+	if s.StartsAt != nil && at.Before(*s.StartsAt) {
+		return false
+	}
+	return s.EndsAt == nil || at.Before(*s.EndsAt)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InvitationValidity is how long an invitation link stays valid
+const InvitationValidity = 7 * 24 * time.Hour
+
+// UserInvitation represents a pending invitation for a new admin user
+type UserInvitation struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Email      string         `json:"email" gorm:"not null;size:255;index"`
+	FirstName  string         `json:"first_name" gorm:"size:100"`
+	LastName   string         `json:"last_name" gorm:"size:100"`
+	Role       Role           `json:"role" gorm:"not null;size:50"`
+	TokenHash  string         `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt  time.Time      `json:"expires_at" gorm:"not null"`
+	AcceptedAt *time.Time     `json:"accepted_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Foreign keys
+	InvitedByID uint `json:"invited_by_id" gorm:"index"`
+}
+
+// InviteRequest represents the request payload for inviting a user
+type InviteRequest struct {
+	Email     string `json:"email" validate:"required,email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Role      Role   `json:"role" validate:"required"`
+}
+
+// AcceptInvitationRequest represents the request payload for accepting an invitation
+type AcceptInvitationRequest struct {
+	Password  string `json:"password" validate:"required,min=8"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// IsPending checks if the invitation can still be accepted
+func (i *UserInvitation) IsPending() bool {
+	return i.AcceptedAt == nil && time.Now().Before(i.ExpiresAt)
+}
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -26,6 +28,12 @@ const (
 	MediaTypeVideo MediaType = "video"
 )
 
+// Where a media item's focal point comes from
+const (
+	FocalSourceAuto   = "auto"   // Estimated from the image
+	FocalSourceManual = "manual" // Set by an admin
+)
+
 // Media represents the media file model
 type Media struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
@@ -41,22 +49,52 @@ type Media struct {
 	Width        int            `json:"width"`
 	Height       int            `json:"height"`
 	Duration     int            `json:"duration,omitempty"` // For videos (in seconds)
+	VideoCodec   string         `json:"video_codec,omitempty" gorm:"size:50"`
+	FrameRate    float64        `json:"frame_rate,omitempty"`
+	PosterURL    string         `json:"poster_url,omitempty" gorm:"size:500"`
+	PreviewURL   string         `json:"preview_url,omitempty" gorm:"size:500"`
+	HLSURL       string         `json:"hls_url,omitempty" gorm:"column:hls_url;size:500"`
 	Alt          string         `json:"alt" gorm:"size:255"`
 	Tags         string         `json:"tags" gorm:"type:text"` // JSON array as string
 	IsPublic     bool           `json:"is_public" gorm:"default:true"`
+	Watermark    string         `json:"watermark,omitempty" gorm:"size:10"` // "on" or "off" overrides the category setting
 	IsFeatured   bool           `json:"is_featured" gorm:"default:false"`
 	SortOrder    int            `json:"sort_order" gorm:"default:0"`
 	ViewCount    int            `json:"view_count" gorm:"default:0"`
+	ContentHash  string         `json:"content_hash" gorm:"size:64;index"`     // SHA-256 of the file
+	PHash        string         `json:"phash,omitempty" gorm:"size:16"`        // Perceptual hash (dHash) of images
+	FocalX       float64        `json:"focal_x"`                               // Percent of the width that crops keep in view
+	FocalY       float64        `json:"focal_y"`                               // Percent of the height that crops keep in view
+	FocalSource  string         `json:"focal_source,omitempty" gorm:"size:10"` // Empty until the focal point is estimated
+	Palette      string         `json:"palette" gorm:"type:text"`              // JSON array of #rrggbb colors, the most common first
+	AverageColor string         `json:"average_color,omitempty" gorm:"size:7"`
+	BlurHash     string         `json:"blur_hash,omitempty" gorm:"size:64"` // Placeholder shown while the image loads
+	Exif         string         `json:"exif,omitempty" gorm:"type:text"`    // JSON object, empty until the file is read
 	UploadedAt   time.Time      `json:"uploaded_at"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 
 	// Foreign keys
-	UserID uint `json:"user_id" gorm:"not null;index"`
+	UserID  uint  `json:"user_id" gorm:"not null;index"`
+	AlbumID *uint `json:"album_id" gorm:"index"`
 
 	// Relationships
 	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+
+	// Signed URLs of resized renditions by preset name, set by handlers
+	Images map[string]string `json:"images,omitempty" gorm:"-"`
+}
+
+// MediaExif holds the camera and exposure settings read from an image file
+type MediaExif struct {
+	Camera       string `json:"camera,omitempty"`
+	Lens         string `json:"lens,omitempty"`
+	FocalLength  string `json:"focal_length,omitempty"`  // "85mm"
+	Aperture     string `json:"aperture,omitempty"`      // "f/1.8"
+	ExposureTime string `json:"exposure_time,omitempty"` // "1/250s"
+	ISO          int    `json:"iso,omitempty"`
+	TakenAt      string `json:"taken_at,omitempty"` // Camera local time, "2006-01-02T15:04:05"
 }
 
 // MediaRequest represents the request payload for media upload
@@ -85,17 +123,33 @@ type MediaResponse struct {
 	MimeType     string        `json:"mime_type"`
 	Width        int           `json:"width"`
 	Height       int           `json:"height"`
+	FocalX       float64       `json:"focal_x"`
+	FocalY       float64       `json:"focal_y"`
+	FocalSource  string        `json:"focal_source,omitempty"`
+	Palette      []string      `json:"palette"`
+	AverageColor string        `json:"average_color,omitempty"`
+	BlurHash     string        `json:"blur_hash,omitempty"`
+	Exif         *MediaExif    `json:"exif,omitempty"`
 	Duration     int           `json:"duration,omitempty"`
+	VideoCodec   string        `json:"video_codec,omitempty"`
+	FrameRate    float64       `json:"frame_rate,omitempty"`
+	PosterURL    string        `json:"poster_url,omitempty"`
+	PreviewURL   string        `json:"preview_url,omitempty"`
+	HLSURL       string        `json:"hls_url,omitempty"`
 	Alt          string        `json:"alt"`
 	Tags         []string      `json:"tags"`
 	IsPublic     bool          `json:"is_public"`
+	Watermark    string        `json:"watermark,omitempty"`
 	IsFeatured   bool          `json:"is_featured"`
 	SortOrder    int           `json:"sort_order"`
 	ViewCount    int           `json:"view_count"`
 	UploadedAt   time.Time     `json:"uploaded_at"`
 	CreatedAt    time.Time     `json:"created_at"`
 	UpdatedAt    time.Time     `json:"updated_at"`
+	AlbumID      *uint         `json:"album_id"`
 	User         UserResponse  `json:"user,omitempty"`
+
+	Images map[string]string `json:"images,omitempty"`
 }
 
 // MediaListResponse represents the response for media list with pagination
@@ -144,21 +198,93 @@ func (m *Media) ToResponse() MediaResponse {
 		MimeType:     m.MimeType,
 		Width:        m.Width,
 		Height:       m.Height,
+		FocalX:       m.FocalX,
+		FocalY:       m.FocalY,
+		FocalSource:  m.FocalSource,
+		Palette:      m.GetPalette(),
+		AverageColor: m.AverageColor,
+		BlurHash:     m.BlurHash,
+		Exif:         m.GetExif(),
 		Duration:     m.Duration,
+		VideoCodec:   m.VideoCodec,
+		FrameRate:    m.FrameRate,
+		PosterURL:    m.PosterURL,
+		PreviewURL:   m.PreviewURL,
+		HLSURL:       m.HLSURL,
 		Alt:          m.Alt,
 		Tags:         parseTagsFromString(m.Tags),
 		IsPublic:     m.IsPublic,
+		Watermark:    m.Watermark,
 		IsFeatured:   m.IsFeatured,
 		SortOrder:    m.SortOrder,
 		ViewCount:    m.ViewCount,
 		UploadedAt:   m.UploadedAt,
 		CreatedAt:    m.CreatedAt,
 		UpdatedAt:    m.UpdatedAt,
+		AlbumID:      m.AlbumID,
 		User:         m.User.ToResponse(),
+		Images:       m.Images,
 	}
 	return response
 }
 
+// SetTags normalizes the tags and stores them as a JSON array
+func (m *Media) SetTags(tags []string) {
+	m.Tags = tagsToString(NormalizeTags(tags))
+}
+
+// GetTags returns the media tags
+func (m *Media) GetTags() []string {
+	return parseTagsFromString(m.Tags)
+}
+
+// SetExif stores the EXIF fields as a JSON object. Images without EXIF data
+// store an empty object, so they are not read again.
+func (m *Media) SetExif(exif *MediaExif) {
+	if exif == nil {
+		exif = &MediaExif{}
+	}
+	data, err := json.Marshal(exif)
+	if err != nil {
+		return
+	}
+	m.Exif = string(data)
+}
+
+// GetExif returns the EXIF fields, or nil when there are none
+func (m *Media) GetExif() *MediaExif {
+	var exif MediaExif
+	if m.Exif == "" || json.Unmarshal([]byte(m.Exif), &exif) != nil || exif == (MediaExif{}) {
+		return nil
+	}
+	return &exif
+}
+
+// SetPalette stores the palette colors as a JSON array
+func (m *Media) SetPalette(colors []string) {
+	m.Palette = tagsToString(colors)
+}
+
+// GetPalette returns the palette colors, the most common first
+func (m *Media) GetPalette() []string {
+	return parseTagsFromString(m.Palette)
+}
+
+// NormalizeTags lowercases and trims tags, dropping empty, overlong and duplicate ones
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > 50 || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
 // IncrementViewCount increments the view count for the media
 func (m *Media) IncrementViewCount(tx *gorm.DB) error {
 	return tx.Model(m).UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error
@@ -216,9 +342,11 @@ func parseTagsFromString(tags string) []string {
 	if tags == "" {
 		return []string{}
 	}
-	// This would need proper JSON parsing in a real implementation
-	// For now, return empty slice
-	return []string{}
+	var parsed []string
+	if err := json.Unmarshal([]byte(tags), &parsed); err != nil {
+		return []string{}
+	}
+	return parsed
 }
 
 // tagsToString converts tags slice to JSON string
@@ -226,7 +354,9 @@ func tagsToString(tags []string) string {
 	if len(tags) == 0 {
 		return ""
 	}
-	// This would need proper JSON marshaling in a real implementation
-	// For now, return empty string
-	return ""
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return ""
+	}
+	return string(data)
 } 
\ No newline at end of file
@@ -2,6 +2,7 @@ package models
 
 import (
 	"log"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -16,6 +17,24 @@ func MigrateModels(db *gorm.DB) error {
 		&Media{},
 		&Booking{},
 		&ContactMessage{},
+		&Quote{},
+		&QuoteItem{},
+		&ContractTemplate{},
+		&Contract{},
+		&UserInvitation{},
+		&Session{},
+		&Setting{},
+		&RecoveryCode{},
+		&PasswordResetToken{},
+		&LoginAttempt{},
+		&APIKey{},
+		&SigningKey{},
+		&AuditEvent{},
+		&Upload{},
+		&UploadBatch{},
+		&Album{},
+		&HomepageSection{},
+		&HomepageSectionItem{},
 	)
 
 	if err != nil {
@@ -25,43 +44,61 @@ func MigrateModels(db *gorm.DB) error {
 
 	log.Println("✅ Database migrations completed successfully")
 
-	// Create default admin user if none exists
-	if err := createDefaultAdmin(db); err != nil {
-		log.Printf("⚠️  Warning: Failed to create default admin: %v", err)
+	// Users created before role-based access control were all "admin"
+	if err := db.Model(&User{}).Where("role = ? OR role = ''", RoleLegacyAdmin).Update("role", RoleOwner).Error; err != nil {
+		log.Printf("⚠️  Warning: Failed to migrate legacy admin roles: %v", err)
+	}
+
+	if err := migrateLegacyTags(db); err != nil {
+		log.Printf("⚠️  Warning: Failed to migrate legacy tags: %v", err)
+	}
+
+	if err := MigrateSearchIndexes(db); err != nil {
+		log.Printf("⚠️  Warning: Failed to set up full-text search: %v", err)
 	}
 
 	return nil
 }
 
-// createDefaultAdmin creates a default admin user if none exists
-func createDefaultAdmin(db *gorm.DB) error {
-	var count int64
-	if err := db.Model(&User{}).Count(&count).Error; err != nil {
+// migrateLegacyTags rewrites media tags stored comma-separated by earlier
+// versions as JSON arrays, which the tag filter reads as jsonb
+func migrateLegacyTags(db *gorm.DB) error {
+	var legacy []Media
+	if err := db.Unscoped().Select("id", "tags").Where("tags <> '' AND tags NOT LIKE '[%'").Find(&legacy).Error; err != nil {
 		return err
 	}
-
-	// If no users exist, create default admin
-	if count == 0 {
-		defaultAdmin := &User{
-			Email:     "admin@portfolio.com",
-			Password:  "admin123", // This will be hashed by the BeforeCreate hook
-			FirstName: "Admin",
-			LastName:  "User",
-			Role:      "admin",
-			IsActive:  true,
-		}
-
-		if err := db.Create(defaultAdmin).Error; err != nil {
+	for _, media := range legacy {
+		media.SetTags(strings.Split(media.Tags, ","))
+		if err := db.Unscoped().Model(&Media{}).Where("id = ?", media.ID).UpdateColumn("tags", media.Tags).Error; err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		log.Println("👤 Default admin user created:")
-		log.Println("   Email: admin@portfolio.com")
-		log.Println("   Password: admin123")
-		log.Println("   ⚠️  Please change the password after first login!")
+// Credentials of the admin account created automatically by earlier versions
+const (
+	legacyDefaultAdminEmail    = "admin@portfolio.com"
+	legacyDefaultAdminPassword = "admin123"
+)
+
+// SetupRequired checks if no users exist yet and the first-run setup must be completed
+func SetupRequired(db *gorm.DB) (bool, error) {
+	var count int64
+	if err := db.Model(&User{}).Count(&count).Error; err != nil {
+		return false, err
 	}
+	return count == 0, nil
+}
 
-	return nil
+// HasDefaultAdminCredentials checks if the previously auto-created admin account
+// still exists with its well-known default password
+func HasDefaultAdminCredentials(db *gorm.DB) bool {
+	var user User
+	if err := db.Where("email = ? AND is_active = ?", legacyDefaultAdminEmail, true).First(&user).Error; err != nil {
+		return false
+	}
+	return user.CheckPassword(legacyDefaultAdminPassword)
 }
 
 // SeedData seeds the database with sample data for development
@@ -81,7 +118,7 @@ func SeedData(db *gorm.DB) error {
 
 	// Get the first admin user
 	var admin User
-	if err := db.Where("role = ?", "admin").First(&admin).Error; err != nil {
+	if err := db.Where("role = ?", RoleOwner).First(&admin).Error; err != nil {
 		log.Println("⚠️  No admin user found, skipping media seed")
 		return nil
 	}
@@ -0,0 +1,13 @@
+package models
+
+// Pagination describes the page of a list response. Page is only set for
+// page-numbered requests, while NextCursor is set whenever more items follow
+// and can be sent as ?cursor= to continue after the last item.
+type Pagination struct {
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Pages      int64  `json:"pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Login attempt failure reasons
+const (
+	LoginFailureUnknownUser   = "unknown_user"
+	LoginFailureBadPassword   = "bad_password"
+	LoginFailureBadTwoFactor  = "bad_two_factor_code"
+	LoginFailureAccountLocked = "account_locked"
+)
+
+// PasswordResetToken is a single-use token emailed to reset a forgotten password.
+// Only the hash of the token is stored.
+type PasswordResetToken struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt      *time.Time `json:"used_at"`
+	RequestedIP string     `json:"requested_ip" gorm:"size:45"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Foreign keys
+	UserID uint `json:"user_id" gorm:"not null;index"`
+}
+
+// LoginAttempt records a login attempt for the audit trail
+type LoginAttempt struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Email     string    `json:"email" gorm:"size:255;index"`
+	Success   bool      `json:"success" gorm:"index"`
+	Reason    string    `json:"reason" gorm:"size:50"`
+	IPAddress string    `json:"ip_address" gorm:"size:45"`
+	UserAgent string    `json:"user_agent" gorm:"size:500"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	// Foreign keys
+	UserID *uint `json:"user_id" gorm:"index"`
+}
+
+// ChangePasswordRequest represents the request payload for changing the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// ForgotPasswordRequest represents the request payload for requesting a reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest represents the request payload for resetting a password with a token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// IsValid checks if the reset token is unused and not expired
+func (t *PasswordResetToken) IsValid() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QuoteStatus represents the status of a quote
+type QuoteStatus string
+
+const (
+	QuoteStatusDraft    QuoteStatus = "draft"
+	QuoteStatusSent     QuoteStatus = "sent"
+	QuoteStatusAccepted QuoteStatus = "accepted"
+	QuoteStatusDeclined QuoteStatus = "declined"
+)
+
+// DefaultQuoteValidity is used when a quote is created without an expiry date
+const DefaultQuoteValidity = 30 * 24 * time.Hour
+
+// Quote represents a custom proposal that a client can accept and pay online
+type Quote struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Token         string         `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	Title         string         `json:"title" gorm:"not null;size:255"`
+	ClientName    string         `json:"client_name" gorm:"not null;size:255"`
+	ClientEmail   string         `json:"client_email" gorm:"not null;size:255"`
+	ClientPhone   string         `json:"client_phone" gorm:"size:50"`
+	ServiceType   ServiceType    `json:"service_type" gorm:"not null;size:50"`
+	Description   string         `json:"description" gorm:"type:text"`
+	Location      string         `json:"location" gorm:"size:500"`
+	ScheduledDate *time.Time     `json:"scheduled_date"`
+	Duration      int            `json:"duration"` // Duration in hours
+	Terms         string         `json:"terms" gorm:"type:text"`
+	Total         float64        `json:"total" gorm:"not null"`
+	Status        QuoteStatus    `json:"status" gorm:"default:draft;size:20;index"`
+	ExpiresAt     time.Time      `json:"expires_at" gorm:"not null"`
+	SentAt        *time.Time     `json:"sent_at"`
+	ViewedAt      *time.Time     `json:"viewed_at"`
+	AcceptedAt    *time.Time     `json:"accepted_at"`
+	DeclinedAt    *time.Time     `json:"declined_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Foreign keys
+	UserID    uint  `json:"user_id" gorm:"index"`
+	BookingID *uint `json:"booking_id" gorm:"index"`
+
+	// Relationships
+	Items []QuoteItem `json:"items" gorm:"foreignKey:QuoteID;constraint:OnDelete:CASCADE"`
+}
+
+// QuoteItem represents a single line item on a quote
+type QuoteItem struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	QuoteID     uint    `json:"quote_id" gorm:"not null;index"`
+	Description string  `json:"description" gorm:"not null;size:500"`
+	Quantity    float64 `json:"quantity" gorm:"not null;default:1"`
+	UnitPrice   float64 `json:"unit_price" gorm:"not null"`
+	SortOrder   int     `json:"sort_order" gorm:"default:0"`
+}
+
+// QuoteItemRequest represents a line item in a quote request
+type QuoteItemRequest struct {
+	Description string  `json:"description" validate:"required,max=500"`
+	Quantity    float64 `json:"quantity" validate:"min=0"`
+	UnitPrice   float64 `json:"unit_price" validate:"min=0"`
+}
+
+// QuoteRequest represents the request payload for creating a quote
+type QuoteRequest struct {
+	Title         string             `json:"title" validate:"required,max=255"`
+	ClientName    string             `json:"client_name" validate:"required,max=255"`
+	ClientEmail   string             `json:"client_email" validate:"required,email"`
+	ClientPhone   string             `json:"client_phone" validate:"max=50"`
+	ServiceType   ServiceType        `json:"service_type" validate:"required"`
+	Description   string             `json:"description"`
+	Location      string             `json:"location" validate:"max=500"`
+	ScheduledDate string             `json:"scheduled_date"` // ISO date string
+	Duration      int                `json:"duration" validate:"min=0,max=24"`
+	Terms         string             `json:"terms"`
+	ExpiresAt     string             `json:"expires_at"` // ISO date string
+	Items         []QuoteItemRequest `json:"items" validate:"required,min=1"`
+}
+
+// QuoteResponse represents the response payload for quote data
+type QuoteResponse struct {
+	ID            uint        `json:"id"`
+	Title         string      `json:"title"`
+	ClientName    string      `json:"client_name"`
+	ClientEmail   string      `json:"client_email"`
+	ClientPhone   string      `json:"client_phone"`
+	ServiceType   ServiceType `json:"service_type"`
+	Description   string      `json:"description"`
+	Location      string      `json:"location"`
+	ScheduledDate *time.Time  `json:"scheduled_date"`
+	Duration      int         `json:"duration"`
+	Terms         string      `json:"terms"`
+	Items         []QuoteItem `json:"items"`
+	Total         float64     `json:"total"`
+	Status        QuoteStatus `json:"status"`
+	IsExpired     bool        `json:"is_expired"`
+	ExpiresAt     time.Time   `json:"expires_at"`
+	SentAt        *time.Time  `json:"sent_at"`
+	AcceptedAt    *time.Time  `json:"accepted_at"`
+	BookingID     *uint       `json:"booking_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// CalculateTotal sums the line items of the quote
+func (q *Quote) CalculateTotal() float64 {
+	var total float64
+	for _, item := range q.Items {
+		total += item.Quantity * item.UnitPrice
+	}
+	return total
+}
+
+// IsExpired checks if the quote is past its expiry date
+func (q *Quote) IsExpired() bool {
+	return time.Now().After(q.ExpiresAt)
+}
+
+// CanBeAccepted checks if the client can still accept the quote
+func (q *Quote) CanBeAccepted() bool {
+	return q.Status == QuoteStatusSent && !q.IsExpired()
+}
+
+// ToResponse converts Quote to QuoteResponse
+func (q *Quote) ToResponse() QuoteResponse {
+	return QuoteResponse{
+		ID:            q.ID,
+		Title:         q.Title,
+		ClientName:    q.ClientName,
+		ClientEmail:   q.ClientEmail,
+		ClientPhone:   q.ClientPhone,
+		ServiceType:   q.ServiceType,
+		Description:   q.Description,
+		Location:      q.Location,
+		ScheduledDate: q.ScheduledDate,
+		Duration:      q.Duration,
+		Terms:         q.Terms,
+		Items:         q.Items,
+		Total:         q.Total,
+		Status:        q.Status,
+		IsExpired:     q.IsExpired(),
+		ExpiresAt:     q.ExpiresAt,
+		SentAt:        q.SentAt,
+		AcceptedAt:    q.AcceptedAt,
+		BookingID:     q.BookingID,
+		CreatedAt:     q.CreatedAt,
+	}
+}
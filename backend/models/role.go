@@ -0,0 +1,98 @@
+package models
+
+// Role represents a user's role within the admin area
+type Role string
+
+const (
+	RoleOwner     Role = "owner"     // Full access including user management
+	RoleEditor    Role = "editor"    // Media library only
+	RoleAssistant Role = "assistant" // Bookings and messages
+	RoleViewer    Role = "viewer"    // Read-only access
+
+	// RoleLegacyAdmin is the role assigned before role-based access control existed.
+	// It is migrated to RoleOwner and treated as such if encountered.
+	RoleLegacyAdmin Role = "admin"
+)
+
+// Permission represents an action that can be granted to a role
+type Permission string
+
+const (
+	PermDashboardRead Permission = "dashboard:read"
+	PermMediaRead     Permission = "media:read"
+	PermMediaWrite    Permission = "media:write"
+	PermBookingsRead  Permission = "bookings:read"
+	PermBookingsWrite Permission = "bookings:write"
+	PermMessagesRead  Permission = "messages:read"
+	PermMessagesWrite Permission = "messages:write"
+	PermUsersManage   Permission = "users:manage"
+)
+
+// rolePermissions maps each role to the permissions it grants
+var rolePermissions = map[Role][]Permission{
+	RoleOwner: {
+		PermDashboardRead,
+		PermMediaRead, PermMediaWrite,
+		PermBookingsRead, PermBookingsWrite,
+		PermMessagesRead, PermMessagesWrite,
+		PermUsersManage,
+	},
+	RoleEditor: {
+		PermDashboardRead,
+		PermMediaRead, PermMediaWrite,
+	},
+	RoleAssistant: {
+		PermDashboardRead,
+		PermBookingsRead, PermBookingsWrite,
+		PermMessagesRead, PermMessagesWrite,
+	},
+	RoleViewer: {
+		PermDashboardRead,
+		PermMediaRead,
+		PermBookingsRead,
+		PermMessagesRead,
+	},
+}
+
+// Normalize maps legacy role names to their current equivalent
+func (r Role) Normalize() Role {
+	if r == RoleLegacyAdmin {
+		return RoleOwner
+	}
+	return r
+}
+
+// HasPermission checks if the role grants the given permission
+func (r Role) HasPermission(permission Permission) bool {
+	for _, p := range rolePermissions[r.Normalize()] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns all permissions granted to the role
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r.Normalize()]
+}
+
+// GetValidRoles returns all assignable roles
+func GetValidRoles() []Role {
+	return []Role{
+		RoleOwner,
+		RoleEditor,
+		RoleAssistant,
+		RoleViewer,
+	}
+}
+
+// ValidateRole checks if the role is assignable
+func ValidateRole(role string) bool {
+	for _, validRole := range GetValidRoles() {
+		if Role(role) == validRole {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// searchIndex describes the full-text search column of a table. Vector is
+// the tsvector expression over the row being written (NEW), and Columns are
+// the columns it reads, so updates that change none of them skip the work.
+type searchIndex struct {
+	Table   string
+	Columns []string
+	Vector  string
+}
+
+// searchIndexes are the tables searched by the public and admin search.
+// Titles and subjects weigh most (A), then alt texts, tags and names (B),
+// then descriptions and messages (C) and finally EXIF data and notes (D).
+var searchIndexes = []searchIndex{
+	{
+		Table:   "media",
+		Columns: []string{"title", "alt", "tags", "category", "description", "exif"},
+		Vector: `setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(NEW.alt, '') || ' ' || translate(coalesce(NEW.tags, ''), '[]",', '    ')), 'B') ||
+			setweight(to_tsvector('english', coalesce(NEW.category, '') || ' ' || coalesce(NEW.description, '')), 'C') ||
+			setweight(coalesce(jsonb_to_tsvector('simple', try_jsonb(NEW.exif), '["string", "numeric"]'), ''), 'D')`,
+	},
+	{
+		Table:   "albums",
+		Columns: []string{"title", "description"},
+		Vector: `setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(NEW.description, '')), 'C')`,
+	},
+	{
+		Table:   "contact_messages",
+		Columns: []string{"subject", "name", "email", "message"},
+		Vector: `setweight(to_tsvector('english', coalesce(NEW.subject, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(NEW.name, '') || ' ' || coalesce(NEW.email, '')), 'B') ||
+			setweight(to_tsvector('english', coalesce(NEW.message, '')), 'C')`,
+	},
+	{
+		Table:   "bookings",
+		Columns: []string{"client_name", "client_email", "location", "description", "notes"},
+		Vector: `setweight(to_tsvector('simple', coalesce(NEW.client_name, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(NEW.client_email, '')), 'B') ||
+			setweight(to_tsvector('english', coalesce(NEW.location, '') || ' ' || coalesce(NEW.description, '')), 'C') ||
+			setweight(to_tsvector('english', coalesce(NEW.notes, '')), 'D')`,
+	},
+}
+
+// tryJSONBFunction casts text to jsonb, or returns NULL for text that is not
+// JSON, so a malformed value cannot make writes to its row fail
+const tryJSONBFunction = `CREATE OR REPLACE FUNCTION try_jsonb(value text) RETURNS jsonb AS $$
+BEGIN
+	RETURN NULLIF(value, '')::jsonb;
+EXCEPTION WHEN invalid_text_representation THEN
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql IMMUTABLE`
+
+// MigrateSearchIndexes adds a search_vector column with a GIN index to the
+// searched tables, keeps it up to date with a trigger and fills it for rows
+// written before search existed. It is safe to run on every start.
+func MigrateSearchIndexes(db *gorm.DB) error {
+	if err := db.Exec(tryJSONBFunction).Error; err != nil {
+		return fmt.Errorf("search functions: %w", err)
+	}
+	for _, index := range searchIndexes {
+		unchanged := make([]string, len(index.Columns))
+		for i, column := range index.Columns {
+			unchanged[i] = fmt.Sprintf("NEW.%[1]s IS NOT DISTINCT FROM OLD.%[1]s", column)
+		}
+
+		statements := []string{
+			fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS search_vector tsvector`, index.Table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%[1]s_search ON %[1]s USING GIN (search_vector)`, index.Table),
+			fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s_search_vector_update() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'UPDATE' AND NEW.search_vector IS NOT NULL AND %[2]s THEN
+		RETURN NEW;
+	END IF;
+	NEW.search_vector := %[3]s;
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql`, index.Table, strings.Join(unchanged, " AND "), index.Vector),
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS %[1]s_search_vector ON %[1]s`, index.Table),
+			fmt.Sprintf(`CREATE TRIGGER %[1]s_search_vector BEFORE INSERT OR UPDATE ON %[1]s
+FOR EACH ROW EXECUTE FUNCTION %[1]s_search_vector_update()`, index.Table),
+			// The trigger computes the vector of rows it sees without one
+			fmt.Sprintf(`UPDATE %s SET search_vector = NULL WHERE search_vector IS NULL`, index.Table),
+		}
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return fmt.Errorf("search index on %s: %w", index.Table, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Session represents a logged-in device. The refresh token is rotated on every
+// use and only its hash is stored.
+type Session struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	RefreshTokenHash  string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	PreviousTokenHash string     `json:"-" gorm:"index;size:64"` // Detects reuse of a rotated refresh token
+	Device            string     `json:"device" gorm:"size:100"`
+	UserAgent         string     `json:"user_agent" gorm:"size:500"`
+	IPAddress         string     `json:"ip_address" gorm:"size:45"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// Foreign keys
+	UserID uint `json:"user_id" gorm:"not null;index"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// SessionResponse represents the response payload for session data
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"user_id"`
+	UserEmail  string    `json:"user_email,omitempty"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	IsCurrent  bool      `json:"is_current"`
+}
+
+// RefreshRequest represents the request payload for refreshing an access token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// IsActive checks if the session is neither revoked nor expired
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// ToResponse converts Session to SessionResponse
+func (s *Session) ToResponse(currentSessionID uint) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		UserEmail:  s.User.Email,
+		Device:     s.Device,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		CreatedAt:  s.CreatedAt,
+		IsCurrent:  s.ID == currentSessionID,
+	}
+}
+
+// DescribeDevice returns a short human readable device name from a User-Agent header
+func DescribeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Setting keys
+const (
+	SettingRequireTwoFactor = "security.require_two_factor"
+	SettingImageURLSecret   = "images.url_secret"
+	SettingWatermark        = "images.watermark"
+)
+
+// Setting represents a runtime setting changed through the admin API
+type Setting struct {
+	Key       string    `json:"key" gorm:"primaryKey;size:100"`
+	Value     string    `json:"value" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetSetting returns the value of a setting or the fallback if it is not set
+func GetSetting(db *gorm.DB, key, fallback string) string {
+	var setting Setting
+	if err := db.Where("key = ?", key).First(&setting).Error; err != nil {
+		return fallback
+	}
+	return setting.Value
+}
+
+// GetBoolSetting returns a boolean setting or the fallback if it is not set or invalid
+func GetBoolSetting(db *gorm.DB, key string, fallback bool) bool {
+	value, err := strconv.ParseBool(GetSetting(db, key, strconv.FormatBool(fallback)))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// SetSetting creates or updates a setting
+func SetSetting(db *gorm.DB, key, value string) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&Setting{Key: key, Value: value}).Error
+}
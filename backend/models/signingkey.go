@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// SigningKey is an asymmetric key pair used to sign access tokens, identified in
+// token headers by its KID. Retired keys stop signing but keep verifying tokens
+// until ExpiresAt so rotation does not log anyone out.
+type SigningKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	KID        string     `json:"kid" gorm:"uniqueIndex;not null;size:64"`
+	Algorithm  string     `json:"algorithm" gorm:"not null;size:10"`
+	PrivateKey string     `json:"-" gorm:"type:text;not null"` // PKCS#8 PEM
+	PublicKey  string     `json:"public_key" gorm:"type:text;not null"`
+	RetiredAt  *time.Time `json:"retired_at"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsSigning checks if the key is the one used for new tokens
+func (k *SigningKey) IsSigning() bool {
+	return k.RetiredAt == nil
+}
+
+// IsExpired checks if the key no longer verifies tokens
+func (k *SigningKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// RecoveryCodeCount is the number of recovery codes generated at once
+const RecoveryCodeCount = 10
+
+// RecoveryCode represents a single-use two-factor recovery code. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;size:64;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TwoFactorVerifyRequest represents the second login step
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorCodeRequest represents a request confirmed with a TOTP code
+type TwoFactorCodeRequest struct {
+	ChallengeToken string `json:"challenge_token"` // Used during enforced enrollment before a session exists
+	Code           string `json:"code" validate:"required"`
+}
+
+// TwoFactorDisableRequest represents the request payload for disabling 2FA
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
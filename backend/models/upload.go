@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Upload tracks a resumable upload while its chunks are received. The partial
+// file is kept under uploads/tmp until the last chunk arrives and it becomes a
+// media item.
+type Upload struct {
+	ID            string            `json:"id" gorm:"primaryKey;size:36"`
+	Length        int64             `json:"length" gorm:"not null"`
+	BytesReceived int64             `json:"offset" gorm:"not null;default:0"`
+	FileName      string            `json:"file_name" gorm:"not null;size:255"` // Original file name
+	Metadata      map[string]string `json:"metadata" gorm:"serializer:json"`
+	MediaID       *uint             `json:"media_id"`
+	Completing    bool              `json:"completing" gorm:"not null;default:false"` // The last chunk arrived and the media item is being created
+	CompletedAt   *time.Time        `json:"completed_at"`
+	ExpiresAt     time.Time         `json:"expires_at" gorm:"not null;index"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+
+	// Foreign keys
+	UserID uint `json:"user_id" gorm:"index"`
+}
+
+// IsComplete checks if all bytes of the upload have been received
+func (u *Upload) IsComplete() bool {
+	return u.BytesReceived >= u.Length
+}
+
+// IsExpired checks if an incomplete upload can no longer be resumed
+func (u *Upload) IsExpired() bool {
+	return u.CompletedAt == nil && time.Now().After(u.ExpiresAt)
+}
+
+// UploadBatch shares the category, tags, album and featured flag of a batch
+// upload whose files are sent one request at a time, so a batch is not
+// limited by the request body size. FileCount and TotalSize count the files
+// accepted so far.
+type UploadBatch struct {
+	ID             string    `json:"id" gorm:"primaryKey;size:36"`
+	Category       string    `json:"category" gorm:"not null;size:50"`
+	Tags           []string  `json:"tags" gorm:"serializer:json"`
+	AlbumID        *uint     `json:"album_id"`
+	IsFeatured     bool      `json:"is_featured" gorm:"not null;default:false"`
+	AllowDuplicate bool      `json:"allow_duplicate" gorm:"not null;default:false"`
+	FileCount      int       `json:"file_count" gorm:"not null;default:0"`
+	TotalSize      int64     `json:"total_size" gorm:"not null;default:0"`
+	ExpiresAt      time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// Foreign keys
+	UserID uint `json:"user_id" gorm:"index"`
+}
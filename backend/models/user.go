@@ -14,9 +14,21 @@ type User struct {
 	Password  string         `json:"-" gorm:"not null"`
 	FirstName string         `json:"first_name" gorm:"size:100"`
 	LastName  string         `json:"last_name" gorm:"size:100"`
-	Role      string         `json:"role" gorm:"default:admin;size:50"`
+	Role      Role           `json:"role" gorm:"default:viewer;size:50"`
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
 	LastLogin *time.Time     `json:"last_login"`
+
+	// Two-factor authentication
+	TOTPSecret       string     `json:"-" gorm:"size:64"`
+	TOTPEnabled      bool       `json:"totp_enabled" gorm:"default:false"`
+	TOTPEnabledAt    *time.Time `json:"totp_enabled_at"`
+	TOTPLastUsedStep int64      `json:"-" gorm:"default:0"` // Prevents replaying a code within its validity window
+
+	// Password and lockout state
+	PasswordChangedAt   *time.Time `json:"password_changed_at"`
+	FailedLoginAttempts int        `json:"-" gorm:"default:0"`
+	LockedUntil         *time.Time `json:"locked_until"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
@@ -36,15 +48,18 @@ type UserRequest struct {
 
 // UserResponse represents the response payload for user data
 type UserResponse struct {
-	ID        uint       `json:"id"`
-	Email     string     `json:"email"`
-	FirstName string     `json:"first_name"`
-	LastName  string     `json:"last_name"`
-	Role      string     `json:"role"`
-	IsActive  bool       `json:"is_active"`
-	LastLogin *time.Time `json:"last_login"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID          uint         `json:"id"`
+	Email       string       `json:"email"`
+	FirstName   string       `json:"first_name"`
+	LastName    string       `json:"last_name"`
+	Role        Role         `json:"role"`
+	Permissions []Permission `json:"permissions"`
+	IsActive    bool         `json:"is_active"`
+	TOTPEnabled bool         `json:"totp_enabled"`
+	LockedUntil *time.Time   `json:"locked_until,omitempty"`
+	LastLogin   *time.Time   `json:"last_login"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
 }
 
 // LoginRequest represents the login request payload
@@ -95,15 +110,18 @@ func (u *User) CheckPassword(password string) bool {
 // ToResponse converts User to UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Role:      u.Role,
-		IsActive:  u.IsActive,
-		LastLogin: u.LastLogin,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:          u.ID,
+		Email:       u.Email,
+		FirstName:   u.FirstName,
+		LastName:    u.LastName,
+		Role:        u.Role,
+		Permissions: u.Role.Permissions(),
+		IsActive:    u.IsActive,
+		TOTPEnabled: u.TOTPEnabled,
+		LockedUntil: u.LockedUntil,
+		LastLogin:   u.LastLogin,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
 	}
 }
 
@@ -127,9 +145,35 @@ func (u *User) GetFullName() string {
 	return u.Email
 }
 
-// IsAdmin checks if the user has admin role
+// IsAdmin checks if the user has the owner role
 func (u *User) IsAdmin() bool {
-	return u.Role == "admin"
+	return u.Role.Normalize() == RoleOwner
+}
+
+// HasPermission checks if the user's role grants the given permission
+func (u *User) HasPermission(permission Permission) bool {
+	return u.Role.HasPermission(permission)
+}
+
+// IsLocked checks if the account is temporarily locked after repeated failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+// SetPassword hashes and stores a new password
+func (u *User) SetPassword(tx *gorm.DB, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	u.Password = string(hashedPassword)
+	u.PasswordChangedAt = &now
+	return tx.Model(u).Updates(map[string]interface{}{
+		"password":            u.Password,
+		"password_changed_at": now,
+	}).Error
 }
 
 // UpdateLastLogin updates the user's last login time
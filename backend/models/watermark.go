@@ -0,0 +1,134 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// Watermark positions
+const (
+	WatermarkTopLeft     = "top-left"
+	WatermarkTopRight    = "top-right"
+	WatermarkBottomLeft  = "bottom-left"
+	WatermarkBottomRight = "bottom-right"
+	WatermarkCenter      = "center"
+)
+
+// Per-media watermark overrides
+const (
+	WatermarkInherit = ""    // Use the category or default setting
+	WatermarkOn      = "on"  // Always watermark public renditions
+	WatermarkOff     = "off" // Never watermark this media item
+)
+
+var watermarkColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Watermark describes the text or logo drawn onto public image renditions
+type Watermark struct {
+	Enabled  bool    `json:"enabled"`
+	Text     string  `json:"text,omitempty"`
+	LogoKey  string  `json:"logo_key,omitempty"` // Storage key of a PNG logo, drawn instead of the text
+	Position string  `json:"position"`
+	Opacity  float64 `json:"opacity"`         // 0 to 1
+	Scale    float64 `json:"scale"`           // Watermark width as a fraction of the image width
+	Color    string  `json:"color,omitempty"` // Text color as #rrggbb, white when empty
+}
+
+// WatermarkSettings holds the default watermark and per category replacements
+type WatermarkSettings struct {
+	Default    Watermark                   `json:"default"`
+	Categories map[MediaCategory]Watermark `json:"categories,omitempty"`
+}
+
+// DefaultWatermark is used until the watermark is configured
+var DefaultWatermark = Watermark{
+	Position: WatermarkBottomRight,
+	Opacity:  0.5,
+	Scale:    0.25,
+}
+
+// Validate fills in defaults and checks a watermark. The returned error can be shown to users.
+func (w *Watermark) Validate() error {
+	if w.Position == "" {
+		w.Position = DefaultWatermark.Position
+	}
+	switch w.Position {
+	case WatermarkTopLeft, WatermarkTopRight, WatermarkBottomLeft, WatermarkBottomRight, WatermarkCenter:
+	default:
+		return fmt.Errorf("position must be one of %s, %s, %s, %s or %s",
+			WatermarkTopLeft, WatermarkTopRight, WatermarkBottomLeft, WatermarkBottomRight, WatermarkCenter)
+	}
+	if w.Opacity <= 0 || w.Opacity > 1 {
+		return fmt.Errorf("opacity must be greater than 0 and at most 1")
+	}
+	if w.Scale < 0.02 || w.Scale > 1 {
+		return fmt.Errorf("scale must be between 0.02 and 1")
+	}
+	if w.Color != "" && !watermarkColorPattern.MatchString(w.Color) {
+		return fmt.Errorf("color must be a hex color such as #ffffff")
+	}
+	if len(w.Text) > 100 {
+		return fmt.Errorf("text must be at most 100 characters")
+	}
+	if w.Enabled && w.Text == "" && w.LogoKey == "" {
+		return fmt.Errorf("an enabled watermark needs a text or a logo")
+	}
+	return nil
+}
+
+// Version changes whenever the watermark would look different, or is empty
+// when it is disabled
+func (w Watermark) Version() string {
+	if !w.Enabled {
+		return ""
+	}
+	data, _ := json.Marshal(w)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:4])
+}
+
+// ForCategory returns the watermark configured for a category
+func (s WatermarkSettings) ForCategory(category MediaCategory) Watermark {
+	if watermark, ok := s.Categories[category]; ok {
+		return watermark
+	}
+	return s.Default
+}
+
+// ForMedia returns the watermark of a media item's public renditions
+func (s WatermarkSettings) ForMedia(media *Media) Watermark {
+	watermark := s.ForCategory(media.Category)
+	switch media.Watermark {
+	case WatermarkOn:
+		watermark.Enabled = watermark.Text != "" || watermark.LogoKey != ""
+	case WatermarkOff:
+		watermark.Enabled = false
+	}
+	return watermark
+}
+
+// GetWatermarkSettings loads the watermark settings, falling back to a
+// disabled default watermark
+func GetWatermarkSettings(db *gorm.DB) WatermarkSettings {
+	settings := WatermarkSettings{Default: DefaultWatermark}
+	if value := GetSetting(db, SettingWatermark, ""); value != "" {
+		if err := json.Unmarshal([]byte(value), &settings); err != nil {
+			return WatermarkSettings{Default: DefaultWatermark}
+		}
+	}
+	return settings
+}
+
+// SetWatermarkSettings stores the watermark settings
+func SetWatermarkSettings(db *gorm.DB, settings WatermarkSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return SetSetting(db, SettingWatermark, string(data))
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"photography-portfolio/config"
 	"photography-portfolio/models"
+	"photography-portfolio/utils"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -95,6 +96,19 @@ func main() {
 		filename := info.Name()
 		destPath := filepath.Join(destDir, filename)
 
+		// Skip files already in the library under another name, before
+		// copying so skipped files leave nothing behind
+		contentHash, pHash, err := utils.HashMediaFile(path)
+		if err != nil {
+			fmt.Printf("Failed to hash %s: %v\n", path, err)
+			return nil
+		}
+		var duplicate models.Media
+		if db.Where("content_hash = ? AND file_name <> ?", contentHash, filename).First(&duplicate).Error == nil {
+			fmt.Printf("Skipping duplicate of %s: %s\n", duplicate.FileName, filename)
+			return nil
+		}
+
 		// Copy file if it doesn't exist
 		if _, err := os.Stat(destPath); os.IsNotExist(err) {
 			if err := copyFile(path, destPath); err != nil {
@@ -125,6 +139,8 @@ func main() {
 			FileSize:     info.Size(),
 			MimeType:     "image/jpeg", // Default, could be detected based on file extension
 			ViewCount:    0,
+			ContentHash:  contentHash,
+			PHash:        pHash,
 			UserID:       1, // Assuming admin user has ID 1
 		}
 
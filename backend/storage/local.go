@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores files in a directory on disk
+type Local struct {
+	root    string
+	baseURL string
+}
+
+// NewLocal creates a local storage rooted at dir whose files are served under baseURL
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Local{
+		root:    dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+// Put writes the file to a temporary name first so readers never see a partial file
+func (s *Local) Put(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *Local) Open(key string) (io.ReadSeekCloser, *FileInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if stat.IsDir() {
+		file.Close()
+		return nil, nil, ErrNotFound
+	}
+	return file, &FileInfo{Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+func (s *Local) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeletePrefix removes a directory of files when the prefix ends in a slash,
+// otherwise the files in the prefix's directory that start with it
+func (s *Local) DeletePrefix(prefix string) error {
+	cleaned, err := CleanKey(prefix)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(prefix, "/") {
+		return os.RemoveAll(filepath.Join(s.root, filepath.FromSlash(cleaned)))
+	}
+
+	dir := filepath.Join(s.root, filepath.FromSlash(cleaned))
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(dir), escapeGlob(filepath.Base(dir))+"*"))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if err := os.RemoveAll(match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Local) URL(key string) string {
+	return s.baseURL + "/" + strings.TrimPrefix(key, "/")
+}
+
+// path returns the location of a key on disk
+func (s *Local) path(key string) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// escapeGlob escapes characters with a meaning in filepath.Match patterns
+func escapeGlob(name string) string {
+	replacer := strings.NewReplacer("*", "\\*", "?", "\\?", "[", "\\[")
+	return replacer.Replace(name)
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"photography-portfolio/config"
+)
+
+// ErrNotFound is returned when no file is stored under a key
+var ErrNotFound = errors.New("file not found")
+
+// ErrInvalidKey is returned for keys that are empty or escape the storage root
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps files generated from media, such as video renditions. Keys are
+// slash separated paths like "hls/12/720p/index.m3u8".
+type Storage interface {
+	// Put stores a file, replacing any file with the same key
+	Put(key string, r io.Reader) error
+	// Open returns a stored file and its details
+	Open(key string) (io.ReadSeekCloser, *FileInfo, error)
+	// Delete removes a file. Missing files are not an error.
+	Delete(key string) error
+	// DeletePrefix removes every file whose key starts with the prefix
+	DeletePrefix(prefix string) error
+	// URL returns the path a stored file is served from
+	URL(key string) string
+}
+
+// FileInfo describes a stored file
+type FileInfo struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// New returns the storage configured with STORAGE_DIR and STORAGE_URL
+func New(cfg *config.Config) (Storage, error) {
+	return NewLocal(cfg.StorageDir, cfg.StorageURL)
+}
+
+// CleanKey normalizes a key and rejects keys outside the storage root
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return strings.TrimPrefix(cleaned, "/"), nil
+}
+
+// ContentType returns the content type for a key from its extension,
+// including the HLS playlist and segment types browsers need
+func ContentType(key string) string {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	case ".m4s":
+		return "video/iso.segment"
+	case ".mp4":
+		return "video/mp4"
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/mail"
+	"net/smtp"
+	"strings"
+
+	"photography-portfolio/config"
+)
+
+// SendEmail sends a plain text email using the configured SMTP server.
+// If SMTP is not configured the message is logged instead of sent.
+func SendEmail(cfg *config.Config, to, subject, body string) error {
+	if cfg.SMTPHost == "" {
+		log.Printf("📧 SMTP not configured, email to %s not sent: %s", to, subject)
+		return nil
+	}
+
+	headers := []string{
+		fmt.Sprintf("From: %s", headerValue(cfg.FromEmail)),
+		fmt.Sprintf("To: %s", headerValue(to)),
+		fmt.Sprintf("Subject: %s", headerValue(subject)),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+	}
+	msg := strings.Join(headers, "\r\n") + "\r\n\r\n" + body
+
+	var auth smtp.Auth
+	if cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPass, cfg.SMTPHost)
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort)
+	if err := smtp.SendMail(addr, auth, cfg.FromEmail, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// IsValidEmail checks that an address is a bare email address, without a
+// display name or anything else that could end up in a mail header
+func IsValidEmail(address string) bool {
+	parsed, err := mail.ParseAddress(address)
+	return err == nil && parsed.Address == address
+}
+
+// headerValue keeps a mail header on one line, so values such as a
+// client-supplied subject cannot add headers of their own
+func headerValue(value string) string {
+	return strings.Join(strings.FieldsFunc(value, func(r rune) bool {
+		return r == '\r' || r == '\n'
+	}), " ")
+}
@@ -0,0 +1,43 @@
+package utils
+
+import "testing"
+
+func TestIsValidEmail(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"client@example.com", true},
+		{"first.last+tag@sub.example.co", true},
+
+		{"", false},
+		{"client", false},
+		{"client@", false},
+		{"Client <client@example.com>", false},
+		{"client@example.com\r\nBcc: other@example.com", false},
+		{"client@example.com, other@example.com", false},
+	}
+	for _, tt := range tests {
+		if got := IsValidEmail(tt.address); got != tt.want {
+			t.Errorf("IsValidEmail(%q) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestHeaderValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Your photography quote: Wedding", "Your photography quote: Wedding"},
+		{"Wedding\r\nBcc: other@example.com", "Wedding Bcc: other@example.com"},
+		{"Wedding\nBcc: other@example.com", "Wedding Bcc: other@example.com"},
+		{"\r\nWedding\r\n", "Wedding"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := headerValue(tt.value); got != tt.want {
+			t.Errorf("headerValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
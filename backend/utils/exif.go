@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"photography-portfolio/models"
+)
+
+// exifReadLimit is how much of a JPEG is searched for the EXIF segment,
+// which cameras write right after the start of the file
+const exifReadLimit = 256 * 1024
+
+// EXIF tags that are read
+const (
+	exifTagMake             = 0x010f
+	exifTagModel            = 0x0110
+	exifTagExifIFD          = 0x8769
+	exifTagExposureTime     = 0x829a
+	exifTagFNumber          = 0x829d
+	exifTagISO              = 0x8827
+	exifTagDateTimeOriginal = 0x9003
+	exifTagFocalLength      = 0x920a
+	exifTagLensModel        = 0xa434
+)
+
+// EXIF value types and their sizes in bytes
+var exifTypeSizes = map[uint16]uint32{
+	1:  1, // BYTE
+	2:  1, // ASCII
+	3:  2, // SHORT
+	4:  4, // LONG
+	5:  8, // RATIONAL
+	7:  1, // UNDEFINED
+	9:  4, // SLONG
+	10: 8, // SRATIONAL
+}
+
+var errNoExif = errors.New("no EXIF data")
+
+// exifEntry is a tag read from an IFD with its raw value
+type exifEntry struct {
+	Type  uint16
+	Count uint32
+	Value []byte
+}
+
+// ReadExif returns the camera, lens and exposure settings stored in a JPEG.
+// Files without EXIF data give an empty result rather than an error.
+func ReadExif(r io.Reader) (*models.MediaExif, error) {
+	head, err := io.ReadAll(io.LimitReader(r, exifReadLimit))
+	if err != nil {
+		return nil, err
+	}
+	tiff, err := findExifSegment(head)
+	if errors.Is(err, errNoExif) {
+		return &models.MediaExif{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseExif(tiff)
+}
+
+// findExifSegment walks the JPEG markers up to the image data and returns the
+// TIFF structure of the APP1 EXIF segment
+func findExifSegment(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, errNoExif
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return nil, errNoExif
+		}
+		marker := data[pos+1]
+		if marker == 0xff {
+			pos++
+			continue
+		}
+		// Start of scan or end of image: no metadata follows
+		if marker == 0xda || marker == 0xd9 {
+			return nil, errNoExif
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return nil, errNoExif
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+		pos += 2 + length
+	}
+	return nil, errNoExif
+}
+
+// parseExif reads the tags of interest from IFD0 and the EXIF sub-IFD
+func parseExif(tiff []byte) (*models.MediaExif, error) {
+	if len(tiff) < 8 {
+		return nil, errors.New("EXIF data is truncated")
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("EXIF data has an unknown byte order")
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return nil, errors.New("EXIF data is not a TIFF structure")
+	}
+
+	entries, err := readIFD(tiff, order, order.Uint32(tiff[4:]))
+	if err != nil {
+		return nil, err
+	}
+	if pointer, ok := entries[exifTagExifIFD]; ok {
+		if offset, ok := exifUint(pointer, order); ok {
+			sub, err := readIFD(tiff, order, uint32(offset))
+			if err != nil {
+				return nil, err
+			}
+			for tag, entry := range sub {
+				entries[tag] = entry
+			}
+		}
+	}
+
+	exif := &models.MediaExif{
+		Camera: cameraName(exifString(entries[exifTagMake]), exifString(entries[exifTagModel])),
+		Lens:   exifString(entries[exifTagLensModel]),
+	}
+	if focal, ok := exifRational(entries[exifTagFocalLength], order); ok && focal > 0 {
+		exif.FocalLength = fmt.Sprintf("%gmm", math.Round(focal*10)/10)
+	}
+	if aperture, ok := exifRational(entries[exifTagFNumber], order); ok && aperture > 0 {
+		exif.Aperture = fmt.Sprintf("f/%g", math.Round(aperture*10)/10)
+	}
+	if exposure, ok := exifRational(entries[exifTagExposureTime], order); ok && exposure > 0 {
+		if exposure < 1 {
+			exif.ExposureTime = fmt.Sprintf("1/%ds", int(math.Round(1/exposure)))
+		} else {
+			exif.ExposureTime = fmt.Sprintf("%gs", math.Round(exposure*10)/10)
+		}
+	}
+	if iso, ok := exifUint(entries[exifTagISO], order); ok {
+		exif.ISO = int(iso)
+	}
+	if taken, err := time.Parse("2006:01:02 15:04:05", exifString(entries[exifTagDateTimeOriginal])); err == nil {
+		exif.TakenAt = taken.Format("2006-01-02T15:04:05")
+	}
+	return exif, nil
+}
+
+// readIFD reads the entries of an image file directory
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) (map[uint16]exifEntry, error) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return nil, errors.New("EXIF directory is out of range")
+	}
+	count := uint32(order.Uint16(tiff[offset:]))
+	if uint64(offset)+2+uint64(count)*12 > uint64(len(tiff)) {
+		return nil, errors.New("EXIF directory is truncated")
+	}
+
+	entries := make(map[uint16]exifEntry, count)
+	for i := uint32(0); i < count; i++ {
+		raw := tiff[offset+2+i*12:]
+		entry := exifEntry{Type: order.Uint16(raw[2:]), Count: order.Uint32(raw[4:])}
+		size, ok := exifTypeSizes[entry.Type]
+		if !ok || entry.Count > uint32(len(tiff)) {
+			continue
+		}
+		length := uint64(size) * uint64(entry.Count)
+		if length <= 4 {
+			entry.Value = raw[8 : 8+length]
+		} else {
+			start := uint64(order.Uint32(raw[8:]))
+			if start+length > uint64(len(tiff)) {
+				continue
+			}
+			entry.Value = tiff[start : start+length]
+		}
+		entries[order.Uint16(raw)] = entry
+	}
+	return entries, nil
+}
+
+// exifString returns an ASCII value without its NUL terminator and padding
+func exifString(entry exifEntry) string {
+	if entry.Type != 2 {
+		return ""
+	}
+	value := string(entry.Value)
+	if i := strings.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// exifUint returns the first value of a SHORT or LONG tag
+func exifUint(entry exifEntry, order binary.ByteOrder) (uint32, bool) {
+	switch {
+	case entry.Type == 3 && len(entry.Value) >= 2:
+		return uint32(order.Uint16(entry.Value)), true
+	case entry.Type == 4 && len(entry.Value) >= 4:
+		return order.Uint32(entry.Value), true
+	}
+	return 0, false
+}
+
+// exifRational returns the first value of a RATIONAL tag
+func exifRational(entry exifEntry, order binary.ByteOrder) (float64, bool) {
+	if entry.Type != 5 || len(entry.Value) < 8 {
+		return 0, false
+	}
+	numerator, denominator := order.Uint32(entry.Value), order.Uint32(entry.Value[4:])
+	if denominator == 0 {
+		return 0, false
+	}
+	return float64(numerator) / float64(denominator), true
+}
+
+// cameraName joins the make and model, which often repeats the make
+// ("Canon" and "Canon EOS R5")
+func cameraName(cameraMake, model string) string {
+	if model == "" {
+		return cameraMake
+	}
+	if cameraMake == "" {
+		return model
+	}
+	brand := strings.ToLower(strings.Fields(cameraMake)[0])
+	if strings.HasPrefix(strings.ToLower(model), brand) {
+		return model
+	}
+	return cameraMake + " " + model
+}
@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Register decoders used to verify uploads
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"strings"
+
+	"photography-portfolio/config"
+	"photography-portfolio/models"
+)
+
+const (
+	// sniffWindow is how much of the start and end of a file is checked for embedded markup
+	sniffWindow = 64 * 1024
+	// maxImagePixels rejects decompression bombs before decoding the full image
+	maxImagePixels = 100_000_000
+)
+
+// ErrFileTooLarge is returned when a file exceeds the size limit for its type
+var ErrFileTooLarge = errors.New("File is too large")
+
+// mediaMimeTypes maps file extensions to the content types accepted for them.
+// MP4 and QuickTime share a container, so either is accepted for both.
+var mediaMimeTypes = map[string][]string{
+	"jpg":  {"image/jpeg"},
+	"jpeg": {"image/jpeg"},
+	"png":  {"image/png"},
+	"gif":  {"image/gif"},
+	"webp": {"image/webp"},
+	"mp4":  {"video/mp4", "video/quicktime"},
+	"m4v":  {"video/mp4", "video/quicktime"},
+	"mov":  {"video/quicktime", "video/mp4"},
+	"avi":  {"video/x-msvideo"},
+}
+
+// polyglotMarkers indicate a second format such as HTML, script or an archive
+// hidden inside a media file
+var polyglotMarkers = [][]byte{
+	[]byte("<script"),
+	[]byte("<html"),
+	[]byte("<!doctype"),
+	[]byte("<svg"),
+	[]byte("<iframe"),
+	[]byte("<?php"),
+	[]byte("javascript:"),
+	[]byte("%pdf-"),
+	[]byte("pk\x03\x04"),
+}
+
+// MediaFileInfo describes a validated media file
+type MediaFileInfo struct {
+	MimeType       string
+	IsVideo        bool
+	Width          int
+	Height         int
+	ContentHash    string            // Hex SHA-256 of the file
+	PerceptualHash string            // dHash of decodable images
+	FocalPoint     *FocalPoint       // Estimated subject position of decodable images
+	Colors         *ImageColors      // Palette and placeholder of decodable images
+	Exif           *models.MediaExif // Camera and exposure settings of images
+}
+
+// AllowedMediaExtensions returns the configured image and video extensions
+func AllowedMediaExtensions(cfg *config.Config) []string {
+	return append(append([]string{}, cfg.AllowedImageTypes...), cfg.AllowedVideoTypes...)
+}
+
+// IsAllowedMediaExtension checks if an extension such as ".jpg" is in the configured allowlists
+func IsAllowedMediaExtension(cfg *config.Config, ext string) bool {
+	return isImageExtension(cfg, ext) || isVideoExtension(cfg, ext)
+}
+
+// MaxMediaFileSize returns the size limit for files with the extension.
+// Images are limited to MAX_FILE_SIZE and videos to MAX_UPLOAD_SIZE.
+func MaxMediaFileSize(cfg *config.Config, ext string) int64 {
+	if isVideoExtension(cfg, ext) {
+		return cfg.MaxUploadSize
+	}
+	return cfg.MaxFileSize
+}
+
+// ValidateMediaFile checks a stored file against the configured allowlists and
+// size limits, sniffs its real type from its magic bytes, rejects files whose
+// content does not match the extension or that embed another format, and
+// verifies that images decode. The returned errors can be shown to users.
+func ValidateMediaFile(cfg *config.Config, path, ext string) (*MediaFileInfo, error) {
+	ext = normalizeExtension(ext)
+	if !IsAllowedMediaExtension(cfg, ext) {
+		return nil, fmt.Errorf("Files of type .%s are not allowed", ext)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if limit := MaxMediaFileSize(cfg, ext); stat.Size() > limit {
+		return nil, fmt.Errorf("%w, .%s files are limited to %d MB", ErrFileTooLarge, ext, limit/1024/1024)
+	}
+
+	head := make([]byte, sniffWindow)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	mimeType := SniffMediaType(head)
+	if mimeType == "" {
+		return nil, errors.New("File content is not a supported image or video")
+	}
+	if !containsString(mediaMimeTypes[ext], mimeType) {
+		return nil, fmt.Errorf("File content (%s) does not match the .%s extension", mimeType, ext)
+	}
+
+	tail := head
+	if stat.Size() > sniffWindow {
+		tail = make([]byte, sniffWindow)
+		if _, err := file.ReadAt(tail, stat.Size()-sniffWindow); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if containsPolyglotMarker(head) || containsPolyglotMarker(tail) {
+		return nil, errors.New("File contains embedded markup or another file format")
+	}
+
+	contentHash, err := FileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &MediaFileInfo{
+		MimeType:    mimeType,
+		IsVideo:     strings.HasPrefix(mimeType, "video/"),
+		ContentHash: contentHash,
+	}
+	if info.IsVideo {
+		return info, nil
+	}
+
+	if mimeType == "image/webp" {
+		width, height, err := webpDimensions(head)
+		if err != nil {
+			return nil, errors.New("Image could not be decoded")
+		}
+		info.Width, info.Height = width, height
+		return info, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	imageConfig, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, errors.New("Image could not be decoded")
+	}
+	if imageConfig.Width*imageConfig.Height > maxImagePixels {
+		return nil, errors.New("Image dimensions are too large")
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, errors.New("Image could not be decoded")
+	}
+
+	info.Width, info.Height = imageConfig.Width, imageConfig.Height
+	info.PerceptualHash = PerceptualHash(img)
+	focus := EstimateFocalPoint(img)
+	info.FocalPoint = &focus
+	colors := AnalyzeColors(img)
+	info.Colors = &colors
+	info.Exif = readImageExif(file, mimeType)
+	return info, nil
+}
+
+// readImageExif reads the EXIF data of a JPEG. Other formats and unreadable
+// EXIF data give an empty result, as the image itself is valid.
+func readImageExif(file io.ReadSeeker, mimeType string) *models.MediaExif {
+	if mimeType != "image/jpeg" {
+		return &models.MediaExif{}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return &models.MediaExif{}
+	}
+	exif, err := ReadExif(file)
+	if err != nil {
+		return &models.MediaExif{}
+	}
+	return exif
+}
+
+// SniffMediaType detects the content type of a supported image or video from
+// its first bytes. It returns an empty string for anything else.
+func SniffMediaType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "image/gif"
+	case len(head) >= 12 && bytes.HasPrefix(head, []byte("RIFF")) && string(head[8:12]) == "WEBP":
+		return "image/webp"
+	case len(head) >= 12 && bytes.HasPrefix(head, []byte("RIFF")) && string(head[8:12]) == "AVI ":
+		return "video/x-msvideo"
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		if string(head[8:12]) == "qt  " {
+			return "video/quicktime"
+		}
+		return "video/mp4"
+	case len(head) >= 8 && isQuickTimeAtom(string(head[4:8])):
+		// Older QuickTime files start without an ftyp box
+		return "video/quicktime"
+	}
+	return ""
+}
+
+// isQuickTimeAtom checks for top-level atoms that can start a QuickTime file
+func isQuickTimeAtom(name string) bool {
+	switch name {
+	case "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// webpDimensions validates a WebP header and returns the canvas size
+func webpDimensions(head []byte) (int, int, error) {
+	if len(head) < 30 {
+		return 0, 0, errors.New("webp header too short")
+	}
+
+	chunk := string(head[12:16])
+	data := head[20:]
+	switch chunk {
+	case "VP8 ":
+		// Lossy: frame tag followed by the start code and 14-bit sizes
+		if !bytes.Equal(data[3:6], []byte{0x9D, 0x01, 0x2A}) {
+			return 0, 0, errors.New("invalid vp8 start code")
+		}
+		width := int(binary.LittleEndian.Uint16(data[6:8]) & 0x3FFF)
+		height := int(binary.LittleEndian.Uint16(data[8:10]) & 0x3FFF)
+		return width, height, nil
+	case "VP8L":
+		// Lossless: signature byte followed by 14-bit sizes minus one
+		if data[0] != 0x2F {
+			return 0, 0, errors.New("invalid vp8l signature")
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		return int(bits&0x3FFF) + 1, int((bits>>14)&0x3FFF) + 1, nil
+	case "VP8X":
+		// Extended: 24-bit canvas sizes minus one
+		width := int(data[4]) | int(data[5])<<8 | int(data[6])<<16
+		height := int(data[7]) | int(data[8])<<8 | int(data[9])<<16
+		return width + 1, height + 1, nil
+	}
+	return 0, 0, errors.New("unknown webp chunk")
+}
+
+// containsPolyglotMarker checks a window of a file for markers of another format
+func containsPolyglotMarker(window []byte) bool {
+	lower := bytes.ToLower(window)
+	for _, marker := range polyglotMarkers {
+		if bytes.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func isImageExtension(cfg *config.Config, ext string) bool {
+	return containsString(cfg.AllowedImageTypes, normalizeExtension(ext))
+}
+
+func isVideoExtension(cfg *config.Config, ext string) bool {
+	return containsString(cfg.AllowedVideoTypes, normalizeExtension(ext))
+}
+
+// normalizeExtension lowercases an extension and removes the leading dot
+func normalizeExtension(ext string) string {
+	return strings.TrimPrefix(strings.ToLower(ext), ".")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"photography-portfolio/config"
+)
+
+func TestSniffMediaType(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"jpeg", []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF\x00"), "image/jpeg"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"gif87a", []byte("GIF87a\x01\x00\x01\x00"), "image/gif"},
+		{"gif89a", []byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"avi", []byte("RIFF\x24\x00\x00\x00AVI LIST"), "video/x-msvideo"},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00"), "video/mp4"},
+		{"quicktime with ftyp", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x02\x00"), "video/quicktime"},
+		{"quicktime without ftyp", []byte("\x00\x00\x00\x08wide\x00\x00\x00\x00mdat"), "video/quicktime"},
+
+		{"empty", nil, ""},
+		{"truncated jpeg", []byte("\xFF\xD8"), ""},
+		{"truncated png", []byte("\x89PNG\r\n"), ""},
+		{"riff of another type", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), ""},
+		{"short riff", []byte("RIFF\x24\x00\x00\x00WEB"), ""},
+		{"html", []byte("<!DOCTYPE html><html>"), ""},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg">`), ""},
+		{"pdf", []byte("%PDF-1.7\n"), ""},
+		{"zip", []byte("PK\x03\x04\x14\x00"), ""},
+		{"exe", []byte("MZ\x90\x00\x03\x00"), ""},
+		{"png signature later in the file", []byte("GARBAGE\x89PNG\r\n\x1a\n"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffMediaType(tt.head); got != tt.want {
+				t.Errorf("SniffMediaType(%q) = %q, want %q", tt.head, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMediaFile(t *testing.T) {
+	cfg := &config.Config{
+		MaxFileSize:       1024 * 1024,
+		MaxUploadSize:     10 * 1024 * 1024,
+		AllowedImageTypes: []string{"jpg", "jpeg", "png", "gif", "webp"},
+		AllowedVideoTypes: []string{"mp4", "mov", "avi"},
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for x := 0; x < 16; x++ {
+		img.Set(x, x%8, color.RGBA{R: uint8(x * 16), A: 255})
+	}
+	var pngData, jpegData, gifData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpegData, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gifData, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	mp4Data := []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2\x00\x00\x00\x08free")
+	withSuffix := func(data []byte, suffix string) []byte {
+		return append(append([]byte{}, data...), suffix...)
+	}
+
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+		wantMime string
+		wantErr  string // Part of the expected error, empty when the file is valid
+	}{
+		{"png", "photo.png", pngData.Bytes(), "image/png", ""},
+		{"jpeg", "photo.jpg", jpegData.Bytes(), "image/jpeg", ""},
+		{"uppercase extension", "photo.JPEG", jpegData.Bytes(), "image/jpeg", ""},
+		{"gif", "photo.gif", gifData.Bytes(), "image/gif", ""},
+		{"mp4", "clip.mp4", mp4Data, "video/mp4", ""},
+		{"mp4 named mov", "clip.mov", mp4Data, "video/mp4", ""},
+
+		{"png named jpg", "photo.jpg", pngData.Bytes(), "", "does not match the .jpg extension"},
+		{"jpeg named png", "photo.png", jpegData.Bytes(), "", "does not match the .png extension"},
+		{"gif named png", "photo.png", gifData.Bytes(), "", "does not match the .png extension"},
+		{"video named jpg", "photo.jpg", mp4Data, "", "does not match the .jpg extension"},
+		{"html named jpg", "photo.jpg", []byte("<html><script>alert(1)</script></html>"), "", "not a supported image or video"},
+		{"svg named png", "photo.png", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", "not a supported image or video"},
+		{"disallowed extension", "photo.svg", pngData.Bytes(), "", "are not allowed"},
+		{"no extension", "photo", pngData.Bytes(), "", "are not allowed"},
+
+		{"png with script", "photo.png", withSuffix(pngData.Bytes(), "<script>alert(1)</script>"), "", "embedded markup"},
+		{"jpeg with html", "photo.jpg", withSuffix(jpegData.Bytes(), "<!DOCTYPE html>"), "", "embedded markup"},
+		{"gif with php", "photo.gif", withSuffix(gifData.Bytes(), "<?php system($_GET['c']); ?>"), "", "embedded markup"},
+		{"png with zip", "photo.png", withSuffix(pngData.Bytes(), "PK\x03\x04\x14\x00"), "", "embedded markup"},
+		{"mp4 with pdf", "clip.mp4", withSuffix(mp4Data, "%PDF-1.7"), "", "embedded markup"},
+		{"marker case", "photo.png", withSuffix(pngData.Bytes(), "<ScRiPt>"), "", "embedded markup"},
+
+		{"truncated png", "photo.png", pngData.Bytes()[:20], "", "could not be decoded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := ValidateMediaFile(cfg, path, filepath.Ext(tt.fileName))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ValidateMediaFile(%s) error = %v, want %q", tt.fileName, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateMediaFile(%s) error = %v", tt.fileName, err)
+			}
+			if info.MimeType != tt.wantMime {
+				t.Errorf("ValidateMediaFile(%s) mime type = %s, want %s", tt.fileName, info.MimeType, tt.wantMime)
+			}
+		})
+	}
+}
+
+func TestValidateMediaFileTooLarge(t *testing.T) {
+	cfg := &config.Config{MaxFileSize: 10, AllowedImageTypes: []string{"png"}}
+	path := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateMediaFile(cfg, path, ".png"); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("ValidateMediaFile error = %v, want ErrFileTooLarge", err)
+	}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	// focalSampleSize is the long edge of the downscaled copy the focal point is estimated on
+	focalSampleSize = 128
+	// focalCellSize is the size of the cells whose entropy is compared, in sample pixels
+	focalCellSize = 8
+	// focalBins is the number of luminance levels in each cell's histogram
+	focalBins = 32
+)
+
+// FocalPoint is the point of an image that crops keep in view, in percent of
+// its width and height from the top left corner
+type FocalPoint struct {
+	X float64
+	Y float64
+}
+
+// EstimateFocalPoint guesses where the subject of a photo is. Detailed areas
+// such as faces and players have a higher luminance entropy than skies, walls
+// and blurred backgrounds, so the estimate is the centroid of the cells whose
+// entropy is above average, weighted by how far above. Images without
+// detail get the center.
+func EstimateFocalPoint(img image.Image) FocalPoint {
+	center := FocalPoint{X: 50, Y: 50}
+	rgba := toRGBA(img)
+	srcW, srcH := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if srcW == 0 || srcH == 0 {
+		return center
+	}
+
+	sample := downscale(rgba, focalSampleSize)
+	width, height := sample.Bounds().Dx(), sample.Bounds().Dy()
+
+	cellsX := (width + focalCellSize - 1) / focalCellSize
+	cellsY := (height + focalCellSize - 1) / focalCellSize
+	entropies := make([]float64, cellsX*cellsY)
+	var mean float64
+	for cy := 0; cy < cellsY; cy++ {
+		for cx := 0; cx < cellsX; cx++ {
+			e := cellEntropy(sample, cx*focalCellSize, cy*focalCellSize)
+			entropies[cy*cellsX+cx] = e
+			mean += e
+		}
+	}
+	mean /= float64(len(entropies))
+
+	var sumX, sumY, total float64
+	for cy := 0; cy < cellsY; cy++ {
+		for cx := 0; cx < cellsX; cx++ {
+			excess := entropies[cy*cellsX+cx] - mean
+			if excess <= 0 {
+				continue
+			}
+			weight := excess * excess
+			x0, y0 := cx*focalCellSize, cy*focalCellSize
+			x1, y1 := min(x0+focalCellSize, width), min(y0+focalCellSize, height)
+			sumX += weight * float64(x0+x1) / 2
+			sumY += weight * float64(y0+y1) / 2
+			total += weight
+		}
+	}
+	if total == 0 {
+		return center
+	}
+
+	return FocalPoint{
+		X: math.Round(sumX/total/float64(width)*1000) / 10,
+		Y: math.Round(sumY/total/float64(height)*1000) / 10,
+	}
+}
+
+// cellEntropy returns the Shannon entropy of the luminance of a cell
+func cellEntropy(img *image.RGBA, x0, y0 int) float64 {
+	bounds := img.Bounds()
+	x1 := min(x0+focalCellSize, bounds.Dx())
+	y1 := min(y0+focalCellSize, bounds.Dy())
+
+	var histogram [focalBins]int
+	count := 0
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			o := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			r, g, b := float64(img.Pix[o]), float64(img.Pix[o+1]), float64(img.Pix[o+2])
+			luminance := 0.299*r + 0.587*g + 0.114*b
+			histogram[min(focalBins-1, int(luminance)*focalBins/256)]++
+			count++
+		}
+	}
+
+	var entropy float64
+	for _, n := range histogram {
+		if n > 0 {
+			p := float64(n) / float64(count)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"photography-portfolio/config"
+)
+
+// hlsSegmentSeconds is the target length of each HLS segment
+const hlsSegmentSeconds = 6
+
+// HLSRendition is one quality level of an HLS ladder
+type HLSRendition struct {
+	Name         string // Directory and label, such as "720p"
+	Width        int
+	Height       int
+	VideoBitrate int // kbit/s
+	AudioBitrate int // kbit/s
+}
+
+// Bandwidth returns the peak bandwidth advertised in the master playlist, in bit/s
+func (r HLSRendition) Bandwidth() int {
+	// Allow for the maxrate headroom given to the encoder and container overhead
+	return (r.VideoBitrate*107/100 + r.AudioBitrate) * 1000 * 11 / 10
+}
+
+// hlsLadder lists the renditions from highest to lowest quality. Heights are
+// for landscape video, portrait video uses them as the width.
+var hlsLadder = []HLSRendition{
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "480p", Height: 480, VideoBitrate: 1400, AudioBitrate: 128},
+	{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+}
+
+// HLSLadder returns the renditions for a source video. Renditions larger than
+// the source are skipped, and a video smaller than every rendition is streamed
+// at its own size.
+func HLSLadder(width, height int) []HLSRendition {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+
+	shortSide := min(width, height)
+	var ladder []HLSRendition
+	for _, rendition := range hlsLadder {
+		if rendition.Height <= shortSide {
+			ladder = append(ladder, scaleRendition(rendition, width, height, rendition.Height))
+		}
+	}
+	if len(ladder) == 0 {
+		lowest := hlsLadder[len(hlsLadder)-1]
+		lowest.Name = fmt.Sprintf("%dp", evenDimension(shortSide))
+		ladder = append(ladder, scaleRendition(lowest, width, height, shortSide))
+	}
+	return ladder
+}
+
+// TranscodeHLSRendition encodes one rendition of a video into dir as
+// index.m3u8 and numbered MPEG-TS segments. Keyframes are forced on segment
+// boundaries so players can switch renditions between any two segments.
+func TranscodeHLSRendition(cfg *config.Config, src, dir string, rendition HLSRendition) error {
+	_, err := runVideoCommand(cfg.FFmpegPath,
+		"-y", "-v", "error",
+		"-i", src,
+		"-map", "0:v:0", "-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale=%d:%d", rendition.Width, rendition.Height),
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-profile:v", "main",
+		"-pix_fmt", "yuv420p",
+		"-b:v", fmt.Sprintf("%dk", rendition.VideoBitrate),
+		"-maxrate", fmt.Sprintf("%dk", rendition.VideoBitrate*107/100),
+		"-bufsize", fmt.Sprintf("%dk", rendition.VideoBitrate*3/2),
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", hlsSegmentSeconds),
+		"-sc_threshold", "0",
+		"-c:a", "aac",
+		"-b:a", fmt.Sprintf("%dk", rendition.AudioBitrate),
+		"-ac", "2",
+		"-f", "hls",
+		"-hls_time", fmt.Sprint(hlsSegmentSeconds),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "segment_%04d.ts"),
+		filepath.Join(dir, "index.m3u8"),
+	)
+	return err
+}
+
+// HLSMasterPlaylist lists the renditions, each in a directory named after it
+func HLSMasterPlaylist(renditions []HLSRendition) string {
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, rendition := range renditions {
+		fmt.Fprintf(&playlist, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,NAME=\"%s\"\n",
+			rendition.Bandwidth(), rendition.Width, rendition.Height, rendition.Name)
+		fmt.Fprintf(&playlist, "%s/index.m3u8\n", rendition.Name)
+	}
+	return playlist.String()
+}
+
+// scaleRendition sets a rendition's size from the source aspect ratio
+func scaleRendition(rendition HLSRendition, width, height, shortSide int) HLSRendition {
+	if width >= height {
+		rendition.Width = evenDimension(width * shortSide / height)
+		rendition.Height = evenDimension(shortSide)
+	} else {
+		rendition.Width = evenDimension(shortSide)
+		rendition.Height = evenDimension(height * shortSide / width)
+	}
+	return rendition
+}
+
+// evenDimension rounds a dimension down to an even number, as H.264 requires
+func evenDimension(size int) int {
+	if size < 2 {
+		return 2
+	}
+	return size - size%2
+}
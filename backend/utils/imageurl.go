@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"photography-portfolio/config"
+	"photography-portfolio/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultImageQuality is the JPEG quality used when none is requested
+const DefaultImageQuality = 80
+
+// imageQualities are the JPEG qualities that may be requested
+var imageQualities = []int{60, 70, 80, 90}
+
+// ImageURLSigner signs image transformation URLs so only URLs issued by the
+// server are rendered
+type ImageURLSigner struct {
+	secret []byte
+}
+
+// NewImageURLSigner uses IMAGE_URL_SECRET, or a secret generated on first
+// start and kept in the settings table so URLs stay valid across restarts
+func NewImageURLSigner(db *gorm.DB, cfg *config.Config) (*ImageURLSigner, error) {
+	if cfg.ImageURLSecret != "" {
+		return &ImageURLSigner{secret: []byte(cfg.ImageURLSecret)}, nil
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	// Another instance may have stored a secret first, so keep whichever exists
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Setting{
+		Key:   models.SettingImageURLSecret,
+		Value: hex.EncodeToString(secret),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	stored := models.GetSetting(db, models.SettingImageURLSecret, "")
+	if stored == "" {
+		return nil, fmt.Errorf("failed to load the image URL secret")
+	}
+	return &ImageURLSigner{secret: []byte(stored)}, nil
+}
+
+// Sign returns the signature of a transformation of a media item's image.
+// The version changes when the rendered image would, so cached URLs expire.
+func (s *ImageURLSigner) Sign(mediaID uint, version string, t ImageTransform) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%d|%s|%d|%d|%s|%s|%d", mediaID, version, t.Width, t.Height, t.Fit, t.Format, t.Quality)
+	if t.Watermark {
+		mac.Write([]byte("|wm"))
+	}
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Verify checks a signature in constant time
+func (s *ImageURLSigner) Verify(mediaID uint, version string, t ImageTransform, signature string) bool {
+	return hmac.Equal([]byte(s.Sign(mediaID, version, t)), []byte(signature))
+}
+
+// URL returns the signed /api/img URL of a transformation
+func (s *ImageURLSigner) URL(mediaID uint, version string, t ImageTransform) string {
+	query := url.Values{}
+	if t.Width > 0 {
+		query.Set("w", strconv.Itoa(t.Width))
+	}
+	if t.Height > 0 {
+		query.Set("h", strconv.Itoa(t.Height))
+	}
+	query.Set("fit", t.Fit)
+	query.Set("fmt", t.Format)
+	if t.Quality > 0 {
+		query.Set("q", strconv.Itoa(t.Quality))
+	}
+	if t.Watermark {
+		query.Set("wm", "1")
+	}
+	query.Set("v", version)
+	query.Set("sig", s.Sign(mediaID, version, t))
+	return fmt.Sprintf("/api/img/%d?%s", mediaID, query.Encode())
+}
+
+// NormalizeImageTransform fills in defaults and checks a transformation
+// against the allowed sizes, fits, formats and qualities. The returned error
+// can be shown to users.
+func NormalizeImageTransform(cfg *config.Config, t ImageTransform) (ImageTransform, error) {
+	if t.Width == 0 && t.Height == 0 {
+		return t, fmt.Errorf("w or h is required")
+	}
+	for _, size := range []int{t.Width, t.Height} {
+		if size != 0 && !containsInt(cfg.ImageSizes, size) {
+			return t, fmt.Errorf("w and h must be one of %v", cfg.ImageSizes)
+		}
+	}
+
+	switch t.Fit {
+	case "":
+		t.Fit = FitContain
+		if t.Width > 0 && t.Height > 0 {
+			t.Fit = FitCover
+		}
+	case FitCover, FitContain:
+	default:
+		return t, fmt.Errorf("fit must be %s or %s", FitCover, FitContain)
+	}
+
+	switch t.Format {
+	case "", "jpg":
+		t.Format = FormatJPEG
+	case FormatJPEG, FormatPNG:
+	default:
+		return t, fmt.Errorf("fmt must be %s or %s", FormatJPEG, FormatPNG)
+	}
+
+	if t.Format == FormatPNG {
+		t.Quality = 0
+	} else if t.Quality == 0 {
+		t.Quality = DefaultImageQuality
+	} else if !containsInt(imageQualities, t.Quality) {
+		return t, fmt.Errorf("q must be one of %v", imageQualities)
+	}
+	return t, nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Image fit modes
+const (
+	FitCover   = "cover"   // Fill the box and crop the overflow
+	FitContain = "contain" // Fit inside the box keeping the aspect ratio
+)
+
+// Image output formats
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// ImageTransform describes a resized rendition of an image. A zero width or
+// height is calculated from the aspect ratio.
+type ImageTransform struct {
+	Width     int
+	Height    int
+	Fit       string
+	Format    string
+	Quality   int
+	Watermark bool        // Draw the media item's watermark, applied by the image handler
+	Focus     *FocalPoint // Point cover crops keep in view, the center when nil
+}
+
+// TransformImage resizes and crops an image. Images are never enlarged: a box
+// larger than the source shrinks to fit it while keeping its aspect ratio.
+func TransformImage(src image.Image, t ImageTransform) (*image.RGBA, error) {
+	rgba := toRGBA(src)
+	srcW, srcH := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if srcW == 0 || srcH == 0 {
+		return nil, errors.New("image is empty")
+	}
+
+	if t.Fit == FitCover && t.Width > 0 && t.Height > 0 {
+		width, height := t.Width, t.Height
+		if shrink := math.Min(float64(srcW)/float64(width), float64(srcH)/float64(height)); shrink < 1 {
+			width = max(1, int(math.Round(float64(width)*shrink)))
+			height = max(1, int(math.Round(float64(height)*shrink)))
+		}
+		crop := coverCrop(srcW, srcH, width, height, t.Focus)
+		cropped := rgba.SubImage(crop.Add(rgba.Bounds().Min)).(*image.RGBA)
+		return ResizeImage(cropped, width, height), nil
+	}
+
+	scale := 1.0
+	if t.Width > 0 {
+		scale = math.Min(scale, float64(t.Width)/float64(srcW))
+	}
+	if t.Height > 0 {
+		scale = math.Min(scale, float64(t.Height)/float64(srcH))
+	}
+	width := max(1, int(math.Round(float64(srcW)*scale)))
+	height := max(1, int(math.Round(float64(srcH)*scale)))
+	return ResizeImage(rgba, width, height), nil
+}
+
+// coverCrop returns the part of the source with the box's aspect ratio that
+// is centered on the focal point as far as the image edges allow
+func coverCrop(srcW, srcH, width, height int, focus *FocalPoint) image.Rectangle {
+	cropW, cropH := srcW, srcH
+	if srcW*height > srcH*width {
+		cropW = max(1, int(math.Round(float64(srcH)*float64(width)/float64(height))))
+	} else {
+		cropH = max(1, int(math.Round(float64(srcW)*float64(height)/float64(width))))
+	}
+
+	focusX, focusY := 0.5, 0.5
+	if focus != nil {
+		focusX, focusY = focus.X/100, focus.Y/100
+	}
+	x0 := cropOffset(srcW, cropW, focusX)
+	y0 := cropOffset(srcH, cropH, focusY)
+	return image.Rect(x0, y0, x0+cropW, y0+cropH)
+}
+
+// cropOffset positions a crop of one dimension around a focal point given as a fraction
+func cropOffset(srcSize, cropSize int, focus float64) int {
+	offset := int(math.Round(focus*float64(srcSize) - float64(cropSize)/2))
+	return max(0, min(offset, srcSize-cropSize))
+}
+
+// EncodeImage writes an image as JPEG or PNG. JPEGs have no transparency, so
+// transparent areas are flattened onto white.
+func EncodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case FormatPNG:
+		encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+		return encoder.Encode(w, img)
+	case FormatJPEG:
+		flattened := image.NewRGBA(img.Bounds())
+		draw.Draw(flattened, flattened.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flattened, flattened.Bounds(), img, img.Bounds().Min, draw.Over)
+		return jpeg.Encode(w, flattened, &jpeg.Options{Quality: quality})
+	}
+	return errors.New("unsupported image format")
+}
+
+// ResizeImage scales an image to the given size with a Catmull-Rom filter,
+// widened when downscaling so every source pixel contributes
+func ResizeImage(src *image.RGBA, width, height int) *image.RGBA {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	if srcW == width && srcH == height {
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+		return dst
+	}
+
+	// Resize rows into a float buffer, then columns into the result
+	xWeights := resampleWeights(srcW, width)
+	rows := make([]float32, width*srcH*4)
+	for y := 0; y < srcH; y++ {
+		srcRow := src.Pix[src.PixOffset(src.Bounds().Min.X, src.Bounds().Min.Y+y):]
+		for x, weights := range xWeights {
+			var r, g, b, a float32
+			for i, weight := range weights.values {
+				p := (weights.start + i) * 4
+				r += weight * float32(srcRow[p])
+				g += weight * float32(srcRow[p+1])
+				b += weight * float32(srcRow[p+2])
+				a += weight * float32(srcRow[p+3])
+			}
+			o := (y*width + x) * 4
+			rows[o], rows[o+1], rows[o+2], rows[o+3] = r, g, b, a
+		}
+	}
+
+	yWeights := resampleWeights(srcH, height)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, weights := range yWeights {
+		for x := 0; x < width; x++ {
+			var r, g, b, a float32
+			for i, weight := range weights.values {
+				p := ((weights.start+i)*width + x) * 4
+				r += weight * rows[p]
+				g += weight * rows[p+1]
+				b += weight * rows[p+2]
+				a += weight * rows[p+3]
+			}
+			alpha := clampChannel(a)
+			o := dst.PixOffset(x, y)
+			// Premultiplied colors can never exceed their alpha
+			dst.Pix[o] = min(clampChannel(r), alpha)
+			dst.Pix[o+1] = min(clampChannel(g), alpha)
+			dst.Pix[o+2] = min(clampChannel(b), alpha)
+			dst.Pix[o+3] = alpha
+		}
+	}
+	return dst
+}
+
+// filterWeights are the source pixels and weights that make up one output pixel
+type filterWeights struct {
+	start  int
+	values []float32
+}
+
+// resampleWeights precomputes the filter weights for resizing one dimension
+func resampleWeights(srcSize, dstSize int) []filterWeights {
+	scale := float64(srcSize) / float64(dstSize)
+	filterScale := math.Max(scale, 1)
+	support := 2 * filterScale
+
+	weights := make([]filterWeights, dstSize)
+	for i := range weights {
+		center := (float64(i) + 0.5) * scale
+		start := max(0, int(math.Floor(center-support)))
+		end := min(srcSize, int(math.Ceil(center+support)))
+
+		values := make([]float32, end-start)
+		var sum float64
+		for j := start; j < end; j++ {
+			weight := catmullRom((float64(j) + 0.5 - center) / filterScale)
+			values[j-start] = float32(weight)
+			sum += weight
+		}
+		if sum != 0 {
+			for j := range values {
+				values[j] = float32(float64(values[j]) / sum)
+			}
+		}
+		weights[i] = filterWeights{start: start, values: values}
+	}
+	return weights
+}
+
+// catmullRom is the Catmull-Rom cubic filter kernel
+func catmullRom(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return 1.5*x*x*x - 2.5*x*x + 1
+	case x < 2:
+		return -0.5*x*x*x + 2.5*x*x - 4*x + 2
+	}
+	return 0
+}
+
+func clampChannel(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// toRGBA converts an image to RGBA, reusing it when it already is
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
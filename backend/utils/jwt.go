@@ -7,74 +7,104 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	// tokenIssuer is the issuer of all tokens signed by this server
+	tokenIssuer = "photography-portfolio"
+
+	// challengeAudience marks login challenge tokens so they are never accepted as access tokens
+	challengeAudience = "login-challenge"
+)
+
 // JWTClaims represents the JWT claims structure
 type JWTClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT generates a new JWT token for a user
-func GenerateJWT(userID uint, email, role, secret string, expiresIn time.Duration) (string, error) {
+// GenerateJWT generates a new short-lived access token for a user's session
+func GenerateJWT(userID uint, email, role string, sessionID uint, keys *KeyManager, expiresIn time.Duration) (string, error) {
 	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "photography-portfolio",
+			Issuer:    tokenIssuer,
 			Subject:   email,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.Sign(claims)
 }
 
-// ValidateTokenExpiration checks if the token is expired
-func ValidateTokenExpiration(claims jwt.MapClaims) bool {
-	if exp, ok := claims["exp"]; ok {
-		if expTime, ok := exp.(float64); ok {
-			return time.Unix(int64(expTime), 0).After(time.Now())
+// ParseJWT parses and validates an access token
+func ParseJWT(tokenString string, keys *KeyManager) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+	token, err := keys.Parse(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.SessionID == 0 {
+		return nil, errors.New("invalid token")
+	}
+
+	for _, audience := range claims.Audience {
+		if audience == challengeAudience {
+			return nil, errors.New("invalid token")
 		}
 	}
-	return false
+
+	return claims, nil
 }
 
-// ParseJWT parses and validates a JWT token
-func ParseJWT(tokenString, secret string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return []byte(secret), nil
-	})
+// Challenge token purposes used between login steps
+const (
+	ChallengeTwoFactorVerify = "2fa-verify" // Password accepted, TOTP code required
+	ChallengeTwoFactorEnroll = "2fa-enroll" // Password accepted, 2FA enrollment required
+)
 
-	if err != nil {
-		return nil, err
-	}
+// ChallengeClaims represents the claims of a short-lived login challenge token.
+// Challenge tokens carry no session and are rejected by AuthRequired.
+type ChallengeClaims struct {
+	UserID  uint   `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+// GenerateChallengeToken generates a short-lived token for completing a login step
+func GenerateChallengeToken(userID uint, purpose string, keys *KeyManager, expiresIn time.Duration) (string, error) {
+	claims := ChallengeClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{challengeAudience},
+		},
 	}
 
-	return nil, errors.New("invalid token")
+	return keys.Sign(claims)
 }
 
-// RefreshToken generates a new token with extended expiration
-func RefreshToken(tokenString, secret string, newExpiresIn time.Duration) (string, error) {
-	claims, err := ParseJWT(tokenString, secret)
+// ParseChallengeToken parses a challenge token and checks that it was issued for the given purpose
+func ParseChallengeToken(tokenString, purpose string, keys *KeyManager) (*ChallengeClaims, error) {
+	claims := &ChallengeClaims{}
+	token, err := keys.Parse(tokenString, claims, jwt.WithAudience(challengeAudience))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Update expiration time
-	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(newExpiresIn))
-	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+	if !token.Valid || claims.Purpose != purpose {
+		return nil, errors.New("invalid challenge token")
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
-} 
\ No newline at end of file
+	return claims, nil
+}
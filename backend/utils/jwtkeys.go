@@ -0,0 +1,419 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"photography-portfolio/config"
+	"photography-portfolio/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// keyReloadInterval limits how often an unknown kid triggers a reload from the database
+	keyReloadInterval = 30 * time.Second
+
+	// verifyGraceMargin is added to the access token lifetime to cover challenge tokens and clock skew
+	verifyGraceMargin = 10 * time.Minute
+
+	// rsaKeyBits is the size of generated RS256 keys
+	rsaKeyBits = 2048
+)
+
+// errUnknownSigningKey is returned when a token references a key that is unknown or expired
+var errUnknownSigningKey = errors.New("unknown or expired signing key")
+
+// verificationKey is a public key that still verifies tokens
+type verificationKey struct {
+	method    jwt.SigningMethod
+	public    interface{}
+	expiresAt *time.Time
+}
+
+// activeSigningKey is the private key used for new tokens
+type activeSigningKey struct {
+	kid       string
+	private   interface{}
+	createdAt time.Time
+}
+
+// KeyManager signs and verifies JWTs. With HS256 it uses JWT_SECRET; with RS256 or
+// EdDSA it keeps a set of key pairs in the database, signs with the newest one and
+// verifies with every key that has not expired yet.
+type KeyManager struct {
+	db          *gorm.DB
+	method      jwt.SigningMethod
+	secret      []byte
+	rotateAfter time.Duration
+	verifyFor   time.Duration
+
+	// legacyUntil keeps HS256 tokens issued before switching to an asymmetric algorithm valid until they expire
+	legacyUntil time.Time
+
+	mu       sync.RWMutex
+	signing  *activeSigningKey
+	keys     map[string]verificationKey
+	loadedAt time.Time
+}
+
+// NewKeyManager loads the signing keys and creates the first key pair if needed
+func NewKeyManager(db *gorm.DB, cfg *config.Config) (*KeyManager, error) {
+	method := jwt.GetSigningMethod(cfg.JWTAlgorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.JWTAlgorithm)
+	}
+
+	m := &KeyManager{
+		db:          db,
+		method:      method,
+		secret:      []byte(cfg.JWTSecret),
+		rotateAfter: cfg.JWTKeyRotationInterval,
+		verifyFor:   cfg.JWTExpiresIn + verifyGraceMargin,
+		keys:        map[string]verificationKey{},
+	}
+
+	if m.symmetric() {
+		return m, nil
+	}
+
+	m.legacyUntil = time.Now().Add(m.verifyFor)
+	if err := m.RotateIfDue(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Algorithm returns the algorithm used for new tokens
+func (m *KeyManager) Algorithm() string {
+	return m.method.Alg()
+}
+
+// Sign signs the claims with the current key
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(m.method, claims)
+	if m.symmetric() {
+		return token.SignedString(m.secret)
+	}
+
+	m.mu.RLock()
+	current := m.signing
+	m.mu.RUnlock()
+	if current == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token.Header["kid"] = current.kid
+	return token.SignedString(current.private)
+}
+
+// Parse verifies a token's signature, issuer and expiry and decodes its claims
+func (m *KeyManager) Parse(tokenString string, claims jwt.Claims, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	opts = append(opts,
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodEdDSA.Alg(),
+		}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	return jwt.ParseWithClaims(tokenString, claims, m.keyFunc, opts...)
+}
+
+// keyFunc selects the verification key for a token from its header
+func (m *KeyManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		if len(m.secret) == 0 || (!m.symmetric() && time.Now().After(m.legacyUntil)) {
+			return nil, errUnknownSigningKey
+		}
+		return m.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.lookup(kid)
+	if !ok && m.reloadAllowed() {
+		if err := m.load(); err != nil {
+			log.Printf("⚠️  Warning: Failed to reload signing keys: %v", err)
+		}
+		key, ok = m.lookup(kid)
+	}
+
+	if !ok || key.method.Alg() != token.Method.Alg() {
+		return nil, errUnknownSigningKey
+	}
+	if key.expiresAt != nil && time.Now().After(*key.expiresAt) {
+		return nil, errUnknownSigningKey
+	}
+	return key.public, nil
+}
+
+// lookup returns the verification key for a kid
+func (m *KeyManager) lookup(kid string) (verificationKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, ok := m.keys[kid]
+	return key, ok
+}
+
+// reloadAllowed limits database reloads triggered by unknown kids
+func (m *KeyManager) reloadAllowed() bool {
+	if m.symmetric() {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return time.Since(m.loadedAt) > keyReloadInterval
+}
+
+// RotateIfDue creates a new signing key when there is none for the configured
+// algorithm or the current one is older than the rotation interval
+func (m *KeyManager) RotateIfDue() error {
+	if m.symmetric() {
+		return nil
+	}
+
+	// Another instance may have rotated already
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	current := m.signing
+	m.mu.RUnlock()
+
+	if current != nil && (m.rotateAfter <= 0 || time.Since(current.createdAt) < m.rotateAfter) {
+		return nil
+	}
+	return m.Rotate()
+}
+
+// Rotate creates a new signing key. Previous keys stop signing and keep
+// verifying until every token they signed has expired.
+func (m *KeyManager) Rotate() error {
+	if m.symmetric() {
+		return errors.New("key rotation requires an asymmetric JWT algorithm")
+	}
+
+	privatePEM, publicPEM, err := generateKeyPair(m.method)
+	if err != nil {
+		return err
+	}
+
+	kid, err := GenerateRandomToken(8)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	key := models.SigningKey{
+		KID:        kid,
+		Algorithm:  m.method.Alg(),
+		PrivateKey: privatePEM,
+		PublicKey:  publicPEM,
+	}
+
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.SigningKey{}).
+			Where("retired_at IS NULL").
+			Updates(map[string]interface{}{
+				"retired_at": now,
+				"expires_at": now.Add(m.verifyFor),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&key).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Expired keys are no longer needed
+	m.db.Where("expires_at < ?", now).Delete(&models.SigningKey{})
+
+	log.Printf("🔑 Rotated JWT signing key, new %s key %s", key.Algorithm, key.KID)
+	return m.load()
+}
+
+// StartRotation checks the rotation schedule in the background
+func (m *KeyManager) StartRotation(checkEvery time.Duration) {
+	if m.symmetric() || m.rotateAfter <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(checkEvery)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := m.RotateIfDue(); err != nil {
+				log.Printf("⚠️  Warning: JWT key rotation failed: %v", err)
+			}
+		}
+	}()
+}
+
+// load reads all keys that still verify tokens from the database
+func (m *KeyManager) load() error {
+	var stored []models.SigningKey
+	if err := m.db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("created_at ASC").
+		Find(&stored).Error; err != nil {
+		return err
+	}
+
+	keys := make(map[string]verificationKey, len(stored))
+	var signing *activeSigningKey
+	for _, k := range stored {
+		method := jwt.GetSigningMethod(k.Algorithm)
+		public, err := parsePublicKeyPEM(k.PublicKey)
+		if method == nil || err != nil {
+			log.Printf("⚠️  Warning: Skipping unreadable signing key %s: %v", k.KID, err)
+			continue
+		}
+		keys[k.KID] = verificationKey{
+			method:    method,
+			public:    public,
+			expiresAt: k.ExpiresAt,
+		}
+
+		// The newest active key for the configured algorithm signs new tokens
+		if k.IsSigning() && k.Algorithm == m.method.Alg() {
+			private, err := parsePrivateKeyPEM(k.PrivateKey)
+			if err != nil {
+				log.Printf("⚠️  Warning: Skipping unreadable signing key %s: %v", k.KID, err)
+				continue
+			}
+			signing = &activeSigningKey{
+				kid:       k.KID,
+				private:   private,
+				createdAt: k.CreatedAt,
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.signing = signing
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+
+	return nil
+}
+
+// symmetric reports whether tokens are signed with the shared secret
+func (m *KeyManager) symmetric() bool {
+	return m.method.Alg() == jwt.SigningMethodHS256.Alg()
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that currently verify tokens
+func (m *KeyManager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for kid, key := range m.keys {
+		if key.expiresAt != nil && time.Now().After(*key.expiresAt) {
+			continue
+		}
+
+		jwk := JWK{
+			KeyID:     kid,
+			Use:       "sig",
+			Algorithm: key.method.Alg(),
+		}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// generateKeyPair creates a key pair for the signing method and returns it PEM encoded
+func generateKeyPair(method jwt.SigningMethod) (string, string, error) {
+	var private, public interface{}
+	switch method.Alg() {
+	case jwt.SigningMethodRS256.Alg():
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return "", "", err
+		}
+		private, public = key, &key.PublicKey
+	case jwt.SigningMethodEdDSA.Alg():
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", err
+		}
+		private, public = priv, pub
+	default:
+		return "", "", fmt.Errorf("cannot generate keys for %s", method.Alg())
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", "", err
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	return string(privatePEM), string(publicPEM), nil
+}
+
+// parsePrivateKeyPEM decodes a PKCS#8 private key
+func parsePrivateKeyPEM(data string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+// parsePublicKeyPEM decodes a PKIX public key
+func parsePublicKeyPEM(data string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// paletteSampleSize is the long edge of the downscaled copy colors are taken from
+	paletteSampleSize = 64
+	// paletteColors is the number of clusters the pixels are grouped into
+	paletteColors = 5
+	// paletteMinShare drops clusters covering less of the image than this
+	paletteMinShare = 0.05
+	// paletteIterations is the number of k-means refinement passes
+	paletteIterations = 10
+	// blurHashSampleSize is the long edge of the downscaled copy the BlurHash is computed on
+	blurHashSampleSize = 32
+)
+
+// blurHashCharacters is the base 83 alphabet of BlurHash strings
+const blurHashCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// ImageColors describes the colors of an image for placeholders and color search
+type ImageColors struct {
+	Palette  []string // Main colors as #rrggbb, the most common first
+	Average  string   // Average color as #rrggbb
+	BlurHash string   // 4x3 component BlurHash placeholder
+}
+
+// labColor is a color in the CIELAB space, where distances match how
+// different colors look
+type labColor struct {
+	L, A, B float64
+}
+
+// AnalyzeColors extracts the palette, average color and BlurHash of an image
+func AnalyzeColors(img image.Image) ImageColors {
+	rgba := toRGBA(img)
+	srcW, srcH := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if srcW == 0 || srcH == 0 {
+		return ImageColors{}
+	}
+
+	sample := downscale(rgba, paletteSampleSize)
+	var pixels []color.RGBA
+	var sumR, sumG, sumB float64
+	for y := 0; y < sample.Bounds().Dy(); y++ {
+		for x := 0; x < sample.Bounds().Dx(); x++ {
+			o := sample.PixOffset(x, y)
+			// Transparent areas are not part of the photo
+			if sample.Pix[o+3] < 128 {
+				continue
+			}
+			pixel := color.RGBA{R: sample.Pix[o], G: sample.Pix[o+1], B: sample.Pix[o+2], A: 255}
+			pixels = append(pixels, pixel)
+			sumR += float64(pixel.R)
+			sumG += float64(pixel.G)
+			sumB += float64(pixel.B)
+		}
+	}
+	if len(pixels) == 0 {
+		return ImageColors{}
+	}
+
+	n := float64(len(pixels))
+	return ImageColors{
+		Palette:  extractPalette(pixels),
+		Average:  hexColor(sumR/n, sumG/n, sumB/n),
+		BlurHash: BlurHash(downscale(rgba, blurHashSampleSize), 4, 3),
+	}
+}
+
+// ColorDistance returns how different two #rrggbb colors look, as the CIE76
+// distance where about 2.3 is barely noticeable and 100 is black to white
+func ColorDistance(a, b string) (float64, bool) {
+	x, ok := parseHexColor(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := parseHexColor(b)
+	if !ok {
+		return 0, false
+	}
+	return toLab(x).distance(toLab(y)), true
+}
+
+// NormalizeHexColor lowercases a #rrggbb color, adding a missing "#"
+func NormalizeHexColor(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if !strings.HasPrefix(value, "#") {
+		value = "#" + value
+	}
+	if _, ok := parseHexColor(value); !ok {
+		return "", false
+	}
+	return value, true
+}
+
+// extractPalette groups pixels into clusters of similar colors with k-means
+// and returns the average color of each cluster, largest first
+func extractPalette(pixels []color.RGBA) []string {
+	labs := make([]labColor, len(pixels))
+	for i, pixel := range pixels {
+		labs[i] = toLab(pixel)
+	}
+
+	// Start from pixels spread evenly over the lightness range, so the result
+	// does not depend on randomness
+	order := make([]int, len(labs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return labs[order[i]].L < labs[order[j]].L })
+	k := min(paletteColors, len(labs))
+	centers := make([]labColor, k)
+	for i := range centers {
+		centers[i] = labs[order[(2*i+1)*len(order)/(2*k)]]
+	}
+
+	assignments := make([]int, len(labs))
+	for iteration := 0; iteration < paletteIterations; iteration++ {
+		for i, lab := range labs {
+			best, bestDistance := 0, math.Inf(1)
+			for c, center := range centers {
+				if d := lab.distance(center); d < bestDistance {
+					best, bestDistance = c, d
+				}
+			}
+			assignments[i] = best
+		}
+
+		var sums [paletteColors]labColor
+		var counts [paletteColors]int
+		for i, lab := range labs {
+			c := assignments[i]
+			sums[c].L += lab.L
+			sums[c].A += lab.A
+			sums[c].B += lab.B
+			counts[c]++
+		}
+		for c := range centers {
+			if counts[c] > 0 {
+				n := float64(counts[c])
+				centers[c] = labColor{L: sums[c].L / n, A: sums[c].A / n, B: sums[c].B / n}
+			}
+		}
+	}
+
+	// Report clusters in RGB averages so the colors are exactly ones in the image
+	type cluster struct {
+		r, g, b float64
+		count   int
+	}
+	clusters := make([]cluster, k)
+	for i, pixel := range pixels {
+		c := &clusters[assignments[i]]
+		c.r += float64(pixel.R)
+		c.g += float64(pixel.G)
+		c.b += float64(pixel.B)
+		c.count++
+	}
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].count > clusters[j].count })
+
+	var palette []string
+	for _, c := range clusters {
+		if c.count == 0 || float64(c.count)/float64(len(pixels)) < paletteMinShare {
+			continue
+		}
+		n := float64(c.count)
+		hex := hexColor(c.r/n, c.g/n, c.b/n)
+		if !containsString(palette, hex) {
+			palette = append(palette, hex)
+		}
+	}
+	return palette
+}
+
+// BlurHash encodes an image as a BlurHash string (https://blurha.sh) with the
+// given number of horizontal and vertical components, from 1 to 9
+func BlurHash(img *image.RGBA, componentsX, componentsY int) string {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width == 0 || height == 0 || componentsX < 1 || componentsX > 9 || componentsY < 1 || componentsY > 9 {
+		return ""
+	}
+
+	// Convert to linear light once
+	linear := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			o := img.PixOffset(img.Bounds().Min.X+x, img.Bounds().Min.Y+y)
+			linear[y*width+x] = [3]float64{
+				srgbToLinear(img.Pix[o]),
+				srgbToLinear(img.Pix[o+1]),
+				srgbToLinear(img.Pix[o+2]),
+			}
+		}
+	}
+
+	factors := make([][3]float64, 0, componentsX*componentsY)
+	for j := 0; j < componentsY; j++ {
+		for i := 0; i < componentsX; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := normalisation *
+						math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+					pixel := linear[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+			scale := 1 / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var hash strings.Builder
+	hash.WriteString(encodeBase83((componentsX-1)+(componentsY-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMaximum := 0.0
+		for _, factor := range ac {
+			for _, v := range factor {
+				actualMaximum = math.Max(actualMaximum, math.Abs(v))
+			}
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		hash.WriteString(encodeBase83(quantisedMaximum, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	hash.WriteString(encodeBase83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4))
+	for _, factor := range ac {
+		quantised := 0
+		for _, v := range factor {
+			q := int(math.Max(0, math.Min(18, math.Floor(signedPow(v/maximumValue, 0.5)*9+9.5))))
+			quantised = quantised*19 + q
+		}
+		hash.WriteString(encodeBase83(quantised, 2))
+	}
+	return hash.String()
+}
+
+// downscale shrinks an image so its long edge is at most size pixels
+func downscale(img *image.RGBA, size int) *image.RGBA {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := math.Min(1, float64(size)/float64(max(srcW, srcH)))
+	return ResizeImage(img, max(1, int(math.Round(float64(srcW)*scale))), max(1, int(math.Round(float64(srcH)*scale))))
+}
+
+func encodeBase83(value, length int) string {
+	digits := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		digits[i] = blurHashCharacters[value%83]
+		value /= 83
+	}
+	return string(digits)
+}
+
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signedPow(value, exponent float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exponent), value)
+}
+
+// toLab converts an sRGB color to CIELAB with a D65 white point
+func toLab(c color.RGBA) labColor {
+	r, g, b := srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B)
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return labColor{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+func (c labColor) distance(other labColor) float64 {
+	return math.Sqrt((c.L-other.L)*(c.L-other.L) + (c.A-other.A)*(c.A-other.A) + (c.B-other.B)*(c.B-other.B))
+}
+
+func hexColor(r, g, b float64) string {
+	return fmt.Sprintf("#%02x%02x%02x", uint8(math.Round(r)), uint8(math.Round(g)), uint8(math.Round(b)))
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+
+	"photography-portfolio/config"
+)
+
+// maxPasswordLength is the longest password bcrypt can hash without truncation
+const maxPasswordLength = 72
+
+// ValidatePassword checks a password against the configured password policy
+func ValidatePassword(cfg *config.Config, password string) error {
+	if len(password) < cfg.PasswordMinLength {
+		return fmt.Errorf("Password must be at least %d characters", cfg.PasswordMinLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("Password must be at most %d characters", maxPasswordLength)
+	}
+
+	var hasUpper, hasLower, hasNumber, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasNumber = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if cfg.PasswordRequireMixed && (!hasUpper || !hasLower) {
+		return errors.New("Password must contain both upper and lower case letters")
+	}
+	if cfg.PasswordRequireNumber && !hasNumber {
+		return errors.New("Password must contain a number")
+	}
+	if cfg.PasswordRequireSymbol && !hasSymbol {
+		return errors.New("Password must contain a symbol")
+	}
+
+	return nil
+}
+
+// PasswordPolicy describes the configured password policy for clients
+func PasswordPolicy(cfg *config.Config) map[string]interface{} {
+	return map[string]interface{}{
+		"min_length":         cfg.PasswordMinLength,
+		"max_length":         maxPasswordLength,
+		"require_mixed_case": cfg.PasswordRequireMixed,
+		"require_number":     cfg.PasswordRequireNumber,
+		"require_symbol":     cfg.PasswordRequireSymbol,
+	}
+}
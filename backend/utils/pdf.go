@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	pdfPageWidth    = 612 // US Letter in points
+	pdfPageHeight   = 792
+	pdfMargin       = 72
+	pdfFontSize     = 11
+	pdfLineHeight   = 15
+	pdfCharsPerLine = 90
+)
+
+// GenerateTextPDF renders plain text into a minimal multi-page PDF document
+// using the built-in Helvetica font. Long lines are word-wrapped.
+func GenerateTextPDF(title, text string) []byte {
+	lines := wrapText(text, pdfCharsPerLine)
+	linesPerPage := (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+
+	var pages [][]string
+	for len(lines) > 0 {
+		n := linesPerPage
+		if n > len(lines) {
+			n = len(lines)
+		}
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+	}
+	if len(pages) == 0 {
+		pages = append(pages, []string{""})
+	}
+
+	// Object layout: 1 catalog, 2 pages, 3 font, 4 info, then a page and content object per page
+	var objects []string
+	pageRefs := make([]string, len(pages))
+	for i := range pages {
+		pageRefs[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageRefs, " "), len(pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	objects = append(objects, fmt.Sprintf("<< /Title (%s) /Producer (photography-portfolio) >>", escapePDFString(title)))
+
+	for i, pageLines := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range pageLines {
+			fmt.Fprintf(&content, "(%s) '\n", escapePDFString(line))
+		}
+		content.WriteString("ET")
+
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+i*2,
+		))
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+
+	return buf.Bytes()
+}
+
+// wrapText splits text into lines no longer than width characters
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		// Widths count characters, so multi-byte letters are never split
+		line := ""
+		for _, word := range words {
+			for utf8.RuneCountInString(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:width]))
+				word = string(runes[width:])
+			}
+			if line == "" {
+				line = word
+			} else if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width {
+				line += " " + word
+			} else {
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// escapePDFString escapes text for use in a PDF literal string, replacing
+// characters outside of Latin-1 since the standard fonts cannot render them
+func escapePDFString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 32:
+			// Drop control characters
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"math/bits"
+	"os"
+	"strconv"
+)
+
+// dHash compares the brightness of neighbouring cells in a 9x8 grid
+const (
+	dHashWidth  = 9
+	dHashHeight = 8
+	// dHashSamples caps how many pixels are sampled per cell and axis on large images
+	dHashSamples = 16
+)
+
+// FileSHA256 returns the hex encoded SHA-256 digest of a file
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// PerceptualHash returns the difference hash (dHash) of an image as 16 hex
+// characters. Resized, recompressed or lightly edited copies of a photo have
+// hashes within a few bits of each other.
+func PerceptualHash(img image.Image) string {
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return ""
+	}
+
+	var grid [dHashHeight][dHashWidth]float64
+	for y := 0; y < dHashHeight; y++ {
+		for x := 0; x < dHashWidth; x++ {
+			grid[y][x] = cellBrightness(img, bounds, x, y)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < dHashHeight; y++ {
+		for x := 0; x < dHashWidth-1; x++ {
+			hash <<= 1
+			if grid[y][x] < grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParsePerceptualHash decodes a perceptual hash, or returns false if it is
+// missing or invalid
+func ParsePerceptualHash(hash string) (uint64, bool) {
+	value, err := strconv.ParseUint(hash, 16, 64)
+	return value, err == nil
+}
+
+// HashDistance returns the number of differing bits between two decoded
+// perceptual hashes
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// HashMediaFile returns the content hash of a file and, for images that can be
+// decoded and are within the pixel limit, its perceptual hash
+func HashMediaFile(path string) (string, string, error) {
+	contentHash, err := FileSHA256(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	// Videos, formats without a decoder and images too large to decode
+	// safely only get a content hash
+	imageConfig, _, err := image.DecodeConfig(file)
+	if err != nil || imageConfig.Width*imageConfig.Height > maxImagePixels {
+		return contentHash, "", nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return contentHash, "", nil
+	}
+	return contentHash, PerceptualHash(img), nil
+}
+
+// cellBrightness averages the luminance of a grid cell, sampling large cells
+func cellBrightness(img image.Image, bounds image.Rectangle, cellX, cellY int) float64 {
+	x0 := bounds.Min.X + cellX*bounds.Dx()/dHashWidth
+	x1 := bounds.Min.X + (cellX+1)*bounds.Dx()/dHashWidth
+	y0 := bounds.Min.Y + cellY*bounds.Dy()/dHashHeight
+	y1 := bounds.Min.Y + (cellY+1)*bounds.Dy()/dHashHeight
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+
+	stepX := max(1, (x1-x0)/dHashSamples)
+	stepY := max(1, (y1-y0)/dHashSamples)
+
+	var total float64
+	var count int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			total += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+	return total / float64(count)
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to the 6 digits used here
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := GenerateTOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateTOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateTOTPCodeInvalidSecret(t *testing.T) {
+	if _, err := GenerateTOTPCode("not base32!", 1); err == nil {
+		t.Error("GenerateTOTPCode accepted an invalid secret")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+	code := func(offset int64) string {
+		code, err := GenerateTOTPCode(rfc6238Secret, step+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfc6238Secret, code(0), step, true},
+		{"previous step", rfc6238Secret, code(-1), step - 1, true},
+		{"next step", rfc6238Secret, code(1), step + 1, true},
+		{"two steps behind", rfc6238Secret, code(-2), 0, false},
+		{"two steps ahead", rfc6238Secret, code(2), 0, false},
+		{"spaces are ignored", rfc6238Secret, " " + code(0)[:3] + " " + code(0)[3:] + " ", step, true},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code(0), step, true},
+		{"wrong code", rfc6238Secret, "000000", 0, false},
+		{"too short", rfc6238Secret, code(0)[:5], 0, false},
+		{"too long", rfc6238Secret, code(0) + "1", 0, false},
+		{"empty", rfc6238Secret, "", 0, false},
+		{"invalid secret", "not base32!", code(0), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := ValidateTOTP(tt.secret, tt.code, now)
+			if gotOK != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q) = %d, %v, want %d, %v", tt.code, gotStep, gotOK, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
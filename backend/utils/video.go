@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"photography-portfolio/config"
+)
+
+// videoCommandTimeout bounds a single ffprobe or ffmpeg run
+const videoCommandTimeout = 10 * time.Minute
+
+// ErrFFmpegUnavailable is returned when ffmpeg or ffprobe is not installed
+var ErrFFmpegUnavailable = errors.New("ffmpeg is not installed")
+
+// VideoInfo describes the main video stream of a file
+type VideoInfo struct {
+	Duration  float64 // Seconds
+	Width     int
+	Height    int
+	Codec     string
+	FrameRate float64
+}
+
+// FFmpegAvailable checks whether both ffmpeg and ffprobe can be run
+func FFmpegAvailable(cfg *config.Config) bool {
+	if _, err := exec.LookPath(cfg.FFprobePath); err != nil {
+		return false
+	}
+	_, err := exec.LookPath(cfg.FFmpegPath)
+	return err == nil
+}
+
+// ProbeVideo reads the duration, resolution, codec and frame rate of a video with ffprobe
+func ProbeVideo(cfg *config.Config, path string) (*VideoInfo, error) {
+	output, err := runVideoCommand(cfg.FFprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_name,width,height,avg_frame_rate,r_frame_rate,side_data_list:stream_tags=rotate:format=duration",
+		"-of", "json",
+		path,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecName    string `json:"codec_name"`
+			Width        int    `json:"width"`
+			Height       int    `json:"height"`
+			AvgFrameRate string `json:"avg_frame_rate"`
+			RFrameRate   string `json:"r_frame_rate"`
+			Tags         struct {
+				Rotate string `json:"rotate"`
+			} `json:"tags"`
+			SideDataList []struct {
+				Rotation float64 `json:"rotation"`
+			} `json:"side_data_list"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return nil, errors.New("file has no video stream")
+	}
+
+	stream := probe.Streams[0]
+	info := &VideoInfo{
+		Width:     stream.Width,
+		Height:    stream.Height,
+		Codec:     stream.CodecName,
+		FrameRate: parseFrameRate(stream.AvgFrameRate),
+	}
+	if info.FrameRate == 0 {
+		info.FrameRate = parseFrameRate(stream.RFrameRate)
+	}
+	info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+
+	// Phones record portrait video as rotated landscape frames
+	rotation, _ := strconv.ParseFloat(stream.Tags.Rotate, 64)
+	for _, sideData := range stream.SideDataList {
+		if sideData.Rotation != 0 {
+			rotation = sideData.Rotation
+		}
+	}
+	if int(math.Abs(rotation))%180 == 90 {
+		info.Width, info.Height = info.Height, info.Width
+	}
+
+	return info, nil
+}
+
+// ExtractPosterFrame saves the frame at the given second as a JPEG
+func ExtractPosterFrame(cfg *config.Config, src, dst string, at float64) error {
+	_, err := runVideoCommand(cfg.FFmpegPath,
+		"-y", "-v", "error",
+		"-ss", formatSeconds(at),
+		"-i", src,
+		"-frames:v", "1",
+		"-q:v", "3",
+		dst,
+	)
+	return err
+}
+
+// GeneratePreviewClip saves a short muted H.264 clip starting at the given
+// second, scaled down to at most 640 pixels wide, that can autoplay in a loop
+func GeneratePreviewClip(cfg *config.Config, src, dst string, start, length float64) error {
+	_, err := runVideoCommand(cfg.FFmpegPath,
+		"-y", "-v", "error",
+		"-ss", formatSeconds(start),
+		"-i", src,
+		"-t", formatSeconds(length),
+		"-an",
+		"-vf", "scale='min(640,iw)':-2",
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "28",
+		"-pix_fmt", "yuv420p",
+		"-movflags", "+faststart",
+		dst,
+	)
+	return err
+}
+
+// runVideoCommand runs ffmpeg or ffprobe and returns its standard output
+func runVideoCommand(name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, ErrFFmpegUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), videoCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// parseFrameRate parses ffprobe rates such as "30000/1001"
+func parseFrameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return math.Round(n/d*1000) / 1000
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	"photography-portfolio/models"
+)
+
+// Size of the watermark font glyphs, in font pixels
+const (
+	glyphWidth   = 5
+	glyphHeight  = 7
+	glyphAdvance = glyphWidth + 1
+)
+
+// watermarkFont is a 5x7 bitmap font. Each row is 5 bits, the highest bit on
+// the left. Text is drawn in upper case and unknown characters are skipped.
+var watermarkFont = map[rune][glyphHeight]uint8{
+	' ':  {},
+	'A':  {0x0e, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'B':  {0x1e, 0x11, 0x11, 0x1e, 0x11, 0x11, 0x1e},
+	'C':  {0x0e, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0e},
+	'D':  {0x1c, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1c},
+	'E':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x1f},
+	'F':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x10},
+	'G':  {0x0e, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0f},
+	'H':  {0x11, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'I':  {0x0e, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'J':  {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c},
+	'K':  {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L':  {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1f},
+	'M':  {0x11, 0x1b, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N':  {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O':  {0x0e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'P':  {0x1e, 0x11, 0x11, 0x1e, 0x10, 0x10, 0x10},
+	'Q':  {0x0e, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0d},
+	'R':  {0x1e, 0x11, 0x11, 0x1e, 0x14, 0x12, 0x11},
+	'S':  {0x0f, 0x10, 0x10, 0x0e, 0x01, 0x01, 0x1e},
+	'T':  {0x1f, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'V':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x0a, 0x04},
+	'W':  {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0a},
+	'X':  {0x11, 0x11, 0x0a, 0x04, 0x0a, 0x11, 0x11},
+	'Y':  {0x11, 0x11, 0x11, 0x0a, 0x04, 0x04, 0x04},
+	'Z':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1f},
+	'0':  {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+	'1':  {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3':  {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+	'4':  {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5':  {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6':  {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8':  {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'9':  {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+	'.':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},
+	',':  {0x00, 0x00, 0x00, 0x00, 0x0c, 0x04, 0x08},
+	'-':  {0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00},
+	'_':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1f},
+	'\'': {0x0c, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00},
+	'!':  {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
+	'?':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	':':  {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00},
+	'/':  {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'(':  {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')':  {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'&':  {0x0c, 0x12, 0x14, 0x08, 0x15, 0x12, 0x0d},
+	'@':  {0x0e, 0x11, 0x01, 0x0d, 0x15, 0x15, 0x0e},
+	'+':  {0x00, 0x04, 0x04, 0x1f, 0x04, 0x04, 0x00},
+	'#':  {0x0a, 0x0a, 0x1f, 0x0a, 0x1f, 0x0a, 0x0a},
+	'|':  {0x04, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+}
+
+// ApplyWatermark draws a watermark's logo, or its text when there is no
+// logo, onto an image. The watermark is sized relative to the image width
+// and kept inside a margin.
+func ApplyWatermark(dst *image.RGBA, watermark models.Watermark, logo image.Image) {
+	bounds := dst.Bounds()
+	if bounds.Empty() {
+		return
+	}
+
+	var stamp *image.RGBA
+	if logo != nil {
+		stamp = toRGBA(logo)
+	} else {
+		textColor, ok := parseHexColor(watermark.Color)
+		if !ok {
+			textColor = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		}
+		stamp = renderWatermarkText(watermark.Text, textColor)
+	}
+	if stamp == nil || stamp.Bounds().Empty() {
+		return
+	}
+
+	margin := int(math.Round(float64(min(bounds.Dx(), bounds.Dy())) * 0.03))
+	width := int(math.Round(float64(bounds.Dx()) * watermark.Scale))
+	width = min(width, bounds.Dx()-2*margin)
+	height := int(math.Round(float64(width) * float64(stamp.Bounds().Dy()) / float64(stamp.Bounds().Dx())))
+	if height > bounds.Dy()-2*margin {
+		height = bounds.Dy() - 2*margin
+		width = int(math.Round(float64(height) * float64(stamp.Bounds().Dx()) / float64(stamp.Bounds().Dy())))
+	}
+	if width < 1 || height < 1 {
+		return
+	}
+	stamp = ResizeImage(stamp, width, height)
+
+	var at image.Point
+	switch watermark.Position {
+	case models.WatermarkTopLeft:
+		at = image.Pt(margin, margin)
+	case models.WatermarkTopRight:
+		at = image.Pt(bounds.Dx()-margin-width, margin)
+	case models.WatermarkBottomLeft:
+		at = image.Pt(margin, bounds.Dy()-margin-height)
+	case models.WatermarkCenter:
+		at = image.Pt((bounds.Dx()-width)/2, (bounds.Dy()-height)/2)
+	default:
+		at = image.Pt(bounds.Dx()-margin-width, bounds.Dy()-margin-height)
+	}
+
+	opacity := image.NewUniform(color.Alpha{A: uint8(math.Round(watermark.Opacity * 255))})
+	target := image.Rectangle{Min: at, Max: at.Add(image.Pt(width, height))}.Add(bounds.Min)
+	draw.DrawMask(dst, target, stamp, image.Point{}, opacity, image.Point{}, draw.Over)
+}
+
+// renderWatermarkText draws text with a soft shadow so it stays readable on
+// light backgrounds. The glyphs are drawn oversized and smoothed when the
+// stamp is resized to the image.
+func renderWatermarkText(text string, textColor color.RGBA) *image.RGBA {
+	var glyphs [][glyphHeight]uint8
+	for _, r := range strings.ToUpper(strings.ReplaceAll(text, "©", "(C)")) {
+		if glyph, ok := watermarkFont[r]; ok {
+			glyphs = append(glyphs, glyph)
+		}
+	}
+	if len(glyphs) == 0 {
+		return nil
+	}
+
+	const pixel = 8
+	shadowOffset := pixel / 2
+	width := (len(glyphs)*glyphAdvance-1)*pixel + shadowOffset
+	height := glyphHeight*pixel + shadowOffset
+	stamp := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	shadow := image.NewUniform(color.RGBA{A: 96})
+	fill := image.NewUniform(textColor)
+	for _, layer := range []struct {
+		src    image.Image
+		offset int
+	}{{shadow, shadowOffset}, {fill, 0}} {
+		for i, glyph := range glyphs {
+			for row, bits := range glyph {
+				for col := 0; col < glyphWidth; col++ {
+					if bits&(1<<(glyphWidth-1-col)) == 0 {
+						continue
+					}
+					x := (i*glyphAdvance+col)*pixel + layer.offset
+					y := row*pixel + layer.offset
+					draw.Draw(stamp, image.Rect(x, y, x+pixel, y+pixel), layer.src, image.Point{}, draw.Over)
+				}
+			}
+		}
+	}
+	return stamp
+}
+
+// parseHexColor parses a #rrggbb color
+func parseHexColor(value string) (color.RGBA, bool) {
+	if len(value) == 7 && value[0] == '#' {
+		if rgb, err := strconv.ParseUint(value[1:], 16, 32); err == nil {
+			return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}, true
+		}
+	}
+	return color.RGBA{}, false
+}
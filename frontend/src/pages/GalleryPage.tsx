@@ -201,10 +201,13 @@ export const GalleryPage: React.FC = () => {
                                     className="gallery-item cursor-pointer group"
                                     onClick={() => openLightbox(image, index)}
                                 >
-                                    <div className="aspect-square bg-accent/30 rounded-lg overflow-hidden">
-                                        {image.thumbnail_url || image.s3_url ? (
+                                    <div
+                                        className="aspect-square bg-accent/30 rounded-lg overflow-hidden"
+                                        style={image.average_color ? { backgroundColor: image.average_color } : undefined}
+                                    >
+                                        {image.images || image.thumbnail_url || image.s3_url ? (
                                             <img
-                                                src={image.thumbnail_url || image.s3_url}
+                                                src={image.images?.thumb ?? (image.thumbnail_url || image.s3_url)}
                                                 alt={image.title}
                                                 className="w-full h-full object-cover group-hover:scale-105 transition-transform duration-300"
                                                 loading="lazy"
@@ -282,7 +285,7 @@ export const GalleryPage: React.FC = () => {
                             {/* Image */}
                             <div className="bg-card rounded-lg overflow-hidden">
                                 <img
-                                    src={selectedImage.s3_url}
+                                    src={selectedImage.images?.large ?? selectedImage.s3_url}
                                     alt={selectedImage.title}
                                     className="w-full h-auto max-h-[70vh] object-contain"
                                 />
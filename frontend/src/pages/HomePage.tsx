@@ -85,9 +85,10 @@ export const HomePage: React.FC = () => {
                             <div className="aspect-[4/3] bg-gradient-to-br from-primary/20 to-accent/30 rounded-2xl overflow-hidden shadow-2xl">
                                 {heroImageQuery.data?.media[0] ? (
                                     <img
-                                        src={heroImageQuery.data.media[0].s3_url}
+                                        src={heroImageQuery.data.media[0].images?.large ?? heroImageQuery.data.media[0].s3_url}
                                         alt={heroImageQuery.data.media[0].title}
                                         className="w-full h-full object-cover"
+                                        style={{ objectPosition: `${heroImageQuery.data.media[0].focal_x ?? 50}% ${heroImageQuery.data.media[0].focal_y ?? 50}%` }}
                                     />
                                 ) : (
                                     <div className="w-full h-full flex items-center justify-center">
@@ -168,9 +169,10 @@ export const HomePage: React.FC = () => {
                                         <div className="aspect-[4/3] bg-gradient-to-br from-accent/30 to-primary/20 relative overflow-hidden">
                                             {featuredImage ? (
                                                 <img
-                                                    src={featuredImage.s3_url}
+                                                    src={featuredImage.images?.medium ?? featuredImage.s3_url}
                                                     alt={featuredImage.title}
                                                     className="w-full h-full object-cover"
+                                                    style={{ objectPosition: `${featuredImage.focal_x ?? 50}% ${featuredImage.focal_y ?? 50}%` }}
                                                 />
                                             ) : (
                                                 <div className="absolute inset-0 flex items-center justify-center">
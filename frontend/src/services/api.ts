@@ -13,6 +13,8 @@ import {
   BookingService,
   StripeCheckoutResponse,
   GalleryData,
+  Pagination,
+  FeaturedData,
 } from '../types';
 
 // Create axios instance with base configuration
@@ -32,13 +34,64 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
-// Handle auth errors globally
+const clearStoredAuth = () => {
+  localStorage.removeItem('auth_token');
+  localStorage.removeItem('refresh_token');
+  localStorage.removeItem('user');
+};
+
+// Share a single in-flight refresh between concurrent requests, since
+// refresh tokens are rotated and can only be used once
+let refreshPromise: Promise<string> | null = null;
+
+const refreshAccessToken = (): Promise<string> => {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) {
+    return Promise.reject(new Error('No refresh token'));
+  }
+
+  if (!refreshPromise) {
+    refreshPromise = axios
+      .post<ApiResponse<AuthResponse>>('/api/auth/refresh', {
+        refresh_token: refreshToken,
+      })
+      .then((response) => {
+        const { token, refresh_token } = response.data.data;
+        localStorage.setItem('auth_token', token);
+        localStorage.setItem('refresh_token', refresh_token);
+        return token;
+      })
+      .finally(() => {
+        refreshPromise = null;
+      });
+  }
+
+  return refreshPromise;
+};
+
+// Handle auth errors globally, refreshing the access token once before giving up
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const original = error.config;
+    if (
+      error.response?.status === 401 &&
+      original &&
+      !original._retry &&
+      !original.url?.startsWith('/auth/')
+    ) {
+      original._retry = true;
+      try {
+        const token = await refreshAccessToken();
+        original.headers.Authorization = `Bearer ${token}`;
+        return api(original);
+      } catch {
+        // Fall through to logout
+      }
+    }
+
     if (error.response?.status === 401) {
-      localStorage.removeItem('auth_token');
-      localStorage.removeItem('user');
+      clearStoredAuth();
       window.location.href = '/login';
     }
     return Promise.reject(error);
@@ -56,9 +109,19 @@ export const authAPI = {
   },
 
   logout: async (): Promise<void> => {
-    await api.post('/auth/logout');
-    localStorage.removeItem('auth_token');
-    localStorage.removeItem('user');
+    try {
+      await api.post('/auth/logout');
+    } finally {
+      clearStoredAuth();
+    }
+  },
+
+  logoutEverywhere: async (): Promise<void> => {
+    try {
+      await api.post('/auth/logout-all');
+    } finally {
+      clearStoredAuth();
+    }
   },
 
   getCurrentUser: async (): Promise<User> => {
@@ -146,6 +209,15 @@ export const mediaAPI = {
   },
 };
 
+// Homepage API
+export const homepageAPI = {
+  getFeatured: async (): Promise<FeaturedData> => {
+    const response: AxiosResponse<ApiResponse<FeaturedData>> =
+      await api.get('/featured');
+    return response.data.data;
+  },
+};
+
 // Contact API
 export const contactAPI = {
   submitContact: async (data: ContactFormData): Promise<void> => {
@@ -280,21 +352,9 @@ export const adminAPI = {
     page = 1,
     limit = 10,
     status?: 'read' | 'unread'
-  ): Promise<{
-    messages: ContactMessage[];
-    total: number;
-    page: number;
-    limit: number;
-    pages: number;
-  }> => {
+  ): Promise<Pagination & { messages: ContactMessage[] }> => {
     const response: AxiosResponse<
-      ApiResponse<{
-        messages: ContactMessage[];
-        total: number;
-        page: number;
-        limit: number;
-        pages: number;
-      }>
+      ApiResponse<Pagination & { messages: ContactMessage[] }>
     > = await api.get('/contact/messages', {
       params: { page, limit, status },
     });
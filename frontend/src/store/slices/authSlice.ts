@@ -26,6 +26,7 @@ export const loginUser = createAsyncThunk(
     try {
       const response = await authAPI.login(credentials);
       localStorage.setItem('auth_token', response.token);
+      localStorage.setItem('refresh_token', response.refresh_token);
       localStorage.setItem('user', JSON.stringify(response.user));
       toast.success('Login successful!');
       return response;
@@ -80,6 +81,7 @@ const authSlice = createSlice({
       state.isAuthenticated = false;
       state.error = null;
       localStorage.removeItem('auth_token');
+      localStorage.removeItem('refresh_token');
       localStorage.removeItem('user');
     },
   },
@@ -109,6 +111,8 @@ const authSlice = createSlice({
         state.isAuthenticated = false;
         state.error = null;
         localStorage.removeItem('auth_token');
+        localStorage.removeItem('refresh_token');
+      localStorage.removeItem('refresh_token');
         localStorage.removeItem('user');
       })
       // Get current user
@@ -131,6 +135,8 @@ const authSlice = createSlice({
         state.user = null;
         state.token = null;
         localStorage.removeItem('auth_token');
+        localStorage.removeItem('refresh_token');
+      localStorage.removeItem('refresh_token');
         localStorage.removeItem('user');
       });
   },
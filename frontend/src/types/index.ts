@@ -19,15 +19,98 @@ export interface Media {
   mime_type: string;
   width?: number;
   height?: number;
+  focal_x?: number; // Percent of the width that crops keep in view
+  focal_y?: number;
+  focal_source?: 'auto' | 'manual';
+  palette?: string[]; // Main colors as #rrggbb, the most common first
+  average_color?: string; // Placeholder background while the image loads
+  blur_hash?: string;
+  exif?: string; // JSON object with camera, lens, focal_length, aperture, exposure_time, iso and taken_at
+  duration?: number; // Video length in seconds
+  video_codec?: string;
+  frame_rate?: number;
+  poster_url?: string;
+  preview_url?: string; // Short muted loop for hover previews
+  hls_url?: string; // Adaptive streaming master playlist
+  images?: Record<'thumb' | 'small' | 'medium' | 'large', string>; // Signed rendition URLs
   view_count: number;
   is_featured: boolean;
   is_public: boolean;
+  watermark?: '' | 'on' | 'off'; // Overrides the category watermark
   sort_order: number;
+  album_id?: number | null;
   uploaded_at: string;
   created_at: string;
   updated_at: string;
 }
 
+export interface Album {
+  id: number;
+  title: string;
+  description?: string;
+  is_public: boolean;
+  cover_media_id?: number | null;
+  media_count: number;
+  created_at: string;
+  updated_at: string;
+}
+
+// Homepage curation
+export type HomepageSectionType = 'hero' | 'featured_albums' | 'testimonials';
+
+export interface FeaturedItem {
+  id: number;
+  caption?: string; // Hero slide caption
+  quote?: string; // Testimonial
+  author?: string;
+  author_role?: string;
+  link_url?: string;
+  media?: Media; // Hero slide or testimonial photo
+  album?: Album & { cover?: Media };
+}
+
+export interface FeaturedSection {
+  id: number;
+  type: HomepageSectionType;
+  title: string;
+  subtitle: string;
+  starts_at: string | null;
+  ends_at: string | null;
+  items: FeaturedItem[];
+}
+
+export interface FeaturedData {
+  sections: FeaturedSection[]; // Live sections in homepage order
+  media: Media[]; // Featured public media
+}
+
+export interface SearchResult {
+  type: 'media' | 'album' | 'message' | 'booking';
+  id: number;
+  title: string;
+  highlight: string; // Escaped HTML with matches in <mark> tags
+  rank: number;
+  item: Media | Album | Record<string, unknown>;
+}
+
+export interface SearchResults {
+  query: string;
+  results: SearchResult[];
+  counts: Partial<Record<SearchResult['type'], number>>;
+  total: number;
+  page: number;
+  limit: number;
+  pages: number;
+}
+
+export interface BatchUploadResult {
+  index: number;
+  file_name: string;
+  success: boolean;
+  media?: Media;
+  error?: string;
+}
+
 export type MediaCategory =
   | 'athletes'
   | 'food'
@@ -36,11 +119,15 @@ export type MediaCategory =
   | 'action';
 
 // User Types
+export type UserRole = 'owner' | 'editor' | 'assistant' | 'viewer';
+
 export interface User {
   id: string;
   email: string;
   name: string;
-  role: 'admin';
+  role: UserRole;
+  permissions?: string[];
+  totp_enabled?: boolean;
   created_at: string;
   updated_at: string;
 }
@@ -49,6 +136,15 @@ export interface User {
 export interface AuthResponse {
   user: User;
   token: string;
+  refresh_token: string;
+  expires_in: number;
+}
+
+export interface TwoFactorChallenge {
+  two_factor_required: boolean;
+  two_factor_setup_required: boolean;
+  challenge_token: string;
+  expires_in: number;
 }
 
 export interface LoginCredentials {
@@ -136,13 +232,20 @@ export interface StripeCheckoutResponse {
   booking_id: number;
 }
 
+// Pagination fields of list responses
+export interface Pagination {
+  total: number;
+  page?: number; // Not set for cursor requests
+  limit: number;
+  pages: number;
+  next_cursor?: string; // Send as cursor to get the items after this page
+  has_more: boolean;
+}
+
 // Gallery Types
-export interface GalleryData {
+export interface GalleryData extends Pagination {
   category: MediaCategory;
   media: Media[];
-  total: number;
-  page: number;
-  limit: number;
 }
 
 // Upload Types